@@ -0,0 +1,69 @@
+package backoff
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/adapters/framing"
+)
+
+const (
+	defaultMinInterval = 500 * time.Millisecond
+	defaultMaxInterval = 10 * time.Second
+)
+
+// exponential backoff for reconnection attempts, the interval doubles after every
+// failed attempt until it reaches the maximum
+type Backoff struct {
+	minInterval time.Duration
+	maxInterval time.Duration
+	interval    time.Duration
+}
+
+// intervals are in milliseconds, zero values select the defaults
+func NewBackoff(minIntervalMs int64, maxIntervalMs int64) *Backoff {
+	minInterval := time.Duration(minIntervalMs) * time.Millisecond
+	if minInterval <= 0 {
+		minInterval = defaultMinInterval
+	}
+	maxInterval := time.Duration(maxIntervalMs) * time.Millisecond
+	if maxInterval <= 0 {
+		maxInterval = defaultMaxInterval
+	}
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+
+	return &Backoff{
+		minInterval: minInterval,
+		maxInterval: maxInterval,
+	}
+}
+
+// delay before the next attempt
+func (b *Backoff) Next() time.Duration {
+	if b.interval == 0 {
+		b.interval = b.minInterval
+	} else {
+		b.interval *= 2
+		if b.interval > b.maxInterval {
+			b.interval = b.maxInterval
+		}
+	}
+	return b.interval
+}
+
+func (b *Backoff) Reset() {
+	b.interval = 0
+}
+
+// returns true if the error means the connection to the peer is broken
+func IsConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, framing.ErrCorrupted)
+}
@@ -0,0 +1,49 @@
+package backoff
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	backoff := NewBackoff(100, 700)
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		700 * time.Millisecond,
+		700 * time.Millisecond,
+	}
+	for i, interval := range expected {
+		if next := backoff.Next(); next != interval {
+			t.Errorf("Expected interval %d to be %s, got %s", i, interval, next)
+		}
+	}
+
+	backoff.Reset()
+	if next := backoff.Next(); next != 100*time.Millisecond {
+		t.Errorf("Expected interval after reset to be 100ms, got %s", next)
+	}
+
+	backoff = NewBackoff(0, 0)
+	if next := backoff.Next(); next != defaultMinInterval {
+		t.Errorf("Expected default interval %s, got %s", defaultMinInterval, next)
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	_, err := net.Dial("unix", "/tmp/backoff_no_such_socket")
+	if !IsConnectionError(err) {
+		t.Errorf("Expected dial error to be a connection error")
+	}
+	if !IsConnectionError(io.EOF) {
+		t.Errorf("Expected EOF to be a connection error")
+	}
+	if IsConnectionError(errors.New("invalid response")) {
+		t.Errorf("Expected generic error not to be a connection error")
+	}
+}
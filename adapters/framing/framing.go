@@ -0,0 +1,204 @@
+package framing
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// message framing of the Unix socket IPC protocols
+type Mode uint8
+
+const (
+	// bare JSON values without delimiters, used by the existing peers
+	ModeCompat Mode = iota
+	// every message is terminated by a newline
+	ModeNewline
+	// every message is preceded by its length as 4-byte big-endian unsigned integer
+	ModeLengthPrefixed
+)
+
+const (
+	MaxMessageSize = 1024 * 1024
+	readBufSize    = 4096
+)
+
+// the stream can not be parsed any further, the connection has to be reestablished
+var ErrCorrupted = errors.New("corrupted message stream")
+
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "", "compat":
+		return ModeCompat, nil
+	case "newline":
+		return ModeNewline, nil
+	case "length", "length-prefixed":
+		return ModeLengthPrefixed, nil
+	default:
+		return ModeCompat, fmt.Errorf("unknown framing mode %s", name)
+	}
+}
+
+func (m Mode) String() string {
+	switch m {
+	case ModeNewline:
+		return "newline"
+	case ModeLengthPrefixed:
+		return "length-prefixed"
+	default:
+		return "compat"
+	}
+}
+
+// reads and writes whole messages regardless of how the data is split between socket reads
+type Conn struct {
+	mode    Mode
+	writer  io.Writer
+	reader  *bufio.Reader
+	decoder *json.Decoder
+	// bounds the data read by the decoder for the current message in compat mode
+	limiter *limitedReader
+}
+
+// stops reading once the limit is reached, the limit is the total number of bytes read
+type limitedReader struct {
+	reader io.Reader
+	read   int64
+	limit  int64
+}
+
+var errLimitReached = errors.New("read limit reached")
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.read >= l.limit {
+		return 0, errLimitReached
+	}
+	if int64(len(p)) > l.limit-l.read {
+		p = p[:l.limit-l.read]
+	}
+	n, err := l.reader.Read(p)
+	l.read += int64(n)
+	return n, err
+}
+
+func NewConn(rw io.ReadWriter, mode Mode) *Conn {
+	c := &Conn{
+		mode:   mode,
+		writer: rw,
+		reader: bufio.NewReaderSize(rw, readBufSize),
+	}
+	if mode == ModeCompat {
+		c.limiter = &limitedReader{reader: c.reader}
+		c.decoder = json.NewDecoder(c.limiter)
+	}
+	return c
+}
+
+func (c *Conn) ReadMessage() ([]byte, error) {
+	switch c.mode {
+	case ModeNewline:
+		return c.readLine()
+	case ModeLengthPrefixed:
+		return c.readLengthPrefixed()
+	default:
+		return c.readJSON()
+	}
+}
+
+func (c *Conn) WriteMessage(data []byte) error {
+	if len(data) > MaxMessageSize {
+		return fmt.Errorf("message size %d exceeds the limit of %d bytes", len(data), MaxMessageSize)
+	}
+
+	var msg []byte
+	switch c.mode {
+	case ModeNewline:
+		msg = make([]byte, 0, len(data)+1)
+		msg = append(msg, data...)
+		msg = append(msg, '\n')
+	case ModeLengthPrefixed:
+		msg = make([]byte, 4, len(data)+4)
+		binary.BigEndian.PutUint32(msg, uint32(len(data)))
+		msg = append(msg, data...)
+	default:
+		msg = data
+	}
+
+	_, err := c.writer.Write(msg)
+	return err
+}
+
+func (c *Conn) readJSON() ([]byte, error) {
+	// the message starts where the previous one ended, the decoder may have read ahead
+	c.limiter.limit = c.decoder.InputOffset() + MaxMessageSize
+	var msg json.RawMessage
+	err := c.decoder.Decode(&msg)
+	if err != nil {
+		if errors.Is(err, errLimitReached) {
+			return nil, fmt.Errorf("%w: message exceeds %d bytes", ErrCorrupted, MaxMessageSize)
+		}
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (c *Conn) readLine() ([]byte, error) {
+	for {
+		var line []byte
+		for {
+			chunk, err := c.reader.ReadSlice('\n')
+			if len(line)+len(chunk) > MaxMessageSize+1 {
+				return nil, fmt.Errorf("%w: message exceeds %d bytes", ErrCorrupted, MaxMessageSize)
+			}
+			line = append(line, chunk...)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, bufio.ErrBufferFull) {
+				return nil, err
+			}
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 {
+			return line, nil
+		}
+	}
+}
+
+func (c *Conn) readLengthPrefixed() ([]byte, error) {
+	var header [4]byte
+	_, err := io.ReadFull(c.reader, header[:])
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > MaxMessageSize {
+		return nil, fmt.Errorf("%w: message size %d exceeds %d bytes", ErrCorrupted, size, MaxMessageSize)
+	}
+
+	msg := make([]byte, size)
+	_, err = io.ReadFull(c.reader, msg)
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
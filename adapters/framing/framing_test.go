@@ -0,0 +1,222 @@
+package framing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// returns data in chunks of the given sizes to simulate partial socket reads
+type chunkReader struct {
+	data   []byte
+	chunks []int
+	index  int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	n := len(r.data)
+	if len(r.chunks) > 0 {
+		n = r.chunks[r.index%len(r.chunks)]
+		r.index++
+		if n <= 0 {
+			n = 1
+		}
+	}
+	n = min(n, len(p), len(r.data))
+	copy(p, r.data[:n])
+	r.data = r.data[n:]
+	return n, nil
+}
+
+type readWriter struct {
+	io.Reader
+	io.Writer
+}
+
+func frame(t testing.TB, mode Mode, messages [][]byte) []byte {
+	var buf bytes.Buffer
+	conn := NewConn(&readWriter{Reader: &bytes.Buffer{}, Writer: &buf}, mode)
+	for _, msg := range messages {
+		if err := conn.WriteMessage(msg); err != nil {
+			t.Fatalf("Failed to write message: %s", err.Error())
+		}
+	}
+	return buf.Bytes()
+}
+
+func readAll(t testing.TB, mode Mode, data []byte, chunks []int) [][]byte {
+	reader := &chunkReader{data: data, chunks: chunks}
+	conn := NewConn(&readWriter{Reader: reader, Writer: io.Discard}, mode)
+
+	messages := make([][]byte, 0)
+	for {
+		msg, err := conn.ReadMessage()
+		if errors.Is(err, io.EOF) {
+			return messages
+		}
+		if err != nil {
+			t.Fatalf("Failed to read message: %s", err.Error())
+		}
+		messages = append(messages, msg)
+	}
+}
+
+func TestSplitAndMergedReads(t *testing.T) {
+	messages := [][]byte{
+		[]byte(`{"type":"query"}`),
+		[]byte(`{"type":"cmd","cmd":"nav_start"}`),
+		[]byte(`{"waypoints":[` + strings.Repeat(`{"latitude":56.261437,"longitude":44.191453},`, 200) +
+			`{"latitude":56.261437,"longitude":44.191453}]}`),
+	}
+
+	for _, mode := range []Mode{ModeCompat, ModeNewline, ModeLengthPrefixed} {
+		data := frame(t, mode, messages)
+		for _, chunks := range [][]int{nil, {1}, {3, 7, 4096}, {17}} {
+			received := readAll(t, mode, data, chunks)
+			if len(received) != len(messages) {
+				t.Fatalf("%s: expected %d messages, got %d", mode, len(messages), len(received))
+			}
+			for i := range messages {
+				if !bytes.Equal(received[i], messages[i]) {
+					t.Errorf("%s: expected message %d to be %s, got %s", mode, i, messages[i], received[i])
+				}
+			}
+		}
+	}
+}
+
+func TestCompatWhitespace(t *testing.T) {
+	data := []byte("{\"a\":1}\n  {\"b\":2}{\"c\":3}")
+	received := readAll(t, ModeCompat, data, []int{5})
+	if len(received) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(received))
+	}
+	if string(received[1]) != `{"b":2}` {
+		t.Errorf("Expected second message to be {\"b\":2}, got %s", received[1])
+	}
+}
+
+func TestCorruptedStream(t *testing.T) {
+	tests := []struct {
+		mode Mode
+		data []byte
+	}{
+		{ModeCompat, []byte(`{"a":1}}`)},
+		{ModeLengthPrefixed, []byte{0xff, 0xff, 0xff, 0xff, '{', '}'}},
+		{ModeNewline, bytes.Repeat([]byte("a"), MaxMessageSize+10)},
+		{ModeCompat, append([]byte(`{"a":"`), bytes.Repeat([]byte("a"), MaxMessageSize+10)...)},
+	}
+
+	for _, test := range tests {
+		conn := NewConn(&readWriter{Reader: bytes.NewReader(test.data), Writer: io.Discard}, test.mode)
+		var err error
+		for err == nil {
+			_, err = conn.ReadMessage()
+		}
+		if !errors.Is(err, ErrCorrupted) {
+			t.Errorf("%s: expected corrupted stream error, got %v", test.mode, err)
+		}
+	}
+}
+
+func TestCompatMessageSizePerMessage(t *testing.T) {
+	// the stream is longer than the limit, every message is below it
+	message := []byte(`{"a":"` + strings.Repeat("a", MaxMessageSize/3) + `"}`)
+	messages := [][]byte{message, message, message, message}
+	received := readAll(t, ModeCompat, frame(t, ModeCompat, messages), []int{4096})
+	if len(received) != len(messages) {
+		t.Errorf("Expected %d messages, got %d", len(messages), len(received))
+	}
+}
+
+func TestTruncatedMessage(t *testing.T) {
+	for _, mode := range []Mode{ModeCompat, ModeLengthPrefixed} {
+		data := frame(t, mode, [][]byte{[]byte(`{"type":"query"}`)})
+		conn := NewConn(&readWriter{Reader: bytes.NewReader(data[:len(data)-3]), Writer: io.Discard}, mode)
+		_, err := conn.ReadMessage()
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("%s: expected EOF for truncated message, got %v", mode, err)
+		}
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		name string
+		mode Mode
+	}{
+		{"", ModeCompat},
+		{"compat", ModeCompat},
+		{"newline", ModeNewline},
+		{"length", ModeLengthPrefixed},
+	}
+	for _, test := range tests {
+		mode, err := ParseMode(test.name)
+		if err != nil || mode != test.mode {
+			t.Errorf("Expected %s to be parsed as %s, got %s, %v", test.name, test.mode, mode, err)
+		}
+	}
+
+	if _, err := ParseMode("xml"); err == nil {
+		t.Errorf("Expected error for unknown framing mode")
+	}
+}
+
+func FuzzReadMessage(f *testing.F) {
+	f.Add("query", "set_waypoints", uint8(1), uint8(3))
+	f.Add("{\"nested\":[1,2]}\n", "", uint8(0), uint8(255))
+	f.Add("\x00\x01\xff", "\\\"", uint8(7), uint8(2))
+
+	f.Fuzz(func(t *testing.T, first string, second string, chunk1 uint8, chunk2 uint8) {
+		// JSON encoding makes the payload valid for every mode
+		messages := make([][]byte, 0)
+		for _, s := range []string{first, second, first + second} {
+			msg, err := json.Marshal(map[string]string{"cmd": s})
+			if err != nil {
+				t.Skip()
+			}
+			messages = append(messages, msg)
+		}
+
+		for _, mode := range []Mode{ModeCompat, ModeNewline, ModeLengthPrefixed} {
+			data := frame(t, mode, messages)
+			received := readAll(t, mode, data, []int{int(chunk1), int(chunk2)})
+			if len(received) != len(messages) {
+				t.Fatalf("%s: expected %d messages, got %d", mode, len(messages), len(received))
+			}
+			for i := range messages {
+				if !bytes.Equal(received[i], messages[i]) {
+					t.Errorf("%s: expected message %q, got %q", mode, messages[i], received[i])
+				}
+			}
+		}
+	})
+}
+
+func FuzzCorruptedStream(f *testing.F) {
+	f.Add([]byte(`{"type":"query"}`))
+	f.Add([]byte{0, 0, 0, 2, '{', '}'})
+	f.Add([]byte("}{\n\n"))
+
+	// arbitrary input must never hang or panic the reader
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, mode := range []Mode{ModeCompat, ModeNewline, ModeLengthPrefixed} {
+			conn := NewConn(&readWriter{Reader: &chunkReader{data: data, chunks: []int{3}}, Writer: io.Discard}, mode)
+			for i := 0; i <= len(data); i++ {
+				msg, err := conn.ReadMessage()
+				if err != nil {
+					break
+				}
+				if len(msg) > MaxMessageSize {
+					t.Fatalf("%s: message exceeds the size limit", mode)
+				}
+			}
+		}
+	})
+}
@@ -6,24 +6,47 @@ const (
 )
 
 const (
-	cmdNavStart        = "nav_start"
-	cmdNavStop         = "nav_stop"
-	cmdNetLoss         = "net_loss"
-	cmdSetWaypoints    = "set_waypoints"
-	cmdAddWaypoint     = "add_waypoint"
-	cmdClearWaypoints  = "clear_waypoints"
-	cmdSetHomeWaypoint = "set_home_waypoint"
+	cmdNavStart          = "nav_start"
+	cmdNavStop           = "nav_stop"
+	cmdNetLoss           = "net_loss"
+	cmdSetWaypoints      = "set_waypoints"
+	cmdAddWaypoint       = "add_waypoint"
+	cmdClearWaypoints    = "clear_waypoints"
+	cmdSetHomeWaypoint   = "set_home_waypoint"
+	cmdHoldPosition      = "hold_position"
+	cmdSetNoGoZones      = "set_no_go_zones"
+	cmdCalibrationStart  = "calibration_start"
+	cmdCalibrationStop   = "calibration_stop"
+	cmdCalibrationStatus = "calibration_status"
 )
 
+// arrival radius is in meters, hold time is in milliseconds,
+// arrival mode is one of radius, line or cpa, the configured mode is used if it is empty,
+// detour waypoints are inserted by the route planner around the no-go zones
 type Waypoint struct {
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
+	Latitude         float64 `json:"latitude"`
+	Longitude        float64 `json:"longitude"`
+	TargetSpeedKnots float64 `json:"targetSpeedKnots,omitempty"`
+	ArrivalRadius    float64 `json:"arrivalRadius,omitempty"`
+	HoldTime         int64   `json:"holdTime,omitempty"`
+	Action           string  `json:"action,omitempty"`
+	ArrivalMode      string  `json:"arrivalMode,omitempty"`
+	Detour           bool    `json:"detour,omitempty"`
+}
+
+// action is one of stop, loiter, home, loop or reverse, zero repeat repeats the route forever
+type MissionEnd struct {
+	Action string `json:"action"`
+	Repeat int    `json:"repeat"`
 }
 
 type Request struct {
-	Type      string      `json:"type"`
-	Cmd       string      `json:"cmd"`
-	Waypoints []*Waypoint `json:"waypoints"`
+	Type       string      `json:"type"`
+	Cmd        string      `json:"cmd"`
+	Waypoints  []*Waypoint `json:"waypoints"`
+	MissionEnd *MissionEnd `json:"missionEnd,omitempty"`
+	// polygons of waypoints, an empty list clears the zones
+	NoGoZones [][]*Waypoint `json:"noGoZones,omitempty"`
 }
 
 type PositionData struct {
@@ -32,22 +55,78 @@ type PositionData struct {
 	Longitude     float64 `json:"longitude"`
 	SpeedKnots    float64 `json:"speed_knots"`
 	SpeedKm       float64 `json:"speed_km"`
-	Angle         float64 `json:"angle"`
+	Hdop          float64 `json:"hdop"`
+	FixType       uint8   `json:"fix_type"`
+	// bearing from North in degrees, [0, 360)
+	Angle float64 `json:"angle"`
+	// acquisition time of position and bearing data in Unix milliseconds, 0 if not available
+	Timestamp        int64 `json:"timestamp"`
+	BearingTimestamp int64 `json:"bearing_timestamp"`
+}
+
+type CommandMetrics struct {
+	Sent         uint64 `json:"sent"`
+	Coalesced    uint64 `json:"coalesced"`
+	Deduplicated uint64 `json:"deduplicated"`
+	Dropped      uint64 `json:"dropped"`
+	Failed       uint64 `json:"failed"`
 }
 
 type ShipData struct {
-	Speed    string `json:"speed"`
-	Steering string `json:"steering"`
+	Speed     string          `json:"speed"`
+	Steering  string          `json:"steering"`
+	Timestamp int64           `json:"timestamp"`
+	Commands  *CommandMetrics `json:"commands"`
+}
+
+// waypoint action, timestamp is in Unix milliseconds
+type MissionEvent struct {
+	Seq           uint64  `json:"seq"`
+	Timestamp     int64   `json:"timestamp"`
+	WaypointIndex int     `json:"waypointIndex"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+	Action        string  `json:"action"`
+}
+
+// timestamp of the last breach is in Unix milliseconds, 0 if there was no breach
+type GeofenceStatus struct {
+	Enabled   bool   `json:"enabled"`
+	Breached  bool   `json:"breached"`
+	Reason    string `json:"reason"`
+	Timestamp int64  `json:"timestamp"`
 }
 
 type QueryResponse struct {
-	PositionData *PositionData `json:"positionData"`
-	ShipData     *ShipData     `json:"shipData"`
-	Waypoints    []*Waypoint   `json:"waypoints"`
-	Error        string        `json:"error"`
+	PositionData    *PositionData   `json:"positionData"`
+	RawPositionData *PositionData   `json:"rawPositionData"`
+	ShipData        *ShipData       `json:"shipData"`
+	Waypoints       []*Waypoint     `json:"waypoints"`
+	MissionEvents   []*MissionEvent `json:"missionEvents"`
+	Geofence        *GeofenceStatus `json:"geofence"`
+	Error           string          `json:"error"`
+}
+
+type CalibrationStatus struct {
+	Active     bool       `json:"active"`
+	Samples    int        `json:"samples"`
+	Calibrated bool       `json:"calibrated"`
+	Offset     [3]float64 `json:"offset"`
+	Error      string     `json:"error"`
+}
+
+// code is one of empty_route, invalid_coordinates, leg_too_long or outside_geofence,
+// waypoint index is -1 if the problem concerns the whole route
+type MissionProblem struct {
+	WaypointIndex int    `json:"waypointIndex"`
+	Code          string `json:"code"`
+	Message       string `json:"message"`
 }
 
 type CommandResponse struct {
-	Status string `json:"status"`
-	Error  string `json:"error"`
+	Status      string             `json:"status"`
+	Error       string             `json:"error"`
+	Calibration *CalibrationStatus `json:"calibration,omitempty"`
+	// problems found in the route by nav_start and set_waypoints
+	Problems []*MissionProblem `json:"problems,omitempty"`
 }
@@ -5,8 +5,10 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/moosethebrown/ship-nav/adapters/framing"
 	"github.com/moosethebrown/ship-nav/core"
 	"github.com/moosethebrown/ship-nav/core/model"
 	"github.com/rs/zerolog"
@@ -15,6 +17,7 @@ import (
 type Adapter struct {
 	logger                *zerolog.Logger
 	socketName            string
+	framing               framing.Mode
 	listener              net.Listener
 	conns                 map[string]net.Conn
 	shipDataProvider      core.ShipDataProvider
@@ -22,19 +25,23 @@ type Adapter struct {
 	waypointsDataProvider core.WaypointDataProvider
 	navController         core.NavigationController
 	waypointsUpdater      core.WaypointsUpdater
+	positionCalibrator    core.PositionCalibrator
 }
 
-func NewAdapter(socketName string, sp core.ShipDataProvider,
+func NewAdapter(socketName string, framingMode framing.Mode, sp core.ShipDataProvider,
 	pp core.PositionDataProvider, wp core.WaypointDataProvider,
-	nc core.NavigationController, wu core.WaypointsUpdater, logger *zerolog.Logger) *Adapter {
+	nc core.NavigationController, wu core.WaypointsUpdater, pc core.PositionCalibrator,
+	logger *zerolog.Logger) *Adapter {
 	return &Adapter{
 		socketName:            socketName,
+		framing:               framingMode,
 		conns:                 make(map[string]net.Conn),
 		shipDataProvider:      sp,
 		positionDataProvider:  pp,
 		waypointsDataProvider: wp,
 		navController:         nc,
 		waypointsUpdater:      wu,
+		positionCalibrator:    pc,
 		logger:                logger,
 	}
 }
@@ -108,9 +115,9 @@ func (a *Adapter) handleClient(clientId string) {
 	}
 	defer conn.Close()
 
+	framedConn := framing.NewConn(conn, a.framing)
 	for {
-		buf := make([]byte, 4096)
-		n, err := conn.Read(buf)
+		data, err := framedConn.ReadMessage()
 		if err != nil {
 			a.logger.Error().Err(err).Msgf("Error reading from client %s",
 				clientId)
@@ -118,7 +125,7 @@ func (a *Adapter) handleClient(clientId string) {
 		}
 
 		var rq Request
-		err = json.Unmarshal(buf[:n], &rq)
+		err = json.Unmarshal(data, &rq)
 		if err != nil {
 			a.logger.Error().Err(err).Msg("Error unmarshalling request")
 			break
@@ -130,7 +137,7 @@ func (a *Adapter) handleClient(clientId string) {
 			break
 		}
 
-		_, err = conn.Write(resp)
+		err = framedConn.WriteMessage(resp)
 		if err != nil {
 			a.logger.Error().Err(err).Msg("Failed to send response")
 			break
@@ -150,35 +157,159 @@ func (a *Adapter) handleRequest(rq *Request) ([]byte, error) {
 
 func (a *Adapter) handleQuery() ([]byte, error) {
 	var resp QueryResponse
-	bearing, position := a.positionDataProvider.GetPositionData()
-	resp.PositionData = &PositionData{
-		Angle:         bearing.AngleDeg(),
-		NumSatellites: position.NumSatellites,
-		Latitude:      position.Latitude,
-		Longitude:     position.Longitude,
-		SpeedKnots:    position.SpeedKnots,
-		SpeedKm:       position.SpeedKm,
-	}
+	resp.PositionData = newPositionData(a.positionDataProvider.GetPositionData())
+	resp.RawPositionData = newPositionData(a.positionDataProvider.GetRawPositionData())
 
 	shipData := a.shipDataProvider.GetShipData()
 	resp.ShipData = &ShipData{
-		Speed:    shipData.Speed,
-		Steering: shipData.Steering,
+		Speed:     shipData.Speed.String(),
+		Steering:  shipData.Steering.String(),
+		Timestamp: unixMilli(shipData.Timestamp),
+		Commands: &CommandMetrics{
+			Sent:         shipData.Commands.Sent,
+			Coalesced:    shipData.Commands.Coalesced,
+			Deduplicated: shipData.Commands.Deduplicated,
+			Dropped:      shipData.Commands.Dropped,
+			Failed:       shipData.Commands.Failed,
+		},
 	}
 
 	waypoints := a.waypointsDataProvider.GetWaypoints()
 	resp.Waypoints = make([]*Waypoint, len(waypoints))
 	for i, waypoint := range waypoints {
-		resp.Waypoints[i] = &Waypoint{
-			Latitude:  waypoint.Latitude,
-			Longitude: waypoint.Longitude,
+		resp.Waypoints[i] = newWaypoint(waypoint)
+	}
+
+	missionEvents := a.waypointsDataProvider.GetMissionEvents()
+	resp.MissionEvents = make([]*MissionEvent, len(missionEvents))
+	for i, event := range missionEvents {
+		resp.MissionEvents[i] = &MissionEvent{
+			Seq:           event.Seq,
+			Timestamp:     unixMilli(event.Timestamp),
+			WaypointIndex: event.WaypointIndex,
+			Latitude:      event.Latitude,
+			Longitude:     event.Longitude,
+			Action:        event.Action,
 		}
 	}
 
+	geofence := a.positionDataProvider.GetGeofenceStatus()
+	resp.Geofence = &GeofenceStatus{
+		Enabled:   geofence.Enabled,
+		Breached:  geofence.Breached,
+		Reason:    geofence.Reason,
+		Timestamp: unixMilli(geofence.Timestamp),
+	}
+
 	respData, err := json.Marshal(resp)
 	return respData, err
 }
 
+// reports the problems found in the route in the command response, returns false if there are any
+func setMissionProblems(resp *CommandResponse, problems []*model.MissionProblem) bool {
+	if len(problems) == 0 {
+		return true
+	}
+
+	resp.Status = "failure"
+	resp.Error = "mission validation failed"
+	resp.Problems = make([]*MissionProblem, len(problems))
+	for i, problem := range problems {
+		resp.Problems[i] = &MissionProblem{
+			WaypointIndex: problem.WaypointIndex,
+			Code:          problem.Code,
+			Message:       problem.Message,
+		}
+	}
+	return false
+}
+
+func newWaypoint(waypoint *model.Waypoint) *Waypoint {
+	return &Waypoint{
+		Latitude:         waypoint.Latitude,
+		Longitude:        waypoint.Longitude,
+		TargetSpeedKnots: waypoint.TargetSpeedKnots,
+		ArrivalRadius:    waypoint.ArrivalRadius,
+		HoldTime:         waypoint.HoldTime.Milliseconds(),
+		Action:           waypoint.Action,
+		ArrivalMode:      waypoint.Arrival.String(),
+		Detour:           waypoint.Detour,
+	}
+}
+
+func (wp *Waypoint) toModel() (*model.Waypoint, error) {
+	arrival, err := model.ParseArrivalMode(wp.ArrivalMode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Waypoint{
+		Latitude:         wp.Latitude,
+		Longitude:        wp.Longitude,
+		TargetSpeedKnots: wp.TargetSpeedKnots,
+		ArrivalRadius:    wp.ArrivalRadius,
+		HoldTime:         time.Duration(wp.HoldTime) * time.Millisecond,
+		Action:           wp.Action,
+		Arrival:          arrival,
+	}, nil
+}
+
+func toModelZones(zones [][]*Waypoint) ([]*model.Polygon, error) {
+	polygons := make([]*model.Polygon, len(zones))
+	for i, zone := range zones {
+		vertices := make([]*model.Waypoint, len(zone))
+		for j, vertex := range zone {
+			if vertex == nil {
+				return nil, fmt.Errorf("no-go zone %d has an undefined vertex", i)
+			}
+			vertices[j] = &model.Waypoint{
+				Latitude:  vertex.Latitude,
+				Longitude: vertex.Longitude,
+			}
+		}
+		polygon, err := model.NewPolygon(vertices)
+		if err != nil {
+			return nil, fmt.Errorf("no-go zone %d: %w", i, err)
+		}
+		polygons[i] = polygon
+	}
+	return polygons, nil
+}
+
+func toModelWaypoints(waypoints []*Waypoint) ([]*model.Waypoint, error) {
+	wps := make([]*model.Waypoint, len(waypoints))
+	for i, waypoint := range waypoints {
+		wp, err := waypoint.toModel()
+		if err != nil {
+			return nil, err
+		}
+		wps[i] = wp
+	}
+	return wps, nil
+}
+
+func newPositionData(bearing *model.Bearing, position *model.Position) *PositionData {
+	return &PositionData{
+		Angle:            bearing.Heading().Deg(),
+		NumSatellites:    position.NumSatellites,
+		Latitude:         position.Latitude,
+		Longitude:        position.Longitude,
+		SpeedKnots:       position.SpeedKnots,
+		SpeedKm:          position.SpeedKm,
+		Hdop:             position.Hdop,
+		FixType:          uint8(position.FixType),
+		Timestamp:        unixMilli(position.Timestamp),
+		BearingTimestamp: unixMilli(bearing.Timestamp),
+	}
+}
+
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
 func (a *Adapter) handleCommand(rq *Request) ([]byte, error) {
 	resp := &CommandResponse{
 		Status: "ok",
@@ -186,7 +317,7 @@ func (a *Adapter) handleCommand(rq *Request) ([]byte, error) {
 
 	switch rq.Cmd {
 	case cmdNavStart:
-		a.navController.StartNavigation()
+		setMissionProblems(resp, a.navController.StartNavigation())
 	case cmdNavStop:
 		a.navController.StopNavigation()
 	case cmdNetLoss:
@@ -197,23 +328,43 @@ func (a *Adapter) handleCommand(rq *Request) ([]byte, error) {
 			resp.Error = "no waypoints provided"
 			break
 		}
-		wps := make([]*model.Waypoint, len(rq.Waypoints))
-		for i, wp := range rq.Waypoints {
-			wps[i] = &model.Waypoint{
-				Latitude:  wp.Latitude,
-				Longitude: wp.Longitude,
+		var missionEnd model.MissionEnd
+		if rq.MissionEnd != nil {
+			action, err := model.ParseMissionEndAction(rq.MissionEnd.Action)
+			if err != nil || rq.MissionEnd.Repeat < 0 {
+				resp.Status = "failure"
+				resp.Error = "invalid mission end"
+				break
+			}
+			missionEnd = model.MissionEnd{
+				Action: action,
+				Repeat: rq.MissionEnd.Repeat,
 			}
 		}
-		a.waypointsUpdater.SetWaypoints(wps)
+		wps, err := toModelWaypoints(rq.Waypoints)
+		if err != nil {
+			resp.Status = "failure"
+			resp.Error = err.Error()
+			break
+		}
+		if !setMissionProblems(resp, a.waypointsUpdater.SetWaypoints(wps)) {
+			break
+		}
+		// the configured mission end is used if it is not provided
+		if rq.MissionEnd != nil {
+			a.waypointsUpdater.SetMissionEnd(missionEnd)
+		}
 	case cmdAddWaypoint:
 		if rq.Waypoints == nil || len(rq.Waypoints) == 0 {
 			resp.Status = "failure"
 			resp.Error = "waypoint is not provided"
 			break
 		}
-		wp := &model.Waypoint{
-			Latitude:  rq.Waypoints[0].Latitude,
-			Longitude: rq.Waypoints[0].Longitude,
+		wp, err := rq.Waypoints[0].toModel()
+		if err != nil {
+			resp.Status = "failure"
+			resp.Error = err.Error()
+			break
 		}
 		a.waypointsUpdater.AddWaypoint(wp)
 	case cmdClearWaypoints:
@@ -224,11 +375,47 @@ func (a *Adapter) handleCommand(rq *Request) ([]byte, error) {
 			resp.Error = "waypoint is not provided"
 			break
 		}
-		wp := &model.Waypoint{
-			Latitude:  rq.Waypoints[0].Latitude,
-			Longitude: rq.Waypoints[0].Longitude,
+		wp, err := rq.Waypoints[0].toModel()
+		if err != nil {
+			resp.Status = "failure"
+			resp.Error = err.Error()
+			break
 		}
 		a.waypointsUpdater.SetHomeWaypoint(wp)
+	case cmdHoldPosition:
+		// the current position is held if the waypoint is not provided
+		var wp *model.Waypoint
+		if len(rq.Waypoints) > 0 {
+			var err error
+			wp, err = rq.Waypoints[0].toModel()
+			if err != nil {
+				resp.Status = "failure"
+				resp.Error = err.Error()
+				break
+			}
+		}
+		a.navController.HoldPosition(wp)
+	case cmdSetNoGoZones:
+		zones, err := toModelZones(rq.NoGoZones)
+		if err != nil {
+			resp.Status = "failure"
+			resp.Error = err.Error()
+			break
+		}
+		a.waypointsUpdater.SetNoGoZones(zones)
+	case cmdCalibrationStart:
+		a.positionCalibrator.StartCalibration()
+	case cmdCalibrationStop:
+		a.positionCalibrator.StopCalibration()
+	case cmdCalibrationStatus:
+		status := a.positionCalibrator.CalibrationStatus()
+		resp.Calibration = &CalibrationStatus{
+			Active:     status.Active,
+			Samples:    status.Samples,
+			Calibrated: status.Calibrated,
+			Offset:     status.Offset,
+			Error:      status.Error,
+		}
 	}
 
 	respData, err := json.Marshal(resp)
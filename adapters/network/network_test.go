@@ -2,11 +2,13 @@ package network
 
 import (
 	"encoding/json"
+	"math"
 	"net"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/moosethebrown/ship-nav/adapters/framing"
 	"github.com/moosethebrown/ship-nav/core/model"
 	"github.com/rs/zerolog"
 )
@@ -24,29 +26,52 @@ func (m *mockShipDataProvider) GetShipData() *model.ShipData {
 }
 
 type mockPositionDataProvider struct {
-	position *model.Position
-	bearing  *model.Bearing
+	position    *model.Position
+	bearing     *model.Bearing
+	rawPosition *model.Position
+	rawBearing  *model.Bearing
+	geofence    model.GeofenceStatus
 }
 
 func (m *mockPositionDataProvider) GetPositionData() (*model.Bearing, *model.Position) {
 	return m.bearing, m.position
 }
 
+func (m *mockPositionDataProvider) GetRawPositionData() (*model.Bearing, *model.Position) {
+	return m.rawBearing, m.rawPosition
+}
+
+func (m *mockPositionDataProvider) GetGeofenceStatus() *model.GeofenceStatus {
+	return &m.geofence
+}
+
 type mockWaypointDataProvider struct {
-	waypoints []*model.Waypoint
+	waypoints     []*model.Waypoint
+	missionEvents []*model.MissionEvent
 }
 
 func (m *mockWaypointDataProvider) GetWaypoints() []*model.Waypoint {
 	return m.waypoints
 }
 
+func (m *mockWaypointDataProvider) GetMissionEvents() []*model.MissionEvent {
+	return m.missionEvents
+}
+
 type mockNavController struct {
-	nav     bool
-	netLoss bool
+	nav         bool
+	netLoss     bool
+	hold        bool
+	loiterPoint *model.Waypoint
+	problems    []*model.MissionProblem
 }
 
-func (m *mockNavController) StartNavigation() {
+func (m *mockNavController) StartNavigation() []*model.MissionProblem {
+	if len(m.problems) > 0 {
+		return m.problems
+	}
 	m.nav = true
+	return nil
 }
 
 func (m *mockNavController) StopNavigation() {
@@ -57,13 +82,25 @@ func (m *mockNavController) NetworkLost() {
 	m.netLoss = true
 }
 
+func (m *mockNavController) HoldPosition(waypoint *model.Waypoint) {
+	m.hold = true
+	m.loiterPoint = waypoint
+}
+
 type mockWaypointsUpdater struct {
 	waypoints    []*model.Waypoint
 	homeWaypoint *model.Waypoint
+	missionEnd   *model.MissionEnd
+	noGoZones    []*model.Polygon
+	problems     []*model.MissionProblem
 }
 
-func (m *mockWaypointsUpdater) SetWaypoints(waypoints []*model.Waypoint) {
+func (m *mockWaypointsUpdater) SetWaypoints(waypoints []*model.Waypoint) []*model.MissionProblem {
+	if len(m.problems) > 0 {
+		return m.problems
+	}
 	m.waypoints = waypoints
+	return nil
 }
 
 func (m *mockWaypointsUpdater) AddWaypoint(waypoint *model.Waypoint) {
@@ -78,11 +115,46 @@ func (m *mockWaypointsUpdater) SetHomeWaypoint(waypoint *model.Waypoint) {
 	m.homeWaypoint = waypoint
 }
 
+func (m *mockWaypointsUpdater) SetMissionEnd(missionEnd model.MissionEnd) {
+	m.missionEnd = &missionEnd
+}
+
+func (m *mockWaypointsUpdater) SetNoGoZones(zones []*model.Polygon) {
+	m.noGoZones = zones
+}
+
+type mockPositionCalibrator struct {
+	calibrating bool
+}
+
+func (m *mockPositionCalibrator) StartCalibration() {
+	m.calibrating = true
+}
+
+func (m *mockPositionCalibrator) StopCalibration() {
+	m.calibrating = false
+}
+
+func (m *mockPositionCalibrator) CalibrationStatus() *model.CalibrationStatus {
+	return &model.CalibrationStatus{
+		Active:     m.calibrating,
+		Samples:    120,
+		Calibrated: true,
+		Offset:     [3]float64{1.0, 2.0, 3.0},
+	}
+}
+
 func TestQuery(t *testing.T) {
 	msdp := &mockShipDataProvider{
 		shipData: &model.ShipData{
-			Speed:    "rev100",
-			Steering: "right60",
+			Speed:     model.Reverse(100),
+			Steering:  model.Right(60),
+			Timestamp: time.UnixMilli(1700000000300),
+			Commands: model.CommandMetrics{
+				Sent:         12,
+				Coalesced:    3,
+				Deduplicated: 40,
+			},
 		},
 	}
 	mpdp := &mockPositionDataProvider{
@@ -92,21 +164,53 @@ func TestQuery(t *testing.T) {
 			Longitude:     44.14972,
 			SpeedKnots:    5.24,
 			SpeedKm:       9.7,
+			Timestamp:     time.UnixMilli(1700000000100),
 		},
 		bearing: model.NewBearing(0.0),
+		rawPosition: &model.Position{
+			NumSatellites: 3,
+			Latitude:      56.285131,
+			Longitude:     44.149701,
+			SpeedKnots:    5.5,
+			SpeedKm:       10.2,
+		},
+		rawBearing: model.NewBearing(0.0),
 	}
 	mpdp.bearing.SetInt(1, 2)
+	mpdp.bearing.Timestamp = time.UnixMilli(1700000000200)
+	mpdp.rawBearing.SetAngleDeg(-115.0)
+	mpdp.geofence = model.GeofenceStatus{
+		Enabled:   true,
+		Breached:  true,
+		Reason:    "outside of the keep-in area",
+		Timestamp: time.UnixMilli(1700000000300),
+	}
 	mwdp := &mockWaypointDataProvider{}
 	mwdp.waypoints = make([]*model.Waypoint, 1)
 	mwdp.waypoints[0] = &model.Waypoint{
-		Latitude:  56.261437,
-		Longitude: 44.191453,
+		Latitude:      56.261437,
+		Longitude:     44.191453,
+		ArrivalRadius: 5.0,
+		HoldTime:      30 * time.Second,
+		Action:        "sample",
+		Arrival:       model.ArrivalModeClosestApproach,
+	}
+	mwdp.missionEvents = []*model.MissionEvent{
+		{
+			Seq:           7,
+			Timestamp:     time.UnixMilli(1700000000400),
+			WaypointIndex: 2,
+			Latitude:      56.285119,
+			Longitude:     44.14972,
+			Action:        "photo",
+		},
 	}
 	mnc := &mockNavController{}
 	mwu := &mockWaypointsUpdater{}
 	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
 
-	adapter := NewAdapter(testSocket, msdp, mpdp, mwdp, mnc, mwu, &logger)
+	adapter := NewAdapter(testSocket, framing.ModeCompat, msdp, mpdp, mwdp, mnc, mwu,
+		&mockPositionCalibrator{}, &logger)
 	go adapter.Run()
 	defer adapter.Stop()
 
@@ -171,6 +275,48 @@ func TestQuery(t *testing.T) {
 		t.Errorf("Expected bearing angle to be 63.43494882292201, got %f",
 			angleDeg)
 	}
+	if resp.PositionData.Timestamp != 1700000000100 {
+		t.Errorf("Expected position timestamp to be 1700000000100, got %d",
+			resp.PositionData.Timestamp)
+	}
+	if resp.PositionData.BearingTimestamp != 1700000000200 {
+		t.Errorf("Expected bearing timestamp to be 1700000000200, got %d",
+			resp.PositionData.BearingTimestamp)
+	}
+	if resp.ShipData.Speed != "rev100" {
+		t.Errorf("Expected speed to be rev100, got %s", resp.ShipData.Speed)
+	}
+	if resp.ShipData.Steering != "right60" {
+		t.Errorf("Expected steering to be right60, got %s", resp.ShipData.Steering)
+	}
+	if resp.ShipData.Timestamp != 1700000000300 {
+		t.Errorf("Expected ship data timestamp to be 1700000000300, got %d",
+			resp.ShipData.Timestamp)
+	}
+	if resp.ShipData.Commands == nil || resp.ShipData.Commands.Sent != 12 ||
+		resp.ShipData.Commands.Coalesced != 3 || resp.ShipData.Commands.Deduplicated != 40 {
+		t.Errorf("Expected command metrics sent 12, coalesced 3, deduplicated 40, got %v",
+			resp.ShipData.Commands)
+	}
+	if resp.RawPositionData == nil {
+		t.Fatalf("Expected raw position data in query response")
+	}
+	if resp.RawPositionData.Latitude != 56.285131 {
+		t.Errorf("Expected raw latitude to be 56.285131, got %f",
+			resp.RawPositionData.Latitude)
+	}
+	if resp.RawPositionData.Longitude != 44.149701 {
+		t.Errorf("Expected raw longitude to be 44.149701, got %f",
+			resp.RawPositionData.Longitude)
+	}
+	if resp.RawPositionData.SpeedKnots != 5.5 {
+		t.Errorf("Expected raw speed to be 5.5 knots, got %f",
+			resp.RawPositionData.SpeedKnots)
+	}
+	if math.Abs(resp.RawPositionData.Angle-245.0) > tolerance {
+		t.Errorf("Expected raw bearing angle to be 245.0, got %f",
+			resp.RawPositionData.Angle)
+	}
 	if len(resp.Waypoints) != 1 {
 		t.Fatalf("Expected to get 1 waypoint, got %d",
 			len(resp.Waypoints))
@@ -183,6 +329,24 @@ func TestQuery(t *testing.T) {
 		t.Errorf("Expected waypoint longitude to be 44.191453, got %f",
 			resp.Waypoints[0].Longitude)
 	}
+	if resp.Waypoints[0].ArrivalRadius != 5.0 || resp.Waypoints[0].HoldTime != 30000 ||
+		resp.Waypoints[0].Action != "sample" || resp.Waypoints[0].ArrivalMode != "cpa" {
+		t.Errorf("Expected waypoint arrival radius 5.0, hold time 30000, action sample, arrival mode cpa, got %v",
+			resp.Waypoints[0])
+	}
+	if resp.Geofence == nil || !resp.Geofence.Enabled || !resp.Geofence.Breached ||
+		resp.Geofence.Reason != "outside of the keep-in area" || resp.Geofence.Timestamp != 1700000000300 {
+		t.Errorf("Unexpected geofence status %v", resp.Geofence)
+	}
+	if len(resp.MissionEvents) != 1 {
+		t.Fatalf("Expected to get 1 mission event, got %d",
+			len(resp.MissionEvents))
+	}
+	event := resp.MissionEvents[0]
+	if event.Seq != 7 || event.Timestamp != 1700000000400 || event.WaypointIndex != 2 ||
+		event.Action != "photo" {
+		t.Errorf("Unexpected mission event %v", event)
+	}
 }
 
 func TestCommand(t *testing.T) {
@@ -193,7 +357,8 @@ func TestCommand(t *testing.T) {
 	mwu := &mockWaypointsUpdater{}
 	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
 
-	adapter := NewAdapter(testSocket, msdp, mpdp, mwdp, mnc, mwu, &logger)
+	adapter := NewAdapter(testSocket, framing.ModeCompat, msdp, mpdp, mwdp, mnc, mwu,
+		&mockPositionCalibrator{}, &logger)
 	go adapter.Run()
 	defer adapter.Stop()
 
@@ -283,6 +448,48 @@ func TestCommand(t *testing.T) {
 		t.Errorf("Expected longitude to be 44.14972, got %f",
 			mwu.waypoints[0].Longitude)
 	}
+	if mwu.missionEnd != nil {
+		t.Errorf("Expected configured mission end to be used, got %v", mwu.missionEnd)
+	}
+
+	rq = &Request{
+		Type: rqTypeCmd,
+		Cmd:  cmdSetWaypoints,
+		Waypoints: []*Waypoint{
+			{
+				Latitude:  56.285119,
+				Longitude: 44.14972,
+			},
+		},
+		MissionEnd: &MissionEnd{
+			Action: "circle",
+		},
+	}
+	resp, err = sendCommand(conn, rq)
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "failure" {
+		t.Errorf("Expected failure command response status, got %s",
+			resp.Status)
+	}
+
+	rq.MissionEnd = &MissionEnd{
+		Action: "loop",
+		Repeat: 3,
+	}
+	resp, err = sendCommand(conn, rq)
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "ok" {
+		t.Errorf("Expected ok command response status, got %s",
+			resp.Status)
+	}
+	if mwu.missionEnd == nil || mwu.missionEnd.Action != model.MissionEndLoop ||
+		mwu.missionEnd.Repeat != 3 {
+		t.Errorf("Expected mission end loop 3 times, got %v", mwu.missionEnd)
+	}
 
 	rq = &Request{
 		Type:      rqTypeCmd,
@@ -290,8 +497,13 @@ func TestCommand(t *testing.T) {
 		Waypoints: make([]*Waypoint, 1),
 	}
 	rq.Waypoints[0] = &Waypoint{
-		Latitude:  56.261437,
-		Longitude: 44.191453,
+		Latitude:         56.261437,
+		Longitude:        44.191453,
+		TargetSpeedKnots: 3.5,
+		ArrivalRadius:    8.0,
+		HoldTime:         1500,
+		Action:           "photo",
+		ArrivalMode:      "line",
 	}
 	resp, err = sendCommand(conn, rq)
 	if err != nil {
@@ -313,6 +525,40 @@ func TestCommand(t *testing.T) {
 		t.Errorf("Expected wp2 longitude to be 44.191453, got %f",
 			mwu.waypoints[1].Longitude)
 	}
+	if mwu.waypoints[1].TargetSpeedKnots != 3.5 {
+		t.Errorf("Expected wp2 target speed to be 3.5, got %f",
+			mwu.waypoints[1].TargetSpeedKnots)
+	}
+	if mwu.waypoints[1].ArrivalRadius != 8.0 {
+		t.Errorf("Expected wp2 arrival radius to be 8.0, got %f",
+			mwu.waypoints[1].ArrivalRadius)
+	}
+	if mwu.waypoints[1].HoldTime != 1500*time.Millisecond {
+		t.Errorf("Expected wp2 hold time to be 1.5s, got %s",
+			mwu.waypoints[1].HoldTime)
+	}
+	if mwu.waypoints[1].Action != "photo" {
+		t.Errorf("Expected wp2 action to be photo, got %s",
+			mwu.waypoints[1].Action)
+	}
+	if mwu.waypoints[1].Arrival != model.ArrivalModeLine {
+		t.Errorf("Expected wp2 arrival mode to be line, got %s",
+			mwu.waypoints[1].Arrival)
+	}
+
+	rq.Waypoints[0].ArrivalMode = "cross"
+	resp, err = sendCommand(conn, rq)
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "failure" {
+		t.Errorf("Expected failure command response status, got %s",
+			resp.Status)
+	}
+	if len(mwu.waypoints) != 2 {
+		t.Fatalf("Expected waypoints length 2, got %d",
+			len(mwu.waypoints))
+	}
 
 	rq = &Request{
 		Type: rqTypeCmd,
@@ -359,6 +605,235 @@ func TestCommand(t *testing.T) {
 		t.Errorf("Expected home waypoint longitude to be 44.191453, got %f",
 			mwu.homeWaypoint.Longitude)
 	}
+
+	rq = &Request{
+		Type: rqTypeCmd,
+		Cmd:  cmdHoldPosition,
+	}
+	resp, err = sendCommand(conn, rq)
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "ok" {
+		t.Errorf("Expected ok command response status, got %s",
+			resp.Status)
+	}
+	if !mnc.hold || mnc.loiterPoint != nil {
+		t.Errorf("Expected to hold the current position, got %v", mnc.loiterPoint)
+	}
+
+	rq = &Request{
+		Type:      rqTypeCmd,
+		Cmd:       cmdHoldPosition,
+		Waypoints: make([]*Waypoint, 1),
+	}
+	rq.Waypoints[0] = &Waypoint{
+		Latitude:  56.285119,
+		Longitude: 44.14972,
+	}
+	resp, err = sendCommand(conn, rq)
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "ok" {
+		t.Errorf("Expected ok command response status, got %s",
+			resp.Status)
+	}
+	if mnc.loiterPoint == nil || mnc.loiterPoint.Latitude != 56.285119 ||
+		mnc.loiterPoint.Longitude != 44.14972 {
+		t.Errorf("Expected to hold position 56.285119, 44.14972, got %v", mnc.loiterPoint)
+	}
+
+	rq = &Request{
+		Type: rqTypeCmd,
+		Cmd:  cmdSetNoGoZones,
+		NoGoZones: [][]*Waypoint{
+			{
+				{Latitude: 56.2850, Longitude: 44.1490},
+				{Latitude: 56.2855, Longitude: 44.1490},
+				{Latitude: 56.2855, Longitude: 44.1500},
+				{Latitude: 56.2850, Longitude: 44.1500},
+			},
+		},
+	}
+	resp, err = sendCommand(conn, rq)
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "ok" {
+		t.Errorf("Expected ok command response status, got %s",
+			resp.Status)
+	}
+	if len(mwu.noGoZones) != 1 || len(mwu.noGoZones[0].Vertices) != 4 {
+		t.Fatalf("Expected 1 no-go zone with 4 vertices, got %v", mwu.noGoZones)
+	}
+	if mwu.noGoZones[0].Vertices[2].Latitude != 56.2855 ||
+		mwu.noGoZones[0].Vertices[2].Longitude != 44.1500 {
+		t.Errorf("Expected zone vertex 56.2855, 44.1500, got %v", mwu.noGoZones[0].Vertices[2])
+	}
+
+	// a zone needs at least 3 vertices
+	rq.NoGoZones[0] = rq.NoGoZones[0][:2]
+	resp, err = sendCommand(conn, rq)
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "failure" {
+		t.Errorf("Expected failure command response status, got %s",
+			resp.Status)
+	}
+	if len(mwu.noGoZones) != 1 {
+		t.Errorf("Expected no-go zones to be kept, got %d", len(mwu.noGoZones))
+	}
+
+	rq.NoGoZones = nil
+	resp, err = sendCommand(conn, rq)
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "ok" {
+		t.Errorf("Expected ok command response status, got %s",
+			resp.Status)
+	}
+	if len(mwu.noGoZones) != 0 {
+		t.Errorf("Expected no-go zones to be cleared, got %d", len(mwu.noGoZones))
+	}
+}
+
+func TestCalibrationCommands(t *testing.T) {
+	mpc := &mockPositionCalibrator{}
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+
+	adapter := NewAdapter(testSocket, framing.ModeCompat, &mockShipDataProvider{}, &mockPositionDataProvider{},
+		&mockWaypointDataProvider{}, &mockNavController{}, &mockWaypointsUpdater{}, mpc, &logger)
+	go adapter.Run()
+	defer adapter.Stop()
+
+	time.Sleep(10 * time.Millisecond)
+
+	conn, err := net.Dial("unix", testSocket)
+	if err != nil {
+		t.Fatalf("Failed to connect to socket %s: %s",
+			testSocket, err.Error())
+	}
+	defer conn.Close()
+
+	resp, err := sendCommand(conn, &Request{Type: rqTypeCmd, Cmd: cmdCalibrationStart})
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "ok" {
+		t.Errorf("Expected ok command response status, got %s", resp.Status)
+	}
+	if !mpc.calibrating {
+		t.Error("Calibration is not started")
+	}
+
+	resp, err = sendCommand(conn, &Request{Type: rqTypeCmd, Cmd: cmdCalibrationStatus})
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Calibration == nil {
+		t.Fatal("Calibration status is nil")
+	}
+	if !resp.Calibration.Active {
+		t.Error("Expected calibration to be active")
+	}
+	if resp.Calibration.Samples != 120 {
+		t.Errorf("Expected 120 calibration samples, got %d", resp.Calibration.Samples)
+	}
+	if resp.Calibration.Offset != [3]float64{1.0, 2.0, 3.0} {
+		t.Errorf("Expected calibration offset to be [1 2 3], got %v", resp.Calibration.Offset)
+	}
+
+	resp, err = sendCommand(conn, &Request{Type: rqTypeCmd, Cmd: cmdCalibrationStop})
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "ok" {
+		t.Errorf("Expected ok command response status, got %s", resp.Status)
+	}
+	if mpc.calibrating {
+		t.Error("Calibration is not stopped")
+	}
+}
+
+func TestMissionValidation(t *testing.T) {
+	problems := []*model.MissionProblem{
+		{
+			WaypointIndex: 1,
+			Code:          model.MissionProblemLegTooLong,
+			Message:       "leg of 2200.0 meters to the waypoint exceeds 1000.0 meters",
+		},
+	}
+	mnc := &mockNavController{problems: problems}
+	mwu := &mockWaypointsUpdater{problems: problems}
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+
+	adapter := NewAdapter(testSocket, framing.ModeCompat, &mockShipDataProvider{}, &mockPositionDataProvider{},
+		&mockWaypointDataProvider{}, mnc, mwu, &mockPositionCalibrator{}, &logger)
+	go adapter.Run()
+	defer adapter.Stop()
+
+	time.Sleep(10 * time.Millisecond)
+
+	conn, err := net.Dial("unix", testSocket)
+	if err != nil {
+		t.Fatalf("Failed to connect to socket %s: %s",
+			testSocket, err.Error())
+	}
+	defer conn.Close()
+
+	resp, err := sendCommand(conn, &Request{
+		Type: rqTypeCmd,
+		Cmd:  cmdSetWaypoints,
+		Waypoints: []*Waypoint{
+			{Latitude: 56.410, Longitude: 43.85},
+			{Latitude: 56.430, Longitude: 43.85},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "failure" {
+		t.Errorf("Expected failure command response status, got %s", resp.Status)
+	}
+	if len(resp.Problems) != 1 {
+		t.Fatalf("Expected 1 mission problem, got %d", len(resp.Problems))
+	}
+	problem := resp.Problems[0]
+	if problem.WaypointIndex != 1 || problem.Code != "leg_too_long" || problem.Message == "" {
+		t.Errorf("Unexpected mission problem %v", problem)
+	}
+	if mwu.waypoints != nil {
+		t.Error("Expected waypoints not to be set")
+	}
+
+	resp, err = sendCommand(conn, &Request{Type: rqTypeCmd, Cmd: cmdNavStart})
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "failure" || len(resp.Problems) != 1 {
+		t.Errorf("Expected nav start to be rejected with 1 problem, got %s with %d problems",
+			resp.Status, len(resp.Problems))
+	}
+	if mnc.nav {
+		t.Error("Expected navigation not to be started")
+	}
+
+	// the problems are not reported for a valid route
+	mnc.problems = nil
+	resp, err = sendCommand(conn, &Request{Type: rqTypeCmd, Cmd: cmdNavStart})
+	if err != nil {
+		t.Fatalf("Failed to send command: %s", err.Error())
+	}
+	if resp.Status != "ok" || resp.Problems != nil {
+		t.Errorf("Expected ok command response status without problems, got %s with %v",
+			resp.Status, resp.Problems)
+	}
+	if !mnc.nav {
+		t.Error("Expected navigation to be started")
+	}
 }
 
 func sendCommand(conn net.Conn, rq *Request) (*CommandResponse, error) {
@@ -386,3 +861,71 @@ func sendCommand(conn net.Conn, rq *Request) (*CommandResponse, error) {
 
 	return &resp, nil
 }
+
+func TestFramedSetWaypoints(t *testing.T) {
+	mwu := &mockWaypointsUpdater{}
+	logger := zerolog.New(os.Stdout).With().Timestamp().Logger().Level(zerolog.DebugLevel)
+
+	adapter := NewAdapter(testSocket, framing.ModeNewline, &mockShipDataProvider{}, &mockPositionDataProvider{},
+		&mockWaypointDataProvider{}, &mockNavController{}, mwu, &mockPositionCalibrator{}, &logger)
+	go adapter.Run()
+	defer adapter.Stop()
+
+	time.Sleep(10 * time.Millisecond)
+
+	conn, err := net.Dial("unix", testSocket)
+	if err != nil {
+		t.Fatalf("Failed to connect to socket %s: %s",
+			testSocket, err.Error())
+	}
+	defer conn.Close()
+
+	// request is much larger than a single socket read
+	rq := &Request{
+		Type:      rqTypeCmd,
+		Cmd:       cmdSetWaypoints,
+		Waypoints: make([]*Waypoint, 300),
+	}
+	for i := range rq.Waypoints {
+		rq.Waypoints[i] = &Waypoint{
+			Latitude:  56.261437 + float64(i)*0.0001,
+			Longitude: 44.191453,
+		}
+	}
+	rqData, err := json.Marshal(rq)
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %s", err.Error())
+	}
+
+	// two requests in a single write
+	data := append(rqData, '\n')
+	data = append(data, []byte(`{"type":"cmd","cmd":"nav_start"}`+"\n")...)
+	_, err = conn.Write(data)
+	if err != nil {
+		t.Fatalf("Failed to write to socket: %s", err.Error())
+	}
+
+	framedConn := framing.NewConn(conn, framing.ModeNewline)
+	for i := 0; i < 2; i++ {
+		respData, err := framedConn.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read response: %s", err.Error())
+		}
+		var resp CommandResponse
+		err = json.Unmarshal(respData, &resp)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal response: %s", err.Error())
+		}
+		if resp.Status != "ok" {
+			t.Errorf("Expected ok command response status, got %s", resp.Status)
+		}
+	}
+
+	if len(mwu.waypoints) != 300 {
+		t.Fatalf("Expected 300 waypoints, got %d", len(mwu.waypoints))
+	}
+	if math.Abs(mwu.waypoints[299].Latitude-(56.261437+299*0.0001)) > 1e-9 {
+		t.Errorf("Expected last waypoint latitude to be %f, got %f",
+			56.261437+299*0.0001, mwu.waypoints[299].Latitude)
+	}
+}
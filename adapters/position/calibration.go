@@ -0,0 +1,106 @@
+package position
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+func loadCalibration(filename string) (*model.MagCalibration, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	calibration := &model.MagCalibration{}
+	err = json.Unmarshal(data, calibration)
+	if err != nil {
+		return nil, err
+	}
+
+	return calibration, nil
+}
+
+// the directory of the file is created if it does not exist
+func saveCalibration(filename string, calibration *model.MagCalibration) error {
+	data, err := json.MarshalIndent(calibration, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0644)
+}
+
+func (a *Adapter) setCalibration(calibration *model.MagCalibration) {
+	a.calibration = calibration
+
+	a.statusMutex.Lock()
+	defer a.statusMutex.Unlock()
+	a.status.Calibrated = true
+	a.status.Offset = calibration.Offset
+}
+
+func (a *Adapter) startCalibration() {
+	if a.calibrating {
+		return
+	}
+	a.logger.Info().Msg("Starting calibration")
+
+	a.calibrating = true
+	a.samples = make([][3]float64, 0)
+
+	a.statusMutex.Lock()
+	defer a.statusMutex.Unlock()
+	a.status.Active = true
+	a.status.Samples = 0
+	a.status.Error = ""
+}
+
+func (a *Adapter) addCalibrationSample(magnetometerInfo *MagnetometerInfoResponse) {
+	a.samples = append(a.samples, [3]float64{float64(magnetometerInfo.X),
+		float64(magnetometerInfo.Y), float64(magnetometerInfo.Z)})
+
+	a.statusMutex.Lock()
+	defer a.statusMutex.Unlock()
+	a.status.Samples = len(a.samples)
+}
+
+// fit calibration to the collected samples, apply and persist it
+func (a *Adapter) stopCalibration() {
+	if !a.calibrating {
+		return
+	}
+	a.logger.Info().Msgf("Stopping calibration, %d samples collected", len(a.samples))
+
+	a.calibrating = false
+	calibration, err := model.FitMagCalibration(a.samples)
+	a.samples = nil
+	if err != nil {
+		a.logger.Error().Err(err).Msg("Failed to calculate calibration")
+		a.setCalibrationResult(err)
+		return
+	}
+
+	a.setCalibration(calibration)
+	if a.calibrationFile != "" {
+		err = saveCalibration(a.calibrationFile, calibration)
+		if err != nil {
+			a.logger.Error().Err(err).Msgf("Failed to save calibration to %s", a.calibrationFile)
+		}
+	}
+	a.setCalibrationResult(err)
+}
+
+func (a *Adapter) setCalibrationResult(err error) {
+	a.statusMutex.Lock()
+	defer a.statusMutex.Unlock()
+	a.status.Active = false
+	if err != nil {
+		a.status.Error = err.Error()
+	}
+}
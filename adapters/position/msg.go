@@ -1,10 +1,9 @@
 package position
 
 const (
-	CmdGetGPS           = "GetGPSData"
-	CmdGetMagnetometer  = "GetMagnetometerData"
-	CmdStartCalibration = "StartCalibration"
-	CmdStopCalibration  = "StopCalibration"
+	CmdGetGPS          = "GetGPSData"
+	CmdGetMagnetometer = "GetMagnetometerData"
+	CmdGetAttitude     = "GetAttitudeData"
 )
 
 type IPCRequest struct {
@@ -17,6 +16,10 @@ type GPSInfoResponse struct {
 	Longitude     float64 `json:"longitude"`
 	SpeedKnots    float64 `json:"speedKnots"`
 	SpeedKm       float64 `json:"speedKm"`
+	Hdop          float64 `json:"hdop"`
+	FixType       int     `json:"fixType"`
+	// milliseconds since the fix was computed
+	FixAge int64 `json:"fixAge"`
 }
 
 type MagnetometerInfoResponse struct {
@@ -25,8 +28,14 @@ type MagnetometerInfoResponse struct {
 	Z int32 `json:"z"`
 }
 
-type CalibrationResponse struct {
-	Success bool `json:"success"`
+// accelerometer readings are required, gyroscope readings are optional
+type AttitudeInfoResponse struct {
+	AccelX float64 `json:"accelX"`
+	AccelY float64 `json:"accelY"`
+	AccelZ float64 `json:"accelZ"`
+	GyroX  float64 `json:"gyroX,omitempty"`
+	GyroY  float64 `json:"gyroY,omitempty"`
+	GyroZ  float64 `json:"gyroZ,omitempty"`
 }
 
 type ErrorResponse struct {
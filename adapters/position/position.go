@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 
+	"github.com/moosethebrown/ship-nav/adapters/backoff"
+	"github.com/moosethebrown/ship-nav/adapters/framing"
 	"github.com/moosethebrown/ship-nav/core"
 	"github.com/moosethebrown/ship-nav/core/model"
 )
@@ -15,56 +18,119 @@ import (
 type Configurer interface {
 	PositionSocketName() string
 	PositionPollingInterval() int64
+	PositionAttitudeData() bool
+	PositionCalibrationFile() string
+	PositionReconnectInterval() int64
+	PositionMaxReconnectInterval() int64
+	PositionFraming() string
 	Declination() float64
 }
 
 type Adapter struct {
-	logger          *zerolog.Logger
-	socketName      string
-	pollingInterval int64
-	stopCh          chan bool
-	calibrating     bool
-	calibrationCh   chan bool
-	positionUpdater core.PositionUpdater
-	bearingUpdater  core.BearingUpdater
-	declination     float64
+	logger               *zerolog.Logger
+	socketName           string
+	pollingInterval      int64
+	reconnectInterval    int64
+	maxReconnectInterval int64
+	framing              framing.Mode
+	attitudeData         bool
+	// ship-position failed to report attitude data, reset on reconnect
+	attitudeUnavailable bool
+	stopCh              chan bool
+	calibrating         bool
+	calibrationCh       chan bool
+	calibrationFile     string
+	calibration         *model.MagCalibration
+	samples             [][3]float64
+	statusMutex         sync.Mutex
+	status              model.CalibrationStatus
+	positionUpdater     core.PositionUpdater
+	bearingUpdater      core.BearingUpdater
+	connectionUpdater   core.PositionConnectionUpdater
+	connected           bool
+	connectionReported  bool
+	declination         float64
 }
 
 func NewAdapter(logger *zerolog.Logger, configurer Configurer,
-	positionUpdater core.PositionUpdater, bearingUpdater core.BearingUpdater) *Adapter {
-	return &Adapter{
-		logger:          logger,
-		socketName:      configurer.PositionSocketName(),
-		pollingInterval: configurer.PositionPollingInterval(),
-		stopCh:          make(chan bool, 1),
-		calibrationCh:   make(chan bool, 1),
-		positionUpdater: positionUpdater,
-		bearingUpdater:  bearingUpdater,
-		declination:     configurer.Declination(),
+	positionUpdater core.PositionUpdater, bearingUpdater core.BearingUpdater,
+	connectionUpdater core.PositionConnectionUpdater) *Adapter {
+	framingMode, err := framing.ParseMode(configurer.PositionFraming())
+	if err != nil {
+		logger.Error().Err(err).Msgf("Using %s framing", framingMode)
+	}
+
+	adapter := &Adapter{
+		logger:               logger,
+		socketName:           configurer.PositionSocketName(),
+		pollingInterval:      configurer.PositionPollingInterval(),
+		reconnectInterval:    configurer.PositionReconnectInterval(),
+		maxReconnectInterval: configurer.PositionMaxReconnectInterval(),
+		framing:              framingMode,
+		attitudeData:         configurer.PositionAttitudeData(),
+		stopCh:               make(chan bool, 1),
+		calibrationCh:        make(chan bool, 1),
+		positionUpdater:      positionUpdater,
+		bearingUpdater:       bearingUpdater,
+		connectionUpdater:    connectionUpdater,
+		calibrationFile:      configurer.PositionCalibrationFile(),
+		declination:          configurer.Declination(),
 	}
+
+	if adapter.calibrationFile != "" {
+		calibration, err := loadCalibration(adapter.calibrationFile)
+		if err != nil {
+			logger.Warn().Err(err).Msgf("Failed to load magnetometer calibration from %s", adapter.calibrationFile)
+		} else {
+			adapter.setCalibration(calibration)
+		}
+	}
+
+	return adapter
 }
 
+// connects to the position service and reconnects with exponential backoff if the connection fails
 func (a *Adapter) Run() {
-	conn, err := net.Dial("unix", a.socketName)
-	if err != nil {
-		a.logger.Error().Err(err).Msg("Failed to open Unix socket")
-		return
+	reconnect := backoff.NewBackoff(a.reconnectInterval, a.maxReconnectInterval)
+	for {
+		conn, err := net.Dial("unix", a.socketName)
+		if err != nil {
+			a.setConnected(false)
+			delay := reconnect.Next()
+			a.logger.Error().Err(err).Msgf("Failed to open Unix socket, retrying in %s", delay)
+			if !a.wait(delay) {
+				return
+			}
+			continue
+		}
+
+		a.logger.Info().Msg("Connected to position service")
+		reconnect.Reset()
+		a.attitudeUnavailable = false
+		a.setConnected(true)
+		stopped := a.serve(framing.NewConn(conn, a.framing))
+		conn.Close()
+		if stopped {
+			return
+		}
+		a.setConnected(false)
 	}
-	defer conn.Close()
+}
 
+// returns true if the adapter is stopped, false if the connection is broken
+func (a *Adapter) serve(conn *framing.Conn) bool {
 	ticker := time.NewTicker(time.Duration(a.pollingInterval) * time.Millisecond)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			if a.calibrating {
-				continue
-			}
-
 			gpsInfo, err := a.gpsInfoRequest(conn)
 			if err != nil {
 				a.logger.Error().Err(err).Msg("Failed to query gps info")
+				if backoff.IsConnectionError(err) {
+					return false
+				}
 				continue
 			}
 
@@ -74,37 +140,74 @@ func (a *Adapter) Run() {
 				Longitude:     gpsInfo.Longitude,
 				SpeedKnots:    gpsInfo.SpeedKnots,
 				SpeedKm:       gpsInfo.SpeedKm,
+				Hdop:          gpsInfo.Hdop,
+				FixType:       model.FixType(gpsInfo.FixType),
+				FixAge:        time.Duration(gpsInfo.FixAge) * time.Millisecond,
+				Timestamp:     time.Now(),
 			}
 			a.positionUpdater.UpdatePosition(position)
 
 			magnetometerInfo, err := a.magnetometerInfoRequest(conn)
 			if err != nil {
 				a.logger.Error().Err(err).Msg("Failed to query magnetometer info")
+				if backoff.IsConnectionError(err) {
+					return false
+				}
+				continue
+			}
+			if a.calibrating {
+				a.addCalibrationSample(magnetometerInfo)
 			}
 			bearing := model.NewBearing(a.declination)
-			bearing.SetInt(magnetometerInfo.X, magnetometerInfo.Y)
+			bearing.Timestamp = time.Now()
+			a.setBearing(conn, bearing, magnetometerInfo)
 			a.bearingUpdater.UpdateBearing(bearing)
 
 		case <-a.stopCh:
-			return
+			return true
 
-		case a.calibrating = <-a.calibrationCh:
-			if a.calibrating {
-				a.logger.Info().Msg("Starting calibration")
-			} else {
-				a.logger.Info().Msg("Stopping calibration")
-			}
-			resp, err := a.calibrationRequest(conn, a.calibrating)
-			if err != nil {
-				a.logger.Error().Err(err).Msg("Failed to start/stop calibration")
-			}
-			if !resp.Success {
-				a.logger.Error().Msg("ship-position failed to start/stop calibration")
-			}
+		case start := <-a.calibrationCh:
+			a.handleCalibrationCmd(start)
+		}
+	}
+}
+
+// waits before the next connection attempt, returns false if the adapter is stopped
+func (a *Adapter) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return true
+		case start := <-a.calibrationCh:
+			a.handleCalibrationCmd(start)
+		case <-a.stopCh:
+			return false
 		}
 	}
 }
 
+func (a *Adapter) handleCalibrationCmd(start bool) {
+	if start {
+		a.startCalibration()
+	} else {
+		a.stopCalibration()
+	}
+}
+
+func (a *Adapter) setConnected(connected bool) {
+	if a.connectionReported && a.connected == connected {
+		return
+	}
+	a.connected = connected
+	a.connectionReported = true
+	if a.connectionUpdater != nil {
+		a.connectionUpdater.UpdatePositionConnection(connected)
+	}
+}
+
 func (a *Adapter) Stop() {
 	a.stopCh <- true
 }
@@ -117,27 +220,52 @@ func (a *Adapter) StopCalibration() {
 	a.calibrationCh <- false
 }
 
-func (a *Adapter) gpsInfoRequest(conn net.Conn) (*GPSInfoResponse, error) {
+func (a *Adapter) CalibrationStatus() *model.CalibrationStatus {
+	a.statusMutex.Lock()
+	defer a.statusMutex.Unlock()
+
+	status := a.status
+	return &status
+}
+
+// use tilt compensation if ship-position reports attitude data, 2-axis calculation otherwise
+func (a *Adapter) setBearing(conn *framing.Conn, bearing *model.Bearing, magnetometerInfo *MagnetometerInfoResponse) {
+	x, y, z := a.calibration.Apply(float64(magnetometerInfo.X), float64(magnetometerInfo.Y),
+		float64(magnetometerInfo.Z))
+	if a.attitudeData && !a.attitudeUnavailable {
+		attitudeInfo, err := a.attitudeInfoRequest(conn)
+		if err == nil {
+			bearing.SetTiltCompensated(x, y, z, attitudeInfo.AccelX, attitudeInfo.AccelY, attitudeInfo.AccelZ)
+			return
+		}
+		// not queried again until the connection is reestablished
+		a.attitudeUnavailable = true
+		a.logger.Warn().Err(err).Msg("Failed to query attitude info, using 2-axis bearing until reconnect")
+	}
+
+	bearing.SetFloat(x, y)
+}
+
+func (a *Adapter) gpsInfoRequest(conn *framing.Conn) (*GPSInfoResponse, error) {
 	rq := &IPCRequest{Cmd: CmdGetGPS}
 	data, err := json.Marshal(rq)
 	if err != nil {
 		return nil, err
 	}
-	_, err = conn.Write(data)
+	err = conn.WriteMessage(data)
 	if err != nil {
 		return nil, err
 	}
 
 	resp := &GPSInfoResponse{}
 	errResp := &ErrorResponse{}
-	buf := make([]byte, 4096)
-	n, err := conn.Read(buf)
+	respData, err := conn.ReadMessage()
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(buf[:n], &resp)
+	err = json.Unmarshal(respData, &resp)
 	if err != nil {
-		err = json.Unmarshal(buf[:n], &errResp)
+		err = json.Unmarshal(respData, &errResp)
 		if err == nil {
 			return nil, errors.New(errResp.ErrorMessage)
 		} else {
@@ -148,27 +276,26 @@ func (a *Adapter) gpsInfoRequest(conn net.Conn) (*GPSInfoResponse, error) {
 	return resp, nil
 }
 
-func (a *Adapter) magnetometerInfoRequest(conn net.Conn) (*MagnetometerInfoResponse, error) {
+func (a *Adapter) magnetometerInfoRequest(conn *framing.Conn) (*MagnetometerInfoResponse, error) {
 	rq := &IPCRequest{Cmd: CmdGetMagnetometer}
 	data, err := json.Marshal(rq)
 	if err != nil {
 		return nil, err
 	}
-	_, err = conn.Write(data)
+	err = conn.WriteMessage(data)
 	if err != nil {
 		return nil, err
 	}
 
 	resp := &MagnetometerInfoResponse{}
 	errResp := &ErrorResponse{}
-	buf := make([]byte, 4096)
-	n, err := conn.Read(buf)
+	respData, err := conn.ReadMessage()
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(buf[:n], &resp)
+	err = json.Unmarshal(respData, &resp)
 	if err != nil {
-		err = json.Unmarshal(buf[:n], &errResp)
+		err = json.Unmarshal(respData, &errResp)
 		if err == nil {
 			return nil, errors.New(errResp.ErrorMessage)
 		} else {
@@ -178,37 +305,30 @@ func (a *Adapter) magnetometerInfoRequest(conn net.Conn) (*MagnetometerInfoRespo
 	return resp, nil
 }
 
-func (a *Adapter) calibrationRequest(conn net.Conn, start bool) (*CalibrationResponse, error) {
-	rq := &IPCRequest{}
-	if start {
-		rq.Cmd = CmdStartCalibration
-	} else {
-		rq.Cmd = CmdStopCalibration
-	}
+func (a *Adapter) attitudeInfoRequest(conn *framing.Conn) (*AttitudeInfoResponse, error) {
+	rq := &IPCRequest{Cmd: CmdGetAttitude}
 	data, err := json.Marshal(rq)
 	if err != nil {
 		return nil, err
 	}
-	_, err = conn.Write(data)
+	err = conn.WriteMessage(data)
 	if err != nil {
 		return nil, err
 	}
 
-	resp := &CalibrationResponse{}
+	resp := &AttitudeInfoResponse{}
 	errResp := &ErrorResponse{}
-	buf := make([]byte, 4096)
-	n, err := conn.Read(buf)
+	respData, err := conn.ReadMessage()
 	if err != nil {
 		return nil, err
 	}
-	err = json.Unmarshal(buf[:n], &resp)
+	err = json.Unmarshal(respData, &errResp)
+	if err == nil && errResp.ErrorMessage != "" {
+		return nil, errors.New(errResp.ErrorMessage)
+	}
+	err = json.Unmarshal(respData, &resp)
 	if err != nil {
-		err = json.Unmarshal(buf[:n], &errResp)
-		if err == nil {
-			return nil, errors.New(errResp.ErrorMessage)
-		} else {
-			return nil, err
-		}
+		return nil, err
 	}
 	return resp, nil
 }
@@ -6,9 +6,14 @@ import (
 	"math"
 	"net"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/moosethebrown/ship-nav/adapters/framing"
 	"github.com/moosethebrown/ship-nav/core/model"
 	"github.com/rs/zerolog"
 )
@@ -33,11 +38,31 @@ func (m *mockBearingUpdater) UpdateBearing(bearing *model.Bearing) {
 	m.bearing = bearing
 }
 
+type mockConnectionUpdater struct {
+	mutex  sync.Mutex
+	states []bool
+}
+
+func (m *mockConnectionUpdater) UpdatePositionConnection(connected bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.states = append(m.states, connected)
+}
+
+func (m *mockConnectionUpdater) getStates() []bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]bool{}, m.states...)
+}
+
 type mockInfoProvider struct {
 	socket      string
 	listener    net.Listener
 	connections []net.Conn
-	calibration bool
+	framing     framing.Mode
+	// attitude requests are answered with an error
+	noAttitude       bool
+	attitudeRequests atomic.Int32
 }
 
 func newMockInfoProvider(socket string) *mockInfoProvider {
@@ -83,16 +108,16 @@ func (p *mockInfoProvider) stop() {
 func (p *mockInfoProvider) handleConn(conn net.Conn) {
 	defer conn.Close()
 
+	framedConn := framing.NewConn(conn, p.framing)
 	for {
-		buf := make([]byte, 4096)
-		n, err := conn.Read(buf)
+		data, err := framedConn.ReadMessage()
 		if err != nil {
 			fmt.Printf("Failed to read data from unix socket: %s\n", err.Error())
 			return
 		}
 
 		rq := &IPCRequest{}
-		err = json.Unmarshal(buf[:n], &rq)
+		err = json.Unmarshal(data, &rq)
 		if err != nil {
 			fmt.Printf("Failed to unmarshal request: %s\n", err.Error())
 			return
@@ -107,6 +132,9 @@ func (p *mockInfoProvider) handleConn(conn net.Conn) {
 				Longitude:     43.902243,
 				SpeedKnots:    3.0,
 				SpeedKm:       5.56,
+				Hdop:          0.9,
+				FixType:       3,
+				FixAge:        250,
 			}
 			respData, err = json.Marshal(resp)
 			if err != nil {
@@ -122,12 +150,17 @@ func (p *mockInfoProvider) handleConn(conn net.Conn) {
 			if err != nil {
 				fmt.Printf("Failed to marshal response: %s\n", err.Error())
 			}
-		case CmdStartCalibration:
-			p.calibration = true
-			fallthrough
-		case CmdStopCalibration:
-			p.calibration = false
-			resp := CalibrationResponse{Success: true}
+		case CmdGetAttitude:
+			p.attitudeRequests.Add(1)
+			if p.noAttitude {
+				respData, _ = json.Marshal(ErrorResponse{ErrorMessage: "unknown command"})
+				break
+			}
+			resp := AttitudeInfoResponse{
+				AccelX: 0.17,
+				AccelY: -0.26,
+				AccelZ: 0.95,
+			}
 			respData, err = json.Marshal(resp)
 			if err != nil {
 				fmt.Printf("Failed to marshal response: %s\n", err.Error())
@@ -135,7 +168,7 @@ func (p *mockInfoProvider) handleConn(conn net.Conn) {
 		}
 
 		if respData != nil {
-			_, err = conn.Write(respData)
+			err = framedConn.WriteMessage(respData)
 			if err != nil {
 				fmt.Printf("Failed to send response: %s\n", err.Error())
 				return
@@ -144,33 +177,64 @@ func (p *mockInfoProvider) handleConn(conn net.Conn) {
 	}
 }
 
-type mockConfigurer struct{}
+type mockConfigurer struct {
+	attitudeData    bool
+	calibrationFile string
+	pollingInterval int64
+	framing         string
+}
 
 func (c *mockConfigurer) PositionSocketName() string {
 	return testSocket
 }
 
 func (c *mockConfigurer) PositionPollingInterval() int64 {
-	return 500
+	if c.pollingInterval == 0 {
+		return 500
+	}
+	return c.pollingInterval
+}
+
+func (c *mockConfigurer) PositionAttitudeData() bool {
+	return c.attitudeData
+}
+
+func (c *mockConfigurer) PositionCalibrationFile() string {
+	return c.calibrationFile
+}
+
+func (c *mockConfigurer) PositionFraming() string {
+	return c.framing
+}
+
+func (c *mockConfigurer) PositionReconnectInterval() int64 {
+	return 50
+}
+
+func (c *mockConfigurer) PositionMaxReconnectInterval() int64 {
+	return 200
 }
 
 func (c *mockConfigurer) Declination() float64 {
 	return 0.0
 }
 
-func setupTest() (*mockInfoProvider, *mockBearingUpdater, *mockPositionUpdater, *Adapter) {
+func setupTest(configurer *mockConfigurer) (*mockInfoProvider, *mockBearingUpdater,
+	*mockPositionUpdater, *Adapter) {
 	mockInfoProvider := newMockInfoProvider(testSocket)
 	mockBearingUpdater := &mockBearingUpdater{}
 	mockPositionUpdater := &mockPositionUpdater{}
 
 	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
-	adapter := NewAdapter(&logger, &mockConfigurer{}, mockPositionUpdater, mockBearingUpdater)
+	adapter := NewAdapter(&logger, configurer, mockPositionUpdater, mockBearingUpdater,
+		&mockConnectionUpdater{})
 
 	return mockInfoProvider, mockBearingUpdater, mockPositionUpdater, adapter
 }
 
 func TestDataUpdate(t *testing.T) {
-	mockInfoProvider, mockBearingUpdater, mockPositionUpdater, adapter := setupTest()
+	mockInfoProvider, mockBearingUpdater, mockPositionUpdater, adapter := setupTest(&mockConfigurer{})
+	start := time.Now()
 
 	go mockInfoProvider.run()
 	defer mockInfoProvider.stop()
@@ -190,10 +254,18 @@ func TestDataUpdate(t *testing.T) {
 		t.Errorf("expected bearing angle to be %f, got %f",
 			expectedAngle, mockBearingUpdater.bearing.Angle())
 	}
+	if mockBearingUpdater.bearing.Timestamp.Before(start) {
+		t.Errorf("expected bearing timestamp to be set, got %s",
+			mockBearingUpdater.bearing.Timestamp)
+	}
 
 	if mockPositionUpdater.position == nil {
 		t.Fatal("expected position to be updated")
 	}
+	if mockPositionUpdater.position.Timestamp.Before(start) {
+		t.Errorf("expected position timestamp to be set, got %s",
+			mockPositionUpdater.position.Timestamp)
+	}
 	if mockPositionUpdater.position.NumSatellites != 11 {
 		t.Errorf("expected number of satellites to be 11, got %d",
 			mockPositionUpdater.position.NumSatellites)
@@ -214,4 +286,234 @@ func TestDataUpdate(t *testing.T) {
 		t.Errorf("expected speed in km to be 5.56, got %f",
 			mockPositionUpdater.position.SpeedKm)
 	}
+	if mockPositionUpdater.position.Hdop != 0.9 {
+		t.Errorf("expected HDOP to be 0.9, got %f",
+			mockPositionUpdater.position.Hdop)
+	}
+	if mockPositionUpdater.position.FixType != model.Fix3D {
+		t.Errorf("expected 3D fix, got %d",
+			mockPositionUpdater.position.FixType)
+	}
+	if mockPositionUpdater.position.FixAge != 250*time.Millisecond {
+		t.Errorf("expected fix age to be 250ms, got %s",
+			mockPositionUpdater.position.FixAge)
+	}
+}
+
+func TestTiltCompensatedDataUpdate(t *testing.T) {
+	mockInfoProvider, mockBearingUpdater, _, adapter := setupTest(&mockConfigurer{attitudeData: true})
+
+	go mockInfoProvider.run()
+	defer mockInfoProvider.stop()
+	time.Sleep(50 * time.Millisecond)
+
+	go adapter.Run()
+	defer adapter.Stop()
+
+	time.Sleep(550 * time.Millisecond)
+
+	if mockBearingUpdater.bearing == nil {
+		t.Fatal("expected bearing to be updated")
+	}
+	expected := model.NewBearing(0.0)
+	expected.SetTiltCompensated(13281, 11824, -9584, 0.17, -0.26, 0.95)
+	if mockBearingUpdater.bearing.Angle() != expected.Angle() {
+		t.Errorf("expected bearing angle to be %f, got %f",
+			expected.Angle(), mockBearingUpdater.bearing.Angle())
+	}
+	if expected.Angle() == math.Atan2(float64(11824), float64(13281)) {
+		t.Error("expected tilt compensation to change the bearing angle")
+	}
+}
+
+func TestAttitudeDataUnavailable(t *testing.T) {
+	mockInfoProvider, mockBearingUpdater, _, adapter := setupTest(&mockConfigurer{attitudeData: true})
+	mockInfoProvider.noAttitude = true
+
+	go mockInfoProvider.run()
+	defer mockInfoProvider.stop()
+	time.Sleep(50 * time.Millisecond)
+
+	go adapter.Run()
+	defer adapter.Stop()
+
+	time.Sleep(550 * time.Millisecond)
+
+	if mockBearingUpdater.bearing == nil {
+		t.Fatal("expected bearing to be updated")
+	}
+	expectedAngle := math.Atan2(float64(11824), float64(13281))
+	if mockBearingUpdater.bearing.Angle() != expectedAngle {
+		t.Errorf("expected 2-axis bearing angle to be %f, got %f",
+			expectedAngle, mockBearingUpdater.bearing.Angle())
+	}
+	// attitude data is requested once per connection
+	if requests := mockInfoProvider.attitudeRequests.Load(); requests != 1 {
+		t.Errorf("expected 1 attitude request, got %d", requests)
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	calibrationFile := "/tmp/position_test_magcal.json"
+	os.Remove(calibrationFile)
+	defer os.Remove(calibrationFile)
+
+	_, _, _, adapter := setupTest(&mockConfigurer{calibrationFile: calibrationFile})
+	if adapter.CalibrationStatus().Calibrated {
+		t.Fatal("expected adapter not to be calibrated")
+	}
+
+	adapter.startCalibration()
+	if !adapter.CalibrationStatus().Active {
+		t.Fatal("expected calibration to be active")
+	}
+
+	// hard-iron offset (100, -200, 50) with axis scaling
+	for i := 0; i < 10; i++ {
+		theta := math.Pi * (float64(i) + 0.5) / 10
+		for j := 0; j < 12; j++ {
+			phi := 2 * math.Pi * float64(j) / 12
+			adapter.addCalibrationSample(&MagnetometerInfoResponse{
+				X: int32(100 + 3000*math.Sin(theta)*math.Cos(phi)),
+				Y: int32(-200 + 2000*math.Sin(theta)*math.Sin(phi)),
+				Z: int32(50 + 2500*math.Cos(theta)),
+			})
+		}
+	}
+	if adapter.CalibrationStatus().Samples != 120 {
+		t.Errorf("expected 120 samples, got %d", adapter.CalibrationStatus().Samples)
+	}
+
+	adapter.stopCalibration()
+	status := adapter.CalibrationStatus()
+	if status.Active {
+		t.Error("expected calibration to be stopped")
+	}
+	if status.Error != "" {
+		t.Fatalf("expected calibration to succeed, got %s", status.Error)
+	}
+	if !status.Calibrated {
+		t.Fatal("expected adapter to be calibrated")
+	}
+	expectedOffset := [3]float64{100, -200, 50}
+	for i := 0; i < 3; i++ {
+		if math.Abs(status.Offset[i]-expectedOffset[i]) > 2 {
+			t.Errorf("expected offset %d to be %f, got %f", i, expectedOffset[i], status.Offset[i])
+		}
+	}
+
+	// calibration is loaded from the file on startup
+	_, _, _, adapter = setupTest(&mockConfigurer{calibrationFile: calibrationFile})
+	if !adapter.CalibrationStatus().Calibrated {
+		t.Error("expected calibration to be loaded from file")
+	}
+	if adapter.CalibrationStatus().Offset != status.Offset {
+		t.Errorf("expected loaded offset to be %v, got %v", status.Offset, adapter.CalibrationStatus().Offset)
+	}
+
+	// not enough samples
+	adapter.startCalibration()
+	adapter.addCalibrationSample(&MagnetometerInfoResponse{X: 1, Y: 2, Z: 3})
+	adapter.stopCalibration()
+	if adapter.CalibrationStatus().Error == "" {
+		t.Error("expected calibration error with not enough samples")
+	}
+}
+
+func TestSaveCalibrationCreatesDirectory(t *testing.T) {
+	calibrationFile := filepath.Join(t.TempDir(), "ship-nav", "magcal.json")
+	calibration := &model.MagCalibration{
+		Offset: [3]float64{100, -200, 50},
+	}
+
+	if err := saveCalibration(calibrationFile, calibration); err != nil {
+		t.Fatalf("Failed to save calibration: %s", err.Error())
+	}
+	loaded, err := loadCalibration(calibrationFile)
+	if err != nil {
+		t.Fatalf("Failed to load calibration: %s", err.Error())
+	}
+	if loaded.Offset != calibration.Offset {
+		t.Errorf("expected loaded offset to be %v, got %v", calibration.Offset, loaded.Offset)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	_, _, mockPositionUpdater, adapter := setupTest(&mockConfigurer{pollingInterval: 50})
+	mockConnectionUpdater := adapter.connectionUpdater.(*mockConnectionUpdater)
+
+	// position service is not running yet
+	go adapter.Run()
+	defer adapter.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	if states := mockConnectionUpdater.getStates(); !reflect.DeepEqual(states, []bool{false}) {
+		t.Fatalf("Expected connection states to be [false], got %v", states)
+	}
+
+	mockInfoProvider := newMockInfoProvider(testSocket)
+	go mockInfoProvider.run()
+	time.Sleep(350 * time.Millisecond)
+
+	if states := mockConnectionUpdater.getStates(); !reflect.DeepEqual(states, []bool{false, true}) {
+		t.Fatalf("Expected connection states to be [false true], got %v", states)
+	}
+	if mockPositionUpdater.position == nil {
+		t.Fatal("Expected position to be updated after connection")
+	}
+
+	// position service restarts
+	mockInfoProvider.stop()
+	time.Sleep(200 * time.Millisecond)
+
+	if states := mockConnectionUpdater.getStates(); !reflect.DeepEqual(states, []bool{false, true, false}) {
+		t.Fatalf("Expected connection states to be [false true false], got %v", states)
+	}
+
+	// calibration commands are handled while disconnected
+	adapter.StartCalibration()
+	time.Sleep(10 * time.Millisecond)
+	if !adapter.CalibrationStatus().Active {
+		t.Errorf("Expected calibration to be active")
+	}
+
+	mockPositionUpdater.position = nil
+	mockInfoProvider = newMockInfoProvider(testSocket)
+	go mockInfoProvider.run()
+	defer mockInfoProvider.stop()
+	time.Sleep(500 * time.Millisecond)
+
+	if states := mockConnectionUpdater.getStates(); !reflect.DeepEqual(states, []bool{false, true, false, true}) {
+		t.Fatalf("Expected connection states to be [false true false true], got %v", states)
+	}
+	if mockPositionUpdater.position == nil {
+		t.Fatal("Expected position to be updated after reconnection")
+	}
+	if adapter.CalibrationStatus().Samples == 0 {
+		t.Errorf("Expected calibration samples to be collected after reconnection")
+	}
+}
+
+func TestFraming(t *testing.T) {
+	mockInfoProvider, mockBearingUpdater, mockPositionUpdater, adapter := setupTest(&mockConfigurer{
+		attitudeData:    true,
+		pollingInterval: 50,
+		framing:         "newline",
+	})
+	mockInfoProvider.framing = framing.ModeNewline
+
+	go mockInfoProvider.run()
+	defer mockInfoProvider.stop()
+	time.Sleep(50 * time.Millisecond)
+
+	go adapter.Run()
+	defer adapter.Stop()
+	time.Sleep(120 * time.Millisecond)
+
+	if mockPositionUpdater.position == nil || mockPositionUpdater.position.Latitude != 56.363358 {
+		t.Errorf("expected position to be updated, got %v", mockPositionUpdater.position)
+	}
+	if mockBearingUpdater.bearing == nil {
+		t.Errorf("expected bearing to be updated")
+	}
 }
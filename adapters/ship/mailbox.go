@@ -0,0 +1,125 @@
+package ship
+
+import (
+	"sync"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+type command struct {
+	cmd  string
+	data string
+}
+
+// latest value wins mailbox for the ship commands, only the newest command of each kind
+// is kept until the adapter sends it, commands repeating the last value sent are dropped
+type mailbox struct {
+	mutex sync.Mutex
+	// at most one command of each kind in the order of arrival
+	pending []command
+	// last values sent to ship control
+	sent     map[string]string
+	notifyCh chan bool
+	metrics  model.CommandMetrics
+}
+
+func newMailbox() *mailbox {
+	return &mailbox{
+		pending:  make([]command, 0),
+		sent:     make(map[string]string),
+		notifyCh: make(chan bool, 1),
+	}
+}
+
+// never blocks
+func (m *mailbox) put(cmd string, data string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	last, wasSent := m.sent[cmd]
+	for i := range m.pending {
+		if m.pending[i].cmd != cmd {
+			continue
+		}
+		m.metrics.Coalesced++
+		if wasSent && last == data {
+			// back to the value ship control already has
+			m.pending = append(m.pending[:i], m.pending[i+1:]...)
+		} else {
+			m.pending[i].data = data
+		}
+		return
+	}
+
+	if wasSent && last == data {
+		m.metrics.Deduplicated++
+		return
+	}
+
+	m.pending = append(m.pending, command{cmd: cmd, data: data})
+	select {
+	case m.notifyCh <- true:
+	default:
+	}
+}
+
+// returns the pending commands and records them as sent
+func (m *mailbox) take() []command {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	cmds := m.pending
+	m.pending = make([]command, 0)
+	for _, c := range cmds {
+		m.sent[c.cmd] = c.data
+		m.metrics.Sent++
+	}
+	return cmds
+}
+
+// discards the pending commands, returns the number of commands dropped
+func (m *mailbox) drop() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	dropped := len(m.pending)
+	m.pending = make([]command, 0)
+	m.metrics.Dropped += uint64(dropped)
+	return dropped
+}
+
+// the command has not been executed, the same value has to be sent again
+func (m *mailbox) failed(cmd string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.sent, cmd)
+	m.metrics.Failed++
+}
+
+// ship control state is unknown after reconnection
+func (m *mailbox) reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.sent = make(map[string]string)
+}
+
+// forgets the values ship control does not report, e.g. changed by another client
+func (m *mailbox) reconcile(reported map[string]string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for cmd, data := range reported {
+		if last, ok := m.sent[cmd]; ok && last != data {
+			delete(m.sent, cmd)
+		}
+	}
+}
+
+func (m *mailbox) snapshot() model.CommandMetrics {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.metrics
+}
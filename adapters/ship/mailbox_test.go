@@ -0,0 +1,106 @@
+package ship
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMailboxCoalescing(t *testing.T) {
+	m := newMailbox()
+
+	m.put("set_speed", "fwd10")
+	m.put("set_steering", "left10")
+	m.put("set_speed", "fwd20")
+	m.put("set_speed", "fwd30")
+
+	select {
+	case <-m.notifyCh:
+	default:
+		t.Fatalf("Expected mailbox notification")
+	}
+
+	cmds := m.take()
+	expected := []command{{cmd: "set_speed", data: "fwd30"}, {cmd: "set_steering", data: "left10"}}
+	if !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("Expected commands to be %v, got %v", expected, cmds)
+	}
+	if cmds := m.take(); len(cmds) != 0 {
+		t.Errorf("Expected no commands, got %v", cmds)
+	}
+
+	metrics := m.snapshot()
+	if metrics.Sent != 2 {
+		t.Errorf("Expected 2 commands sent, got %d", metrics.Sent)
+	}
+	if metrics.Coalesced != 2 {
+		t.Errorf("Expected 2 commands coalesced, got %d", metrics.Coalesced)
+	}
+}
+
+func TestMailboxDeduplication(t *testing.T) {
+	m := newMailbox()
+
+	m.put("set_speed", "fwd10")
+	m.take()
+	<-m.notifyCh
+
+	m.put("set_speed", "fwd10")
+	m.put("set_speed", "fwd10")
+	select {
+	case <-m.notifyCh:
+		t.Errorf("Expected no notification for repeated command")
+	default:
+	}
+
+	// the pending command is withdrawn when the last value sent is requested again
+	m.put("set_speed", "fwd20")
+	m.put("set_speed", "fwd10")
+	if cmds := m.take(); len(cmds) != 0 {
+		t.Errorf("Expected no commands, got %v", cmds)
+	}
+
+	// failed command is sent again
+	m.failed("set_speed")
+	m.put("set_speed", "fwd10")
+	expected := []command{{cmd: "set_speed", data: "fwd10"}}
+	if cmds := m.take(); !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("Expected commands to be %v, got %v", expected, cmds)
+	}
+
+	// speed is changed by another ship control client
+	m.reconcile(map[string]string{"set_speed": "stop"})
+	m.put("set_speed", "fwd10")
+	if cmds := m.take(); !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("Expected commands to be %v, got %v", expected, cmds)
+	}
+
+	metrics := m.snapshot()
+	if metrics.Deduplicated != 2 {
+		t.Errorf("Expected 2 commands deduplicated, got %d", metrics.Deduplicated)
+	}
+	if metrics.Failed != 1 {
+		t.Errorf("Expected 1 command failed, got %d", metrics.Failed)
+	}
+	if metrics.Sent != 3 {
+		t.Errorf("Expected 3 commands sent, got %d", metrics.Sent)
+	}
+}
+
+func TestMailboxDrop(t *testing.T) {
+	m := newMailbox()
+
+	m.put("set_speed", "fwd10")
+	m.put("set_steering", "left10")
+	if dropped := m.drop(); dropped != 2 {
+		t.Errorf("Expected 2 commands dropped, got %d", dropped)
+	}
+
+	// dropped commands are not considered sent
+	m.put("set_speed", "fwd10")
+	if cmds := m.take(); len(cmds) != 1 {
+		t.Errorf("Expected 1 command, got %v", cmds)
+	}
+	if metrics := m.snapshot(); metrics.Dropped != 2 {
+		t.Errorf("Expected 2 commands dropped, got %d", metrics.Dropped)
+	}
+}
@@ -1,17 +1,28 @@
 package ship
 
+import "github.com/moosethebrown/ship-nav/core/model"
+
+// requests carry an id which ship control may echo in the response,
+// responses without id are matched to the requests in the order they were sent
 type IPCRequest struct {
+	Id   uint64 `json:"id,omitempty"`
 	Type string `json:"type"`
 	Cmd  string `json:"cmd"`
 	Data string `json:"data"`
 }
 
 type IPCCommandResponse struct {
+	Id     uint64 `json:"id,omitempty"`
 	Status string `json:"status"`
 	Error  string `json:"error"`
 }
 
 type IPCQueryResponse struct {
-	Speed    string `json:"speed"`
-	Steering string `json:"steering"`
+	Id       uint64         `json:"id,omitempty"`
+	Speed    model.Speed    `json:"speed"`
+	Steering model.Steering `json:"steering"`
+}
+
+type ipcResponseHeader struct {
+	Id uint64 `json:"id"`
 }
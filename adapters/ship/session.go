@@ -0,0 +1,142 @@
+package ship
+
+import (
+	"net"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/adapters/framing"
+)
+
+// request waiting for the response from ship control
+type request struct {
+	id   uint64
+	cmd  string
+	data string
+	// number of times the command has been sent
+	attempt  int
+	deadline time.Time
+	// timed out, kept in the queue to consume a late response without id
+	abandoned bool
+}
+
+func (r *request) isQuery() bool {
+	return r.cmd == ""
+}
+
+type response struct {
+	data []byte
+	err  error
+}
+
+// state of a single connection to ship control, requests are pipelined
+// and responses are read by a separate goroutine
+type session struct {
+	conn   net.Conn
+	framed *framing.Conn
+	// requests in the order they were sent
+	pending []*request
+	// last request sent for each command, older ones are superseded
+	latestCmd map[string]*request
+	// ship control echoes request ids, responses can be matched out of order
+	idsEchoed bool
+	// consecutive requests without response
+	timeouts int
+}
+
+func newSession(conn net.Conn, mode framing.Mode) *session {
+	return &session{
+		conn:      conn,
+		framed:    framing.NewConn(conn, mode),
+		pending:   make([]*request, 0),
+		latestCmd: make(map[string]*request),
+	}
+}
+
+func (s *session) read(responseCh chan<- response, done <-chan bool) {
+	for {
+		data, err := s.framed.ReadMessage()
+		select {
+		case responseCh <- response{data: data, err: err}:
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *session) write(data []byte, timeout time.Duration) error {
+	err := s.conn.SetWriteDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return err
+	}
+	return s.framed.WriteMessage(data)
+}
+
+func (s *session) add(req *request) {
+	s.pending = append(s.pending, req)
+	if !req.isQuery() {
+		s.latestCmd[req.cmd] = req
+	}
+}
+
+// returns the request the response with the given id belongs to,
+// the oldest request if ship control does not echo ids
+func (s *session) match(id uint64) *request {
+	if id != 0 {
+		s.idsEchoed = true
+		for i, req := range s.pending {
+			if req.id == id {
+				s.pending = append(s.pending[:i], s.pending[i+1:]...)
+				return req
+			}
+		}
+		return nil
+	}
+
+	if len(s.pending) == 0 {
+		return nil
+	}
+	req := s.pending[0]
+	s.pending = s.pending[1:]
+	return req
+}
+
+func (s *session) queryPending() bool {
+	for _, req := range s.pending {
+		if req.isQuery() && !req.abandoned {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *session) superseded(req *request) bool {
+	return !req.isQuery() && s.latestCmd[req.cmd] != req
+}
+
+// marks the requests with passed deadline as abandoned and returns them
+func (s *session) expire(now time.Time) []*request {
+	expired := make([]*request, 0)
+	for _, req := range s.pending {
+		if !req.abandoned && !now.Before(req.deadline) {
+			req.abandoned = true
+			s.timeouts++
+			expired = append(expired, req)
+		}
+	}
+
+	if s.idsEchoed {
+		// late responses are matched by id, abandoned requests are not needed anymore
+		active := s.pending[:0]
+		for _, req := range s.pending {
+			if !req.abandoned {
+				active = append(active, req)
+			}
+		}
+		s.pending = active
+	}
+
+	return expired
+}
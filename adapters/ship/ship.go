@@ -3,9 +3,12 @@ package ship
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"time"
 
+	"github.com/moosethebrown/ship-nav/adapters/backoff"
+	"github.com/moosethebrown/ship-nav/adapters/framing"
 	"github.com/moosethebrown/ship-nav/core"
 	"github.com/moosethebrown/ship-nav/core/model"
 	"github.com/rs/zerolog"
@@ -14,28 +17,63 @@ import (
 type Configurer interface {
 	ShipSocketName() string
 	ShipPollingInterval() int64
+	ShipReconnectInterval() int64
+	ShipMaxReconnectInterval() int64
+	ShipFraming() string
+	ShipRequestTimeout() int64
+	ShipCommandRetries() int
 }
 
+const (
+	defaultRequestTimeout    = 1000
+	deadlineChecksPerTimeout = 4
+	// consecutive requests without response after which ship control is considered hung
+	maxTimeouts = 3
+)
+
 type Adapter struct {
-	logger          *zerolog.Logger
-	socketName      string
-	pollingInterval int64
-	shipDataUpdater core.ShipDataUpdater
-	stopCh          chan bool
-	speedCh         chan string
-	steeringCh      chan string
+	logger               *zerolog.Logger
+	socketName           string
+	pollingInterval      int64
+	reconnectInterval    int64
+	maxReconnectInterval int64
+	framing              framing.Mode
+	requestTimeout       time.Duration
+	commandRetries       int
+	shipDataUpdater      core.ShipDataUpdater
+	connectionUpdater    core.ShipConnectionUpdater
+	cmdFailureUpdater    core.ShipCommandFailureUpdater
+	nextId               uint64
+	connected            bool
+	connectionReported   bool
+	stopCh               chan bool
+	mailbox              *mailbox
 }
 
 func NewAdapter(logger *zerolog.Logger, configurer Configurer,
 	shipDataUpdater core.ShipDataUpdater) *Adapter {
+	framingMode, err := framing.ParseMode(configurer.ShipFraming())
+	if err != nil {
+		logger.Error().Err(err).Msgf("Using %s framing", framingMode)
+	}
+
+	requestTimeout := configurer.ShipRequestTimeout()
+	if requestTimeout <= 0 {
+		requestTimeout = defaultRequestTimeout
+	}
+
 	return &Adapter{
-		logger:          logger,
-		socketName:      configurer.ShipSocketName(),
-		pollingInterval: configurer.ShipPollingInterval(),
-		shipDataUpdater: shipDataUpdater,
-		stopCh:          make(chan bool, 1),
-		speedCh:         make(chan string, 1),
-		steeringCh:      make(chan string, 1),
+		logger:               logger,
+		socketName:           configurer.ShipSocketName(),
+		pollingInterval:      configurer.ShipPollingInterval(),
+		reconnectInterval:    configurer.ShipReconnectInterval(),
+		maxReconnectInterval: configurer.ShipMaxReconnectInterval(),
+		framing:              framingMode,
+		requestTimeout:       time.Duration(requestTimeout) * time.Millisecond,
+		commandRetries:       configurer.ShipCommandRetries(),
+		shipDataUpdater:      shipDataUpdater,
+		stopCh:               make(chan bool, 1),
+		mailbox:              newMailbox(),
 	}
 }
 
@@ -43,120 +81,264 @@ func (a *Adapter) SetShipDataUpdater(shipDataUpdater core.ShipDataUpdater) {
 	a.shipDataUpdater = shipDataUpdater
 }
 
+func (a *Adapter) SetConnectionUpdater(connectionUpdater core.ShipConnectionUpdater) {
+	a.connectionUpdater = connectionUpdater
+}
+
+func (a *Adapter) SetCommandFailureUpdater(commandFailureUpdater core.ShipCommandFailureUpdater) {
+	a.cmdFailureUpdater = commandFailureUpdater
+}
+
+// connects to ship control and reconnects with exponential backoff if the connection fails
 func (a *Adapter) Run() {
-	conn, err := net.Dial("unix", a.socketName)
-	if err != nil {
-		a.logger.Error().Err(err).Msg("Failed to connect to socket")
-		return
+	reconnect := backoff.NewBackoff(a.reconnectInterval, a.maxReconnectInterval)
+	for {
+		conn, err := net.Dial("unix", a.socketName)
+		if err != nil {
+			a.setConnected(false)
+			delay := reconnect.Next()
+			a.logger.Error().Err(err).Msgf("Failed to connect to socket, retrying in %s", delay)
+			if !a.wait(delay) {
+				return
+			}
+			continue
+		}
+
+		a.logger.Info().Msg("Connected to ship control")
+		reconnect.Reset()
+		a.setConnected(true)
+		stopped := a.serve(conn)
+		conn.Close()
+		if stopped {
+			return
+		}
+		a.setConnected(false)
 	}
-	defer conn.Close()
+}
+
+// returns true if the adapter is stopped, false if the connection is broken
+func (a *Adapter) serve(conn net.Conn) bool {
+	s := newSession(conn, a.framing)
+	a.mailbox.reset()
+	responseCh := make(chan response)
+	done := make(chan bool)
+	defer close(done)
+	go s.read(responseCh, done)
 
 	ticker := time.NewTicker(time.Duration(a.pollingInterval) * time.Millisecond)
 	defer ticker.Stop()
+	deadlineTicker := time.NewTicker(a.requestTimeout / deadlineChecksPerTimeout)
+	defer deadlineTicker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			queryResponse, err := a.query(conn)
-			if err != nil {
-				a.logger.Error().Err(err).Msg("Failed to send IPCQuery")
+			if s.queryPending() {
+				a.logger.Debug().Msg("Previous IPCQuery is not answered yet, skipping")
 				continue
 			}
-			shipData := &model.ShipData{
-				Speed:    queryResponse.Speed,
-				Steering: queryResponse.Steering,
-			}
-			a.shipDataUpdater.UpdateShipData(shipData)
-		case speed := <-a.speedCh:
-			resp, err := a.command(conn, "set_speed", speed)
+			err := a.send(s, &request{})
 			if err != nil {
-				a.logger.Error().Err(err).Msg("Failed to send set_speed command")
+				a.logger.Error().Err(err).Msg("Failed to send IPCQuery")
+				if backoff.IsConnectionError(err) {
+					return false
+				}
 			}
-			if resp.Status != "ok" {
-				a.logger.Error().Err(errors.New(resp.Error)).Msg("set_speed command returned error")
+		case resp := <-responseCh:
+			if resp.err != nil {
+				a.logger.Error().Err(resp.err).Msg("Failed to read response")
+				return false
 			}
-		case steering := <-a.steeringCh:
-			resp, err := a.command(conn, "set_steering", steering)
-			if err != nil {
-				a.logger.Error().Err(err).Msg("Failed to send set_steering command")
+			a.handleResponse(s, resp.data)
+		case now := <-deadlineTicker.C:
+			if !a.checkDeadlines(s, now) {
+				return false
 			}
-			if resp.Status != "ok" {
-				a.logger.Error().Err(errors.New(resp.Error)).Msg("set_steering command returned error")
+		case <-a.mailbox.notifyCh:
+			for _, c := range a.mailbox.take() {
+				if !a.sendCommand(s, c.cmd, c.data, 1) {
+					return false
+				}
 			}
 		case <-a.stopCh:
-			return
+			return true
+		}
+	}
+}
+
+// waits before the next connection attempt, commands can not be delivered
+// while disconnected and are dropped so that core is never blocked,
+// returns false if the adapter is stopped
+func (a *Adapter) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return true
+		case <-a.mailbox.notifyCh:
+			a.logger.Warn().Msgf("Not connected, %d commands are dropped", a.mailbox.drop())
+		case <-a.stopCh:
+			return false
 		}
 	}
 }
 
+func (a *Adapter) setConnected(connected bool) {
+	if a.connectionReported && a.connected == connected {
+		return
+	}
+	a.connected = connected
+	a.connectionReported = true
+	if a.connectionUpdater != nil {
+		a.connectionUpdater.UpdateShipConnection(connected)
+	}
+}
+
 func (a *Adapter) Stop() {
 	a.stopCh <- true
 }
 
-func (a *Adapter) SetSpeed(speed string) {
-	a.speedCh <- speed
+// commands never block the caller, only the newest speed and steering are sent
+func (a *Adapter) SetSpeed(speed model.Speed) {
+	a.mailbox.put("set_speed", speed.String())
 }
 
-func (a *Adapter) SetSteering(steering string) {
-	a.steeringCh <- steering
+func (a *Adapter) SetSteering(steering model.Steering) {
+	a.mailbox.put("set_steering", steering.String())
 }
 
-func (a *Adapter) query(conn net.Conn) (*IPCQueryResponse, error) {
-	req := &IPCRequest{
+func (a *Adapter) send(s *session, req *request) error {
+	a.nextId++
+	req.id = a.nextId
+
+	ipcRequest := &IPCRequest{
+		Id:   req.id,
 		Type: "query",
 	}
-	data, err := json.Marshal(req)
+	if !req.isQuery() {
+		ipcRequest.Type = "cmd"
+		ipcRequest.Cmd = req.cmd
+		ipcRequest.Data = req.data
+	}
+	data, err := json.Marshal(ipcRequest)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	_, err = conn.Write(data)
+	err = s.write(data, a.requestTimeout)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	resp := &IPCQueryResponse{}
-	buf := make([]byte, 4096)
-	n, err := conn.Read(buf)
+	req.deadline = time.Now().Add(a.requestTimeout)
+	s.add(req)
+	return nil
+}
+
+// returns false if the connection is broken
+func (a *Adapter) sendCommand(s *session, cmd string, data string, attempt int) bool {
+	err := a.send(s, &request{
+		cmd:     cmd,
+		data:    data,
+		attempt: attempt,
+	})
 	if err != nil {
-		return nil, err
+		a.logger.Error().Err(err).Msgf("Failed to send %s command", cmd)
+		if backoff.IsConnectionError(err) {
+			// commands are lost together with the connection, core is notified about connection loss
+			return false
+		}
+		a.commandFailed(cmd, data, err)
 	}
+	return true
+}
 
-	err = json.Unmarshal(buf[:n], resp)
+func (a *Adapter) handleResponse(s *session, data []byte) {
+	header := &ipcResponseHeader{}
+	err := json.Unmarshal(data, header)
 	if err != nil {
-		return nil, err
+		a.logger.Error().Err(err).Msg("Failed to unmarshal response")
+		return
 	}
 
-	return resp, nil
-}
-
-func (a *Adapter) command(conn net.Conn, cmd string, data string) (*IPCCommandResponse, error) {
-	req := &IPCRequest{
-		Type: "cmd",
-		Cmd:  cmd,
-		Data: data,
+	s.timeouts = 0
+	req := s.match(header.Id)
+	if req == nil {
+		a.logger.Warn().Msgf("Unexpected response id %d", header.Id)
+		return
 	}
-	jsonRq, err := json.Marshal(req)
-	if err != nil {
-		return nil, err
+	if req.abandoned {
+		a.logger.Debug().Msgf("Late response to request id %d is dropped", req.id)
+		return
 	}
 
-	_, err = conn.Write(jsonRq)
-	if err != nil {
-		return nil, err
+	if req.isQuery() {
+		queryResponse := &IPCQueryResponse{}
+		err = json.Unmarshal(data, queryResponse)
+		if err != nil {
+			a.logger.Error().Err(err).Msg("Failed to unmarshal IPCQuery response")
+			return
+		}
+		a.mailbox.reconcile(map[string]string{
+			"set_speed":    queryResponse.Speed.String(),
+			"set_steering": queryResponse.Steering.String(),
+		})
+		shipData := &model.ShipData{
+			Speed:     queryResponse.Speed,
+			Steering:  queryResponse.Steering,
+			Timestamp: time.Now(),
+			Commands:  a.mailbox.snapshot(),
+		}
+		a.shipDataUpdater.UpdateShipData(shipData)
+		return
 	}
 
-	resp := &IPCCommandResponse{}
-	buf := make([]byte, 4096)
-	n, err := conn.Read(buf)
+	cmdResponse := &IPCCommandResponse{}
+	err = json.Unmarshal(data, cmdResponse)
 	if err != nil {
-		return nil, err
+		a.logger.Error().Err(err).Msgf("Failed to unmarshal %s command response", req.cmd)
+		return
+	}
+	if cmdResponse.Status != "ok" && !s.superseded(req) {
+		a.commandFailed(req.cmd, req.data, errors.New(cmdResponse.Error))
 	}
+}
 
-	err = json.Unmarshal(buf[:n], resp)
-	if err != nil {
-		return nil, err
+// commands without response are retried until the retries are exhausted,
+// returns false if ship control does not respond and the connection has to be reestablished
+func (a *Adapter) checkDeadlines(s *session, now time.Time) bool {
+	for _, req := range s.expire(now) {
+		if req.isQuery() {
+			a.logger.Warn().Msgf("IPCQuery id %d timed out", req.id)
+			continue
+		}
+		if s.superseded(req) {
+			a.logger.Debug().Msgf("%s %s command timed out, superseded by a newer one", req.cmd, req.data)
+			continue
+		}
+		if req.attempt > a.commandRetries {
+			a.commandFailed(req.cmd, req.data, fmt.Errorf("no response after %d attempts", req.attempt))
+			continue
+		}
+		a.logger.Warn().Msgf("%s %s command timed out, retrying", req.cmd, req.data)
+		if !a.sendCommand(s, req.cmd, req.data, req.attempt+1) {
+			return false
+		}
 	}
 
-	return resp, nil
+	if s.timeouts >= maxTimeouts {
+		a.logger.Error().Msgf("Ship control did not respond to %d requests, reconnecting", s.timeouts)
+		return false
+	}
+	return true
+}
+
+func (a *Adapter) commandFailed(cmd string, data string, err error) {
+	a.logger.Error().Err(err).Msgf("%s %s command failed", cmd, data)
+	a.mailbox.failed(cmd)
+	if a.cmdFailureUpdater != nil {
+		a.cmdFailureUpdater.ShipCommandFailed(cmd, data, err)
+	}
 }
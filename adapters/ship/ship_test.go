@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/moosethebrown/ship-nav/adapters/framing"
 	"github.com/moosethebrown/ship-nav/core/model"
 	"github.com/rs/zerolog"
 )
@@ -16,7 +19,11 @@ const (
 	testSocket = "/tmp/ship_testsock"
 )
 
-type mockShipConfigurer struct{}
+type mockShipConfigurer struct {
+	framing        string
+	requestTimeout int64
+	commandRetries int
+}
 
 func (m *mockShipConfigurer) ShipSocketName() string {
 	return testSocket
@@ -26,6 +33,65 @@ func (m *mockShipConfigurer) ShipPollingInterval() int64 {
 	return 100
 }
 
+func (m *mockShipConfigurer) ShipFraming() string {
+	return m.framing
+}
+
+func (m *mockShipConfigurer) ShipRequestTimeout() int64 {
+	return m.requestTimeout
+}
+
+func (m *mockShipConfigurer) ShipCommandRetries() int {
+	return m.commandRetries
+}
+
+func (m *mockShipConfigurer) ShipReconnectInterval() int64 {
+	return 50
+}
+
+func (m *mockShipConfigurer) ShipMaxReconnectInterval() int64 {
+	return 200
+}
+
+type mockConnectionUpdater struct {
+	mutex  sync.Mutex
+	states []bool
+}
+
+func (m *mockConnectionUpdater) UpdateShipConnection(connected bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.states = append(m.states, connected)
+}
+
+func (m *mockConnectionUpdater) getStates() []bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]bool{}, m.states...)
+}
+
+type mockCommandFailure struct {
+	cmd  string
+	data string
+}
+
+type mockCommandFailureUpdater struct {
+	mutex    sync.Mutex
+	failures []mockCommandFailure
+}
+
+func (m *mockCommandFailureUpdater) ShipCommandFailed(cmd string, data string, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.failures = append(m.failures, mockCommandFailure{cmd: cmd, data: data})
+}
+
+func (m *mockCommandFailureUpdater) getFailures() []mockCommandFailure {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]mockCommandFailure{}, m.failures...)
+}
+
 type mockShipDataUpdater struct {
 	shipData *model.ShipData
 }
@@ -42,12 +108,23 @@ type mockShipControl struct {
 	steering    string
 	numQueries  int
 	numCmds     int
+	framing     framing.Mode
+	// responses carry the request id
+	echoIds bool
+	// commands are received but not answered
+	ignoreCmds bool
+	// no requests are answered
+	hung bool
+	// commands are answered with fail status
+	failCmds bool
 }
 
 func newMockShipControl(socketName string) *mockShipControl {
 	return &mockShipControl{
 		socketName:  socketName,
 		connections: make([]net.Conn, 0),
+		speed:       "stop",
+		steering:    "straight",
 	}
 }
 
@@ -86,26 +163,38 @@ func (m *mockShipControl) stop() {
 func (m *mockShipControl) handleConn(conn net.Conn) {
 	defer conn.Close()
 
-	buf := make([]byte, 4096)
+	framedConn := framing.NewConn(conn, m.framing)
 	for {
-		n, err := conn.Read(buf)
+		data, err := framedConn.ReadMessage()
 		if err != nil {
 			fmt.Printf("Failed to read data from unix socket: %s\n", err.Error())
 			return
 		}
 
 		rq := &IPCRequest{}
-		err = json.Unmarshal(buf[:n], &rq)
+		err = json.Unmarshal(data, &rq)
 		if err != nil {
 			fmt.Printf("Failed to unmarshal request: %s\n", err.Error())
 			return
 		}
 
 		var respData []byte
+		if m.hung {
+			continue
+		}
 		if rq.Type == "cmd" {
 			m.numCmds++
+			if m.ignoreCmds {
+				continue
+			}
 			resp := IPCCommandResponse{}
-			if rq.Cmd == "set_speed" {
+			if m.echoIds {
+				resp.Id = rq.Id
+			}
+			if m.failCmds {
+				resp.Status = "fail"
+				resp.Error = "Ship control failure"
+			} else if rq.Cmd == "set_speed" {
 				m.speed = rq.Data
 				resp.Status = "ok"
 			} else if rq.Cmd == "set_steering" {
@@ -121,9 +210,17 @@ func (m *mockShipControl) handleConn(conn net.Conn) {
 			}
 		} else if rq.Type == "query" {
 			m.numQueries++
-			resp := IPCQueryResponse{
-				Speed:    m.speed,
-				Steering: m.steering,
+			resp := IPCQueryResponse{}
+			resp.Speed, err = model.ParseSpeed(m.speed)
+			if err != nil {
+				fmt.Printf("Invalid speed: %s\n", err.Error())
+			}
+			resp.Steering, err = model.ParseSteering(m.steering)
+			if err != nil {
+				fmt.Printf("Invalid steering: %s\n", err.Error())
+			}
+			if m.echoIds {
+				resp.Id = rq.Id
 			}
 			respData, err = json.Marshal(resp)
 			if err != nil {
@@ -141,7 +238,7 @@ func (m *mockShipControl) handleConn(conn net.Conn) {
 		}
 
 		if respData != nil {
-			_, err = conn.Write(respData)
+			err = framedConn.WriteMessage(respData)
 			if err != nil {
 				fmt.Printf("Failed to send response: %s\n", err.Error())
 				return
@@ -161,6 +258,7 @@ func setupTest() (*mockShipControl, *mockShipDataUpdater, *Adapter) {
 
 func TestQuery(t *testing.T) {
 	mockShipControl, mockShipDataUpdater, adapter := setupTest()
+	start := time.Now()
 
 	go mockShipControl.run()
 	defer mockShipControl.stop()
@@ -178,12 +276,15 @@ func TestQuery(t *testing.T) {
 	if mockShipControl.numQueries != 1 {
 		t.Fatalf("Expected to receive 1 query, got %d", mockShipControl.numQueries)
 	}
-	if mockShipDataUpdater.shipData.Speed != "rev30" {
+	if mockShipDataUpdater.shipData.Speed != model.Reverse(30) {
 		t.Errorf("Expected speed to be rev30, got %s", mockShipDataUpdater.shipData.Speed)
 	}
-	if mockShipDataUpdater.shipData.Steering != "left10" {
+	if mockShipDataUpdater.shipData.Steering != model.Left(10) {
 		t.Errorf("Expected steering to be left10, got %s", mockShipDataUpdater.shipData.Steering)
 	}
+	if mockShipDataUpdater.shipData.Timestamp.Before(start) {
+		t.Errorf("Expected ship data timestamp to be set, got %s", mockShipDataUpdater.shipData.Timestamp)
+	}
 }
 
 func TestCommand(t *testing.T) {
@@ -200,23 +301,233 @@ func TestCommand(t *testing.T) {
 	mockShipControl.speed = "fwd100"
 	mockShipControl.steering = "straight"
 
-	adapter.SetSpeed("rev80")
+	adapter.SetSpeed(model.Reverse(80))
 	time.Sleep(120 * time.Millisecond)
 
 	if mockShipControl.numCmds != 1 {
 		t.Fatalf("Expected to receive 1 command, got %d", mockShipControl.numCmds)
 	}
-	if mockShipDataUpdater.shipData.Speed != "rev80" {
+	if mockShipDataUpdater.shipData.Speed != model.Reverse(80) {
 		t.Errorf("Expected speed to be rev80, got %s", mockShipDataUpdater.shipData.Speed)
 	}
 
-	adapter.SetSteering("right70")
+	adapter.SetSteering(model.Right(70))
 	time.Sleep(100 * time.Millisecond)
 
 	if mockShipControl.numCmds != 2 {
 		t.Fatalf("Expected to receive 2 commands, got %d", mockShipControl.numCmds)
 	}
-	if mockShipDataUpdater.shipData.Steering != "right70" {
+	if mockShipDataUpdater.shipData.Steering != model.Right(70) {
 		t.Errorf("Expected steering to be right70, got %s", mockShipDataUpdater.shipData.Steering)
 	}
 }
+
+func TestReconnect(t *testing.T) {
+	_, mockShipDataUpdater, adapter := setupTest()
+	mockConnectionUpdater := &mockConnectionUpdater{}
+	adapter.SetConnectionUpdater(mockConnectionUpdater)
+
+	// ship control is not running yet
+	go adapter.Run()
+	defer adapter.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	if states := mockConnectionUpdater.getStates(); !reflect.DeepEqual(states, []bool{false}) {
+		t.Fatalf("Expected connection states to be [false], got %v", states)
+	}
+
+	mockShipControl := newMockShipControl(testSocket)
+	go mockShipControl.run()
+	time.Sleep(350 * time.Millisecond)
+
+	if states := mockConnectionUpdater.getStates(); !reflect.DeepEqual(states, []bool{false, true}) {
+		t.Fatalf("Expected connection states to be [false true], got %v", states)
+	}
+	if mockShipControl.numQueries == 0 {
+		t.Errorf("Expected ship control to receive queries after connection")
+	}
+
+	// ship control restarts
+	mockShipControl.stop()
+	time.Sleep(200 * time.Millisecond)
+
+	if states := mockConnectionUpdater.getStates(); !reflect.DeepEqual(states, []bool{false, true, false}) {
+		t.Fatalf("Expected connection states to be [false true false], got %v", states)
+	}
+
+	// commands are not blocked while disconnected
+	adapter.SetSpeed(model.Forward(10))
+	adapter.SetSteering(model.Left(10))
+	adapter.SetSpeed(model.Forward(20))
+
+	mockShipControl = newMockShipControl(testSocket)
+	mockShipControl.speed = "rev20"
+	go mockShipControl.run()
+	defer mockShipControl.stop()
+	time.Sleep(500 * time.Millisecond)
+
+	if states := mockConnectionUpdater.getStates(); !reflect.DeepEqual(states, []bool{false, true, false, true}) {
+		t.Fatalf("Expected connection states to be [false true false true], got %v", states)
+	}
+	if mockShipControl.numQueries == 0 {
+		t.Errorf("Expected ship control to receive queries after reconnection")
+	}
+	if mockShipDataUpdater.shipData.Speed != model.Reverse(20) {
+		t.Errorf("Expected speed to be rev20, got %s", mockShipDataUpdater.shipData.Speed)
+	}
+}
+
+func TestFraming(t *testing.T) {
+	for _, mode := range []framing.Mode{framing.ModeNewline, framing.ModeLengthPrefixed} {
+		mockShipControl := newMockShipControl(testSocket)
+		mockShipControl.framing = mode
+		mockShipDataUpdater := &mockShipDataUpdater{}
+		logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+		adapter := NewAdapter(&logger, &mockShipConfigurer{framing: mode.String()}, mockShipDataUpdater)
+
+		go mockShipControl.run()
+		time.Sleep(50 * time.Millisecond)
+		go adapter.Run()
+
+		adapter.SetSpeed(model.Forward(60))
+		time.Sleep(120 * time.Millisecond)
+
+		if mockShipControl.numCmds != 1 {
+			t.Errorf("%s: expected to receive 1 command, got %d", mode, mockShipControl.numCmds)
+		}
+		if mockShipDataUpdater.shipData == nil || mockShipDataUpdater.shipData.Speed != model.Forward(60) {
+			t.Errorf("%s: expected speed to be fwd60, got %v", mode, mockShipDataUpdater.shipData)
+		}
+
+		adapter.Stop()
+		mockShipControl.stop()
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestCommandRetries(t *testing.T) {
+	mockShipControl := newMockShipControl(testSocket)
+	mockShipControl.echoIds = true
+	mockShipControl.ignoreCmds = true
+	mockShipDataUpdater := &mockShipDataUpdater{}
+	mockCommandFailureUpdater := &mockCommandFailureUpdater{}
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	adapter := NewAdapter(&logger, &mockShipConfigurer{requestTimeout: 60, commandRetries: 2},
+		mockShipDataUpdater)
+	adapter.SetCommandFailureUpdater(mockCommandFailureUpdater)
+
+	go mockShipControl.run()
+	defer mockShipControl.stop()
+	time.Sleep(50 * time.Millisecond)
+
+	go adapter.Run()
+	defer adapter.Stop()
+
+	// commands without response are retried while queries sent in between are answered
+	mockShipControl.speed = "fwd20"
+	adapter.SetSpeed(model.Forward(50))
+	time.Sleep(300 * time.Millisecond)
+
+	if mockShipControl.numCmds != 3 {
+		t.Errorf("Expected to receive 3 commands, got %d", mockShipControl.numCmds)
+	}
+	if mockShipDataUpdater.shipData == nil || mockShipDataUpdater.shipData.Speed != model.Forward(20) {
+		t.Errorf("Expected speed to be fwd20, got %v", mockShipDataUpdater.shipData)
+	}
+	failures := mockCommandFailureUpdater.getFailures()
+	expected := []mockCommandFailure{{cmd: "set_speed", data: "fwd50"}}
+	if !reflect.DeepEqual(failures, expected) {
+		t.Errorf("Expected command failures to be %v, got %v", expected, failures)
+	}
+}
+
+func TestCommandFailure(t *testing.T) {
+	mockShipControl, _, adapter := setupTest()
+	mockShipControl.failCmds = true
+	mockCommandFailureUpdater := &mockCommandFailureUpdater{}
+	adapter.SetCommandFailureUpdater(mockCommandFailureUpdater)
+
+	go mockShipControl.run()
+	defer mockShipControl.stop()
+	time.Sleep(50 * time.Millisecond)
+
+	go adapter.Run()
+	defer adapter.Stop()
+
+	adapter.SetSteering(model.Left(30))
+	time.Sleep(120 * time.Millisecond)
+
+	// ship control answered, the command is not retried
+	if mockShipControl.numCmds != 1 {
+		t.Errorf("Expected to receive 1 command, got %d", mockShipControl.numCmds)
+	}
+	failures := mockCommandFailureUpdater.getFailures()
+	expected := []mockCommandFailure{{cmd: "set_steering", data: "left30"}}
+	if !reflect.DeepEqual(failures, expected) {
+		t.Errorf("Expected command failures to be %v, got %v", expected, failures)
+	}
+}
+
+func TestHungShipControl(t *testing.T) {
+	mockShipControl := newMockShipControl(testSocket)
+	mockShipControl.hung = true
+	mockShipDataUpdater := &mockShipDataUpdater{}
+	mockConnectionUpdater := &mockConnectionUpdater{}
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+	adapter := NewAdapter(&logger, &mockShipConfigurer{requestTimeout: 40}, mockShipDataUpdater)
+	adapter.SetConnectionUpdater(mockConnectionUpdater)
+
+	go mockShipControl.run()
+	defer mockShipControl.stop()
+	time.Sleep(50 * time.Millisecond)
+
+	go adapter.Run()
+	defer adapter.Stop()
+
+	// the adapter keeps accepting commands
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		adapter.SetSpeed(model.Forward(i))
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected commands not to block, took %s", elapsed)
+	}
+
+	time.Sleep(400 * time.Millisecond)
+
+	states := mockConnectionUpdater.getStates()
+	if len(states) < 3 || !reflect.DeepEqual(states[:3], []bool{true, false, true}) {
+		t.Errorf("Expected the connection to be reestablished, got states %v", states)
+	}
+	if mockShipDataUpdater.shipData != nil {
+		t.Errorf("Expected no ship data, got %v", mockShipDataUpdater.shipData)
+	}
+}
+
+func TestCommandDeduplication(t *testing.T) {
+	mockShipControl, mockShipDataUpdater, adapter := setupTest()
+
+	go mockShipControl.run()
+	defer mockShipControl.stop()
+	time.Sleep(50 * time.Millisecond)
+
+	go adapter.Run()
+	defer adapter.Stop()
+
+	for i := 0; i < 5; i++ {
+		adapter.SetSpeed(model.Forward(50))
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if mockShipControl.numCmds != 1 {
+		t.Errorf("Expected to receive 1 command, got %d", mockShipControl.numCmds)
+	}
+	if mockShipDataUpdater.shipData == nil {
+		t.Fatalf("Expected ship data to be updated")
+	}
+	commands := mockShipDataUpdater.shipData.Commands
+	if commands.Sent != 1 || commands.Deduplicated != 4 {
+		t.Errorf("Expected 1 command sent and 4 deduplicated, got %+v", commands)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"sync"
 
+	"github.com/moosethebrown/ship-nav/adapters/framing"
 	"github.com/moosethebrown/ship-nav/adapters/network"
 	"github.com/moosethebrown/ship-nav/adapters/position"
 	"github.com/moosethebrown/ship-nav/adapters/ship"
@@ -84,11 +85,18 @@ func (app *App) init() {
 	app.theCore = core.NewCore(app.conf, app.shipAdapter, &coreLogger)
 
 	app.shipAdapter.SetShipDataUpdater(app.theCore)
+	app.shipAdapter.SetConnectionUpdater(app.theCore)
+	app.shipAdapter.SetCommandFailureUpdater(app.theCore)
 
 	positionAdapterLogger := app.logger.With().Str("component", "position-adapter").Logger()
-	app.positionAdapter = position.NewAdapter(&positionAdapterLogger, app.conf, app.theCore, app.theCore)
+	app.positionAdapter = position.NewAdapter(&positionAdapterLogger, app.conf, app.theCore, app.theCore,
+		app.theCore)
 
 	networkAdapterLogger := app.logger.With().Str("component", "network-adapter").Logger()
-	app.networkAdapter = network.NewAdapter(app.conf.NetworkSocketName(), app.theCore, app.theCore,
-		app.theCore, app.theCore, app.theCore, &networkAdapterLogger)
+	networkFraming, err := framing.ParseMode(app.conf.NetworkFraming())
+	if err != nil {
+		networkAdapterLogger.Error().Err(err).Msgf("Using %s framing", networkFraming)
+	}
+	app.networkAdapter = network.NewAdapter(app.conf.NetworkSocketName(), networkFraming, app.theCore,
+		app.theCore, app.theCore, app.theCore, app.theCore, app.positionAdapter, &networkAdapterLogger)
 }
@@ -2,34 +2,100 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+
+	"github.com/moosethebrown/ship-nav/core/model"
 )
 
 type coreConfig struct {
-	Declination          float64 `json:"declination"`
-	UpdateBufSize        int     `json:"updateBufSize"`
-	TurningSpeed         string  `json:"turningSpeed"`
-	TurningSteeringLeft  string  `json:"turningSteeringLeft"`
-	TurningSteeringRight string  `json:"turningSteeringRight"`
-	ApproachSpeed        string  `json:"approachSpeed"`
-	FullSpeed            string  `json:"fullSpeed"`
-	ApproachDistance     float64 `json:"approachDistance"`
-	DistanceInaccuracy   float64 `json:"distanceInaccuracy"`
+	Declination             float64        `json:"declination"`
+	UpdateBufSize           int            `json:"updateBufSize"`
+	TurningSpeed            model.Speed    `json:"turningSpeed"`
+	TurningSteeringLeft     model.Steering `json:"turningSteeringLeft"`
+	TurningSteeringRight    model.Steering `json:"turningSteeringRight"`
+	ApproachSpeed           model.Speed    `json:"approachSpeed"`
+	FullSpeed               model.Speed    `json:"fullSpeed"`
+	ApproachDistance        float64        `json:"approachDistance"`
+	DecelerationDistance    float64        `json:"decelerationDistance"`
+	DistanceInaccuracy      float64        `json:"distanceInaccuracy"`
+	HeadingKp               float64        `json:"headingKp"`
+	HeadingKi               float64        `json:"headingKi"`
+	HeadingKd               float64        `json:"headingKd"`
+	HeadingIntegralLimit    float64        `json:"headingIntegralLimit"`
+	HeadingMaxSteering      int            `json:"headingMaxSteering"`
+	HeadingSteeringStep     int            `json:"headingSteeringStep"`
+	CrossTrackGain          float64        `json:"crossTrackGain"`
+	MaxCrossTrackCorrection float64        `json:"maxCrossTrackCorrection"`
+	MaxCrossTrack           float64        `json:"maxCrossTrack"`
+	GeodesicModel           string         `json:"geodesicModel"`
+	EstimatorEnabled        bool           `json:"estimatorEnabled"`
+	EstimatorGpsNoise       float64        `json:"estimatorGpsNoise"`
+	EstimatorSpeedNoise     float64        `json:"estimatorSpeedNoise"`
+	EstimatorHeadingNoise   float64        `json:"estimatorHeadingNoise"`
+	EstimatorAccelNoise     float64        `json:"estimatorAccelNoise"`
+	EstimatorYawAccelNoise  float64        `json:"estimatorYawAccelNoise"`
+	FixMinSatellites        int            `json:"fixMinSatellites"`
+	FixMaxHdop              float64        `json:"fixMaxHdop"`
+	FixMinType              int            `json:"fixMinType"`
+	FixMaxAge               int64          `json:"fixMaxAge"`
+	MaxPositionAge          int64          `json:"maxPositionAge"`
+	MaxBearingAge           int64          `json:"maxBearingAge"`
+	MaxShipDataAge          int64          `json:"maxShipDataAge"`
+	SpeedAccelRate          float64        `json:"speedAccelRate"`
+	SpeedDecelRate          float64        `json:"speedDecelRate"`
+	SpeedRampInterval       int64          `json:"speedRampInterval"`
+	SogControlEnabled       bool           `json:"sogControlEnabled"`
+	SogKp                   float64        `json:"sogKp"`
+	SogKi                   float64        `json:"sogKi"`
+	SogIntegralLimit        float64        `json:"sogIntegralLimit"`
+	SogTargetSpeed          float64        `json:"sogTargetSpeed"`
+	SogApproachSpeed        float64        `json:"sogApproachSpeed"`
+	SogMinThrottle          model.Speed    `json:"sogMinThrottle"`
+	SogMaxThrottle          model.Speed    `json:"sogMaxThrottle"`
+	SogMaxAge               int64          `json:"sogMaxAge"`
+	MissionEnd              string         `json:"missionEnd"`
+	MissionRepeat           int            `json:"missionRepeat"`
+	ArrivalMode             string         `json:"arrivalMode"`
+	ArrivalMaxMiss          float64        `json:"arrivalMaxMiss"`
+	LoiterRadius            float64        `json:"loiterRadius"`
+	LoiterSpeed             model.Speed    `json:"loiterSpeed"`
+	TurnTolerance           float64        `json:"turnTolerance"`
+	TurnDwell               int64          `json:"turnDwell"`
+	TurnLeadTime            int64          `json:"turnLeadTime"`
+	TurnTimeout             int64          `json:"turnTimeout"`
+	NoGoZones               [][][2]float64 `json:"noGoZones"`
+	NoGoZoneMargin          float64        `json:"noGoZoneMargin"`
+	GeofenceKeepIn          [][2]float64   `json:"geofenceKeepIn"`
+	GeofenceMaxHomeDistance float64        `json:"geofenceMaxHomeDistance"`
+	MaxLegLength            float64        `json:"maxLegLength"`
 }
 
 type networkConfig struct {
 	SocketName string `json:"socketName"`
+	Framing    string `json:"framing"`
 }
 
 type positionConfig struct {
-	SocketName      string `json:"socketName"`
-	PollingInterval int64  `json:"pollingInterval"`
+	SocketName           string `json:"socketName"`
+	PollingInterval      int64  `json:"pollingInterval"`
+	AttitudeData         bool   `json:"attitudeData"`
+	CalibrationFile      string `json:"calibrationFile"`
+	ReconnectInterval    int64  `json:"reconnectInterval"`
+	MaxReconnectInterval int64  `json:"maxReconnectInterval"`
+	Framing              string `json:"framing"`
 }
 
 type shipConfig struct {
-	SocketName      string `json:"socketName"`
-	PollingInterval int64  `json:"pollingInterval"`
+	SocketName           string `json:"socketName"`
+	PollingInterval      int64  `json:"pollingInterval"`
+	ReconnectInterval    int64  `json:"reconnectInterval"`
+	MaxReconnectInterval int64  `json:"maxReconnectInterval"`
+	Framing              string `json:"framing"`
+	RequestTimeout       int64  `json:"requestTimeout"`
+	CommandRetries       int    `json:"commandRetries"`
 }
 
 type Config struct {
@@ -57,9 +123,95 @@ func NewConfig(filename string) (*Config, error) {
 		return nil, err
 	}
 
+	err = config.validate()
+	if err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
+// values which are well-formed but can not be used for navigation
+func (c *Config) validate() error {
+	if c.CoreConfig == nil {
+		return errors.New("coreConfig is missing")
+	}
+	if !c.CoreConfig.TurningSpeed.IsForward() {
+		return fmt.Errorf("turningSpeed %s is not forward", c.CoreConfig.TurningSpeed)
+	}
+	if !c.CoreConfig.ApproachSpeed.IsForward() {
+		return fmt.Errorf("approachSpeed %s is not forward", c.CoreConfig.ApproachSpeed)
+	}
+	if !c.CoreConfig.FullSpeed.IsForward() {
+		return fmt.Errorf("fullSpeed %s is not forward", c.CoreConfig.FullSpeed)
+	}
+	if c.CoreConfig.ApproachSpeed > c.CoreConfig.FullSpeed {
+		return fmt.Errorf("approachSpeed %s exceeds fullSpeed %s", c.CoreConfig.ApproachSpeed,
+			c.CoreConfig.FullSpeed)
+	}
+	if !c.CoreConfig.TurningSteeringLeft.IsLeft() {
+		return fmt.Errorf("turningSteeringLeft %s is not left", c.CoreConfig.TurningSteeringLeft)
+	}
+	if !c.CoreConfig.TurningSteeringRight.IsRight() {
+		return fmt.Errorf("turningSteeringRight %s is not right", c.CoreConfig.TurningSteeringRight)
+	}
+	if _, err := model.ParseMissionEndAction(c.CoreConfig.MissionEnd); err != nil {
+		return err
+	}
+	if c.CoreConfig.MissionRepeat < 0 {
+		return fmt.Errorf("missionRepeat %d is negative", c.CoreConfig.MissionRepeat)
+	}
+	if _, err := model.ParseArrivalMode(c.CoreConfig.ArrivalMode); err != nil {
+		return err
+	}
+	if _, err := model.ParseGeodesic(c.CoreConfig.GeodesicModel); err != nil {
+		return err
+	}
+	// turning speed is used if the loiter speed is not set
+	if !c.CoreConfig.LoiterSpeed.IsStop() && !c.CoreConfig.LoiterSpeed.IsForward() {
+		return fmt.Errorf("loiterSpeed %s is not forward", c.CoreConfig.LoiterSpeed)
+	}
+	// the cross-track correction is applied through the heading controller
+	if c.CoreConfig.CrossTrackGain != 0 && c.CoreConfig.HeadingKp == 0 {
+		return errors.New("crossTrackGain requires the heading controller, headingKp is zero")
+	}
+	if c.CoreConfig.TurnTolerance < 0 || c.CoreConfig.TurnTolerance >= 180 {
+		return fmt.Errorf("turnTolerance %f is out of range [0, 180)", c.CoreConfig.TurnTolerance)
+	}
+	if c.CoreConfig.TurnDwell < 0 || c.CoreConfig.TurnLeadTime < 0 || c.CoreConfig.TurnTimeout < 0 {
+		return errors.New("turnDwell, turnLeadTime and turnTimeout must not be negative")
+	}
+	for i, zone := range c.CoreConfig.NoGoZones {
+		if _, err := model.NewPolygon(toWaypoints(zone)); err != nil {
+			return fmt.Errorf("noGoZones[%d]: %w", i, err)
+		}
+	}
+	if c.CoreConfig.NoGoZoneMargin < 0 {
+		return fmt.Errorf("noGoZoneMargin %f is negative", c.CoreConfig.NoGoZoneMargin)
+	}
+	if len(c.CoreConfig.GeofenceKeepIn) > 0 {
+		if _, err := model.NewPolygon(toWaypoints(c.CoreConfig.GeofenceKeepIn)); err != nil {
+			return fmt.Errorf("geofenceKeepIn: %w", err)
+		}
+	}
+	if c.CoreConfig.GeofenceMaxHomeDistance < 0 {
+		return fmt.Errorf("geofenceMaxHomeDistance %f is negative", c.CoreConfig.GeofenceMaxHomeDistance)
+	}
+	if c.CoreConfig.MaxLegLength < 0 {
+		return fmt.Errorf("maxLegLength %f is negative", c.CoreConfig.MaxLegLength)
+	}
+	if c.CoreConfig.SogControlEnabled {
+		if c.CoreConfig.SogMinThrottle.IsReverse() || c.CoreConfig.SogMaxThrottle.IsReverse() {
+			return errors.New("sogMinThrottle and sogMaxThrottle must not be reverse")
+		}
+		if c.CoreConfig.SogMinThrottle > c.CoreConfig.SogMaxThrottle {
+			return fmt.Errorf("sogMinThrottle %s exceeds sogMaxThrottle %s", c.CoreConfig.SogMinThrottle,
+				c.CoreConfig.SogMaxThrottle)
+		}
+	}
+	return nil
+}
+
 func (c *Config) Declination() float64 {
 	return c.CoreConfig.Declination
 }
@@ -68,23 +220,23 @@ func (c *Config) UpdateBufSize() int {
 	return c.CoreConfig.UpdateBufSize
 }
 
-func (c *Config) TurningSpeed() string {
+func (c *Config) TurningSpeed() model.Speed {
 	return c.CoreConfig.TurningSpeed
 }
 
-func (c *Config) TurningSteeringLeft() string {
+func (c *Config) TurningSteeringLeft() model.Steering {
 	return c.CoreConfig.TurningSteeringLeft
 }
 
-func (c *Config) TurningSteeringRight() string {
+func (c *Config) TurningSteeringRight() model.Steering {
 	return c.CoreConfig.TurningSteeringRight
 }
 
-func (c *Config) ApproachSpeed() string {
+func (c *Config) ApproachSpeed() model.Speed {
 	return c.CoreConfig.ApproachSpeed
 }
 
-func (c *Config) FullSpeed() string {
+func (c *Config) FullSpeed() model.Speed {
 	return c.CoreConfig.FullSpeed
 }
 
@@ -92,14 +244,249 @@ func (c *Config) ApproachDistance() float64 {
 	return c.CoreConfig.ApproachDistance
 }
 
+func (c *Config) DecelerationDistance() float64 {
+	return c.CoreConfig.DecelerationDistance
+}
+
 func (c *Config) DistanceInaccuracy() float64 {
 	return c.CoreConfig.DistanceInaccuracy
 }
 
+func (c *Config) HeadingKp() float64 {
+	return c.CoreConfig.HeadingKp
+}
+
+func (c *Config) HeadingKi() float64 {
+	return c.CoreConfig.HeadingKi
+}
+
+func (c *Config) HeadingKd() float64 {
+	return c.CoreConfig.HeadingKd
+}
+
+func (c *Config) HeadingIntegralLimit() float64 {
+	return c.CoreConfig.HeadingIntegralLimit
+}
+
+func (c *Config) HeadingMaxSteering() int {
+	return c.CoreConfig.HeadingMaxSteering
+}
+
+func (c *Config) HeadingSteeringStep() int {
+	return c.CoreConfig.HeadingSteeringStep
+}
+
+func (c *Config) CrossTrackGain() float64 {
+	return c.CoreConfig.CrossTrackGain
+}
+
+func (c *Config) MaxCrossTrackCorrection() float64 {
+	return c.CoreConfig.MaxCrossTrackCorrection
+}
+
+func (c *Config) MaxCrossTrack() float64 {
+	return c.CoreConfig.MaxCrossTrack
+}
+
+func (c *Config) GeodesicModel() string {
+	return c.CoreConfig.GeodesicModel
+}
+
+func (c *Config) EstimatorEnabled() bool {
+	return c.CoreConfig.EstimatorEnabled
+}
+
+func (c *Config) EstimatorGpsNoise() float64 {
+	return c.CoreConfig.EstimatorGpsNoise
+}
+
+func (c *Config) EstimatorSpeedNoise() float64 {
+	return c.CoreConfig.EstimatorSpeedNoise
+}
+
+func (c *Config) EstimatorHeadingNoise() float64 {
+	return c.CoreConfig.EstimatorHeadingNoise
+}
+
+func (c *Config) EstimatorAccelNoise() float64 {
+	return c.CoreConfig.EstimatorAccelNoise
+}
+
+func (c *Config) EstimatorYawAccelNoise() float64 {
+	return c.CoreConfig.EstimatorYawAccelNoise
+}
+
+func (c *Config) FixMinSatellites() int {
+	return c.CoreConfig.FixMinSatellites
+}
+
+func (c *Config) FixMaxHdop() float64 {
+	return c.CoreConfig.FixMaxHdop
+}
+
+func (c *Config) FixMinType() int {
+	return c.CoreConfig.FixMinType
+}
+
+func (c *Config) FixMaxAge() int64 {
+	return c.CoreConfig.FixMaxAge
+}
+
+func (c *Config) MaxPositionAge() int64 {
+	return c.CoreConfig.MaxPositionAge
+}
+
+func (c *Config) MaxBearingAge() int64 {
+	return c.CoreConfig.MaxBearingAge
+}
+
+func (c *Config) MaxShipDataAge() int64 {
+	return c.CoreConfig.MaxShipDataAge
+}
+
+func (c *Config) SpeedAccelRate() float64 {
+	return c.CoreConfig.SpeedAccelRate
+}
+
+func (c *Config) SpeedDecelRate() float64 {
+	return c.CoreConfig.SpeedDecelRate
+}
+
+func (c *Config) SpeedRampInterval() int64 {
+	return c.CoreConfig.SpeedRampInterval
+}
+
+func (c *Config) SogControlEnabled() bool {
+	return c.CoreConfig.SogControlEnabled
+}
+
+func (c *Config) SogKp() float64 {
+	return c.CoreConfig.SogKp
+}
+
+func (c *Config) SogKi() float64 {
+	return c.CoreConfig.SogKi
+}
+
+func (c *Config) SogIntegralLimit() float64 {
+	return c.CoreConfig.SogIntegralLimit
+}
+
+func (c *Config) SogTargetSpeed() float64 {
+	return c.CoreConfig.SogTargetSpeed
+}
+
+func (c *Config) SogApproachSpeed() float64 {
+	return c.CoreConfig.SogApproachSpeed
+}
+
+func (c *Config) SogMinThrottle() model.Speed {
+	return c.CoreConfig.SogMinThrottle
+}
+
+func (c *Config) SogMaxThrottle() model.Speed {
+	return c.CoreConfig.SogMaxThrottle
+}
+
+func (c *Config) SogMaxAge() int64 {
+	return c.CoreConfig.SogMaxAge
+}
+
+func (c *Config) MissionEnd() string {
+	return c.CoreConfig.MissionEnd
+}
+
+func (c *Config) MissionRepeat() int {
+	return c.CoreConfig.MissionRepeat
+}
+
+func (c *Config) ArrivalMode() string {
+	return c.CoreConfig.ArrivalMode
+}
+
+func (c *Config) ArrivalMaxMiss() float64 {
+	return c.CoreConfig.ArrivalMaxMiss
+}
+
+func (c *Config) LoiterRadius() float64 {
+	return c.CoreConfig.LoiterRadius
+}
+
+func (c *Config) LoiterSpeed() model.Speed {
+	return c.CoreConfig.LoiterSpeed
+}
+
+func (c *Config) TurnTolerance() float64 {
+	return c.CoreConfig.TurnTolerance
+}
+
+func (c *Config) TurnDwell() int64 {
+	return c.CoreConfig.TurnDwell
+}
+
+func (c *Config) TurnLeadTime() int64 {
+	return c.CoreConfig.TurnLeadTime
+}
+
+func (c *Config) TurnTimeout() int64 {
+	return c.CoreConfig.TurnTimeout
+}
+
+func (c *Config) NoGoZones() []*model.Polygon {
+	zones := make([]*model.Polygon, 0, len(c.CoreConfig.NoGoZones))
+	for _, vertices := range c.CoreConfig.NoGoZones {
+		// zones are checked by validate
+		if zone, err := model.NewPolygon(toWaypoints(vertices)); err == nil {
+			zones = append(zones, zone)
+		}
+	}
+	return zones
+}
+
+func (c *Config) NoGoZoneMargin() float64 {
+	return c.CoreConfig.NoGoZoneMargin
+}
+
+// nil if the keep-in area is not configured
+func (c *Config) GeofenceKeepIn() *model.Polygon {
+	if len(c.CoreConfig.GeofenceKeepIn) == 0 {
+		return nil
+	}
+	keepIn, err := model.NewPolygon(toWaypoints(c.CoreConfig.GeofenceKeepIn))
+	if err != nil {
+		return nil
+	}
+	return keepIn
+}
+
+func (c *Config) GeofenceMaxHomeDistance() float64 {
+	return c.CoreConfig.GeofenceMaxHomeDistance
+}
+
+func (c *Config) MaxLegLength() float64 {
+	return c.CoreConfig.MaxLegLength
+}
+
+// polygon vertices are given as [latitude, longitude] pairs
+func toWaypoints(vertices [][2]float64) []*model.Waypoint {
+	waypoints := make([]*model.Waypoint, len(vertices))
+	for i, vertex := range vertices {
+		waypoints[i] = &model.Waypoint{
+			Latitude:  vertex[0],
+			Longitude: vertex[1],
+		}
+	}
+	return waypoints
+}
+
 func (c *Config) NetworkSocketName() string {
 	return c.NetworkConfig.SocketName
 }
 
+func (c *Config) NetworkFraming() string {
+	return c.NetworkConfig.Framing
+}
+
 func (c *Config) PositionSocketName() string {
 	return c.PositionConfig.SocketName
 }
@@ -108,6 +495,26 @@ func (c *Config) PositionPollingInterval() int64 {
 	return c.PositionConfig.PollingInterval
 }
 
+func (c *Config) PositionAttitudeData() bool {
+	return c.PositionConfig.AttitudeData
+}
+
+func (c *Config) PositionCalibrationFile() string {
+	return c.PositionConfig.CalibrationFile
+}
+
+func (c *Config) PositionReconnectInterval() int64 {
+	return c.PositionConfig.ReconnectInterval
+}
+
+func (c *Config) PositionMaxReconnectInterval() int64 {
+	return c.PositionConfig.MaxReconnectInterval
+}
+
+func (c *Config) PositionFraming() string {
+	return c.PositionConfig.Framing
+}
+
 func (c *Config) ShipSocketName() string {
 	return c.ShipConfig.SocketName
 }
@@ -115,3 +522,23 @@ func (c *Config) ShipSocketName() string {
 func (c *Config) ShipPollingInterval() int64 {
 	return c.ShipConfig.PollingInterval
 }
+
+func (c *Config) ShipReconnectInterval() int64 {
+	return c.ShipConfig.ReconnectInterval
+}
+
+func (c *Config) ShipMaxReconnectInterval() int64 {
+	return c.ShipConfig.MaxReconnectInterval
+}
+
+func (c *Config) ShipFraming() string {
+	return c.ShipConfig.Framing
+}
+
+func (c *Config) ShipRequestTimeout() int64 {
+	return c.ShipConfig.RequestTimeout
+}
+
+func (c *Config) ShipCommandRetries() int {
+	return c.ShipConfig.CommandRetries
+}
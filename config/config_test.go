@@ -1,6 +1,13 @@
 package config
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
 
 func TestConfig(t *testing.T) {
 	conf, err := NewConfig("../ship-nav.conf")
@@ -14,31 +21,187 @@ func TestConfig(t *testing.T) {
 	if conf.UpdateBufSize() != 100 {
 		t.Errorf("Expected update buf size to be 100, got %d", conf.UpdateBufSize())
 	}
-	if conf.TurningSpeed() != "fwd30" {
+	if conf.TurningSpeed() != model.Forward(30) {
 		t.Errorf("Expected turning speed to be fwd30, got %s", conf.TurningSpeed())
 	}
-	if conf.TurningSteeringLeft() != "left40" {
+	if conf.TurningSteeringLeft() != model.Left(40) {
 		t.Errorf("Expected turning steering left to be left40, got %s", conf.TurningSteeringLeft())
 	}
-	if conf.TurningSteeringRight() != "right40" {
+	if conf.TurningSteeringRight() != model.Right(40) {
 		t.Errorf("Expected turning steering right to be right40, got %s", conf.TurningSteeringRight())
 	}
-	if conf.ApproachSpeed() != "fwd50" {
+	if conf.ApproachSpeed() != model.Forward(50) {
 		t.Errorf("Expected approach speed to be fwd50, got %s", conf.ApproachSpeed())
 	}
-	if conf.FullSpeed() != "fwd100" {
+	if conf.FullSpeed() != model.Forward(100) {
 		t.Errorf("Expected full speed to be fwd100, got %s", conf.FullSpeed())
 	}
 	if conf.ApproachDistance() != 10.0 {
 		t.Errorf("Expected approach distance to be 10.0, got %f", conf.ApproachDistance())
 	}
+	if conf.DecelerationDistance() != 20.0 {
+		t.Errorf("Expected deceleration distance to be 20.0, got %f", conf.DecelerationDistance())
+	}
 	if conf.DistanceInaccuracy() != 3.0 {
 		t.Errorf("Expected distance inaccuracy to be 3.0, got %f", conf.DistanceInaccuracy())
 	}
+	if conf.HeadingKp() != 1.5 {
+		t.Errorf("Expected heading Kp to be 1.5, got %f", conf.HeadingKp())
+	}
+	if conf.HeadingKi() != 0.1 {
+		t.Errorf("Expected heading Ki to be 0.1, got %f", conf.HeadingKi())
+	}
+	if conf.HeadingKd() != 0.4 {
+		t.Errorf("Expected heading Kd to be 0.4, got %f", conf.HeadingKd())
+	}
+	if conf.HeadingIntegralLimit() != 200.0 {
+		t.Errorf("Expected heading integral limit to be 200.0, got %f", conf.HeadingIntegralLimit())
+	}
+	if conf.HeadingMaxSteering() != 100 {
+		t.Errorf("Expected heading max steering to be 100, got %d", conf.HeadingMaxSteering())
+	}
+	if conf.HeadingSteeringStep() != 10 {
+		t.Errorf("Expected heading steering step to be 10, got %d", conf.HeadingSteeringStep())
+	}
+	if conf.CrossTrackGain() != 2.0 {
+		t.Errorf("Expected cross-track gain to be 2.0, got %f", conf.CrossTrackGain())
+	}
+	if conf.MaxCrossTrackCorrection() != 30.0 {
+		t.Errorf("Expected max cross-track correction to be 30.0, got %f", conf.MaxCrossTrackCorrection())
+	}
+	if conf.MaxCrossTrack() != 15.0 {
+		t.Errorf("Expected max cross-track to be 15.0, got %f", conf.MaxCrossTrack())
+	}
+	if conf.GeodesicModel() != "haversine" {
+		t.Errorf("Expected geodesic model to be haversine, got %s", conf.GeodesicModel())
+	}
+	if conf.EstimatorEnabled() {
+		t.Errorf("Expected estimator to be disabled")
+	}
+	if conf.EstimatorGpsNoise() != 3.0 {
+		t.Errorf("Expected estimator GPS noise to be 3.0, got %f", conf.EstimatorGpsNoise())
+	}
+	if conf.EstimatorSpeedNoise() != 0.2 {
+		t.Errorf("Expected estimator speed noise to be 0.2, got %f", conf.EstimatorSpeedNoise())
+	}
+	if conf.EstimatorHeadingNoise() != 3.0 {
+		t.Errorf("Expected estimator heading noise to be 3.0, got %f", conf.EstimatorHeadingNoise())
+	}
+	if conf.EstimatorAccelNoise() != 0.5 {
+		t.Errorf("Expected estimator acceleration noise to be 0.5, got %f", conf.EstimatorAccelNoise())
+	}
+	if conf.EstimatorYawAccelNoise() != 5.0 {
+		t.Errorf("Expected estimator yaw acceleration noise to be 5.0, got %f", conf.EstimatorYawAccelNoise())
+	}
+	if conf.FixMinSatellites() != 4 {
+		t.Errorf("Expected min fix satellites to be 4, got %d", conf.FixMinSatellites())
+	}
+	if conf.FixMaxHdop() != 5.0 {
+		t.Errorf("Expected max fix HDOP to be 5.0, got %f", conf.FixMaxHdop())
+	}
+	if conf.FixMinType() != 2 {
+		t.Errorf("Expected min fix type to be 2, got %d", conf.FixMinType())
+	}
+	if conf.FixMaxAge() != 2000 {
+		t.Errorf("Expected max fix age to be 2000, got %d", conf.FixMaxAge())
+	}
+	if conf.MaxPositionAge() != 3000 {
+		t.Errorf("Expected max position age to be 3000, got %d", conf.MaxPositionAge())
+	}
+	if conf.MaxBearingAge() != 3000 {
+		t.Errorf("Expected max bearing age to be 3000, got %d", conf.MaxBearingAge())
+	}
+	if conf.MaxShipDataAge() != 3000 {
+		t.Errorf("Expected max ship data age to be 3000, got %d", conf.MaxShipDataAge())
+	}
+	if conf.SpeedAccelRate() != 20.0 {
+		t.Errorf("Expected speed acceleration rate to be 20.0, got %f", conf.SpeedAccelRate())
+	}
+	if conf.SpeedDecelRate() != 25.0 {
+		t.Errorf("Expected speed deceleration rate to be 25.0, got %f", conf.SpeedDecelRate())
+	}
+	if conf.SpeedRampInterval() != 200 {
+		t.Errorf("Expected speed ramp interval to be 200, got %d", conf.SpeedRampInterval())
+	}
+	if conf.SogControlEnabled() {
+		t.Errorf("Expected sog control to be disabled")
+	}
+	if conf.SogKp() != 10.0 {
+		t.Errorf("Expected sog Kp to be 10.0, got %f", conf.SogKp())
+	}
+	if conf.SogKi() != 2.0 {
+		t.Errorf("Expected sog Ki to be 2.0, got %f", conf.SogKi())
+	}
+	if conf.SogIntegralLimit() != 20.0 {
+		t.Errorf("Expected sog integral limit to be 20.0, got %f", conf.SogIntegralLimit())
+	}
+	if conf.SogTargetSpeed() != 4.0 {
+		t.Errorf("Expected sog target speed to be 4.0, got %f", conf.SogTargetSpeed())
+	}
+	if conf.SogApproachSpeed() != 1.5 {
+		t.Errorf("Expected sog approach speed to be 1.5, got %f", conf.SogApproachSpeed())
+	}
+	if conf.SogMinThrottle() != model.Forward(20) {
+		t.Errorf("Expected sog min throttle to be fwd20, got %s", conf.SogMinThrottle())
+	}
+	if conf.SogMaxThrottle() != model.Forward(100) {
+		t.Errorf("Expected sog max throttle to be fwd100, got %s", conf.SogMaxThrottle())
+	}
+	if conf.SogMaxAge() != 2000 {
+		t.Errorf("Expected sog max age to be 2000, got %d", conf.SogMaxAge())
+	}
+	if conf.MissionEnd() != "stop" {
+		t.Errorf("Expected mission end to be stop, got %s", conf.MissionEnd())
+	}
+	if conf.MissionRepeat() != 0 {
+		t.Errorf("Expected mission repeat to be 0, got %d", conf.MissionRepeat())
+	}
+	if conf.ArrivalMode() != "radius" {
+		t.Errorf("Expected arrival mode to be radius, got %s", conf.ArrivalMode())
+	}
+	if conf.ArrivalMaxMiss() != 15.0 {
+		t.Errorf("Expected arrival max miss to be 15.0, got %f", conf.ArrivalMaxMiss())
+	}
+	if conf.LoiterRadius() != 10.0 {
+		t.Errorf("Expected loiter radius to be 10.0, got %f", conf.LoiterRadius())
+	}
+	if conf.LoiterSpeed() != model.Forward(30) {
+		t.Errorf("Expected loiter speed to be fwd30, got %s", conf.LoiterSpeed())
+	}
+	if conf.TurnTolerance() != 3.0 {
+		t.Errorf("Expected turn tolerance to be 3.0, got %f", conf.TurnTolerance())
+	}
+	if conf.TurnDwell() != 1000 {
+		t.Errorf("Expected turn dwell to be 1000, got %d", conf.TurnDwell())
+	}
+	if conf.TurnLeadTime() != 500 {
+		t.Errorf("Expected turn lead time to be 500, got %d", conf.TurnLeadTime())
+	}
+	if conf.TurnTimeout() != 60000 {
+		t.Errorf("Expected turn timeout to be 60000, got %d", conf.TurnTimeout())
+	}
+	if len(conf.NoGoZones()) != 0 {
+		t.Errorf("Expected no no-go zones, got %d", len(conf.NoGoZones()))
+	}
+	if conf.NoGoZoneMargin() != 5.0 {
+		t.Errorf("Expected no-go zone margin to be 5.0, got %f", conf.NoGoZoneMargin())
+	}
+	if conf.GeofenceKeepIn() != nil {
+		t.Errorf("Expected no geofence keep-in area, got %v", conf.GeofenceKeepIn())
+	}
+	if conf.GeofenceMaxHomeDistance() != 0.0 {
+		t.Errorf("Expected geofence max home distance to be 0.0, got %f", conf.GeofenceMaxHomeDistance())
+	}
+	if conf.MaxLegLength() != 0.0 {
+		t.Errorf("Expected max leg length to be 0.0, got %f", conf.MaxLegLength())
+	}
 
 	if conf.NetworkSocketName() != "/tmp/ship-nav.sock" {
 		t.Errorf("Expected network socket name to be /tmp/ship-nav.sock, got %s", conf.NetworkSocketName())
 	}
+	if conf.NetworkFraming() != "compat" {
+		t.Errorf("Expected network framing to be compat, got %s", conf.NetworkFraming())
+	}
 
 	if conf.PositionSocketName() != "/tmp/ship_position.sock" {
 		t.Errorf("Expected position socket name to be /tmp/ship-position.sock, got %s", conf.PositionSocketName())
@@ -46,6 +209,23 @@ func TestConfig(t *testing.T) {
 	if conf.PositionPollingInterval() != 500 {
 		t.Errorf("Expected position polling interval to be 500, got %d", conf.PositionPollingInterval())
 	}
+	if conf.PositionAttitudeData() {
+		t.Error("Expected position attitude data to be disabled")
+	}
+	if conf.PositionCalibrationFile() != "/var/lib/ship-nav/magcal.json" {
+		t.Errorf("Expected position calibration file to be /var/lib/ship-nav/magcal.json, got %s",
+			conf.PositionCalibrationFile())
+	}
+	if conf.PositionFraming() != "compat" {
+		t.Errorf("Expected position framing to be compat, got %s", conf.PositionFraming())
+	}
+	if conf.PositionReconnectInterval() != 500 {
+		t.Errorf("Expected position reconnect interval to be 500, got %d", conf.PositionReconnectInterval())
+	}
+	if conf.PositionMaxReconnectInterval() != 10000 {
+		t.Errorf("Expected position max reconnect interval to be 10000, got %d",
+			conf.PositionMaxReconnectInterval())
+	}
 
 	if conf.ShipSocketName() != "/tmp/scsocket" {
 		t.Errorf("Expected ship socket name to be /tmp/scsocket, got %s", conf.ShipSocketName())
@@ -53,7 +233,92 @@ func TestConfig(t *testing.T) {
 	if conf.ShipPollingInterval() != 500 {
 		t.Errorf("Expected ship polling interval to be 500, got %d", conf.ShipPollingInterval())
 	}
+	if conf.ShipFraming() != "compat" {
+		t.Errorf("Expected ship framing to be compat, got %s", conf.ShipFraming())
+	}
+	if conf.ShipReconnectInterval() != 500 {
+		t.Errorf("Expected ship reconnect interval to be 500, got %d", conf.ShipReconnectInterval())
+	}
+	if conf.ShipMaxReconnectInterval() != 5000 {
+		t.Errorf("Expected ship max reconnect interval to be 5000, got %d", conf.ShipMaxReconnectInterval())
+	}
+	if conf.ShipRequestTimeout() != 1000 {
+		t.Errorf("Expected ship request timeout to be 1000, got %d", conf.ShipRequestTimeout())
+	}
+	if conf.ShipCommandRetries() != 2 {
+		t.Errorf("Expected ship command retries to be 2, got %d", conf.ShipCommandRetries())
+	}
 	if conf.LogLevel != "info" {
 		t.Errorf("Expected logLevel to be info, got %s", conf.LogLevel)
 	}
 }
+
+// optional parameters missing in older config files fall back to the defaults
+func TestConfigWithoutLoiterSpeed(t *testing.T) {
+	data, err := os.ReadFile("../ship-nav.conf")
+	if err != nil {
+		t.Fatalf("Failed to read config file: %s", err.Error())
+	}
+	filename := filepath.Join(t.TempDir(), "ship-nav.conf")
+	err = os.WriteFile(filename, []byte(strings.Replace(string(data), `"loiterSpeed": "fwd30",`, "", 1)), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write config file: %s", err.Error())
+	}
+
+	conf, err := NewConfig(filename)
+	if err != nil {
+		t.Fatalf("Expected config without loiterSpeed to be accepted, got %s", err.Error())
+	}
+	if !conf.LoiterSpeed().IsStop() {
+		t.Errorf("Expected loiter speed not to be set, got %s", conf.LoiterSpeed())
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	data, err := os.ReadFile("../ship-nav.conf")
+	if err != nil {
+		t.Fatalf("Failed to read config file: %s", err.Error())
+	}
+
+	tests := []struct {
+		old string
+		new string
+	}{
+		{`"turningSpeed": "fwd30"`, `"turningSpeed": "fast"`},
+		{`"fullSpeed": "fwd100"`, `"fullSpeed": "fwd120"`},
+		{`"approachSpeed": "fwd50"`, `"approachSpeed": "rev50"`},
+		{`"approachSpeed": "fwd50"`, `"approachSpeed": "stop"`},
+		{`"fullSpeed": "fwd100"`, `"fullSpeed": "fwd40"`},
+		{`"turningSteeringLeft": "left40"`, `"turningSteeringLeft": "right40"`},
+		{`"turningSteeringRight": "right40"`, `"turningSteeringRight": "straight"`},
+		{`"loiterSpeed": "fwd30"`, `"loiterSpeed": "rev30"`},
+		{`"missionEnd": "stop"`, `"missionEnd": "circle"`},
+		{`"missionRepeat": 0`, `"missionRepeat": -1`},
+		{`"arrivalMode": "radius"`, `"arrivalMode": "cross"`},
+		{`"geodesicModel": "haversine"`, `"geodesicModel": "flat"`},
+		{`"headingKp": 1.5`, `"headingKp": 0.0`},
+		{`"turnTolerance": 3.0`, `"turnTolerance": -1.0`},
+		{`"turnTimeout": 60000`, `"turnTimeout": -1`},
+		{`"noGoZones": []`, `"noGoZones": [[[56.30, 44.00], [56.31, 44.00]]]`},
+		{`"noGoZoneMargin": 5.0`, `"noGoZoneMargin": -1.0`},
+		{`"geofenceKeepIn": []`, `"geofenceKeepIn": [[56.30, 44.00], [56.31, 44.00]]`},
+		{`"geofenceMaxHomeDistance": 0.0`, `"geofenceMaxHomeDistance": -100.0`},
+		{`"maxLegLength": 0.0`, `"maxLegLength": -100.0`},
+	}
+
+	for _, test := range tests {
+		if !strings.Contains(string(data), test.old) {
+			t.Fatalf("Expected config file to contain %s", test.old)
+		}
+		filename := filepath.Join(t.TempDir(), "ship-nav.conf")
+		err = os.WriteFile(filename, []byte(strings.Replace(string(data), test.old, test.new, 1)), 0644)
+		if err != nil {
+			t.Fatalf("Failed to write config file: %s", err.Error())
+		}
+
+		_, err = NewConfig(filename)
+		if err == nil {
+			t.Errorf("Expected config with %s to be rejected", test.new)
+		}
+	}
+}
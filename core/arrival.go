@@ -0,0 +1,61 @@
+package core
+
+import (
+	"math"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+// decides that the waypoint is reached, with position noise and the polling interval the ship
+// may pass the waypoint outside of the arrival radius and would circle around it otherwise
+type arrivalDetector struct {
+	mode model.ArrivalMode
+	// line crossing and closest approach are accepted only this close to the waypoint,
+	// zero disables the limit
+	maxMiss     float64
+	minDistance float64
+}
+
+func newArrivalDetector(mode model.ArrivalMode, maxMiss float64) *arrivalDetector {
+	if mode == model.ArrivalModeDefault {
+		mode = model.ArrivalModeRadius
+	}
+
+	detector := &arrivalDetector{
+		mode:    mode,
+		maxMiss: maxMiss,
+	}
+	detector.reset()
+	return detector
+}
+
+// called at the start of every leg
+func (d *arrivalDetector) reset() {
+	d.minDistance = math.Inf(1)
+}
+
+func (d *arrivalDetector) arrived(position *model.Position, legStart *model.Waypoint,
+	waypoint *model.Waypoint, distance float64, radius float64) bool {
+	if distance <= radius {
+		return true
+	}
+
+	switch waypoint.ArrivalModeOr(d.mode) {
+	case model.ArrivalModeLine:
+		return d.withinMiss(distance) && position.PastLineMeters(legStart, waypoint) >= 0
+	case model.ArrivalModeClosestApproach:
+		closest := d.minDistance
+		if distance < closest {
+			d.minDistance = distance
+			return false
+		}
+		// the ship moves away from the closest point of approach, the arrival radius
+		// filters out the position noise
+		return distance > closest+radius && d.withinMiss(closest)
+	}
+	return false
+}
+
+func (d *arrivalDetector) withinMiss(distance float64) bool {
+	return d.maxMiss <= 0 || distance <= d.maxMiss
+}
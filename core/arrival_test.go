@@ -0,0 +1,139 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+var arrivalLegStart = &model.Waypoint{Latitude: 56.33, Longitude: 44.0}
+var arrivalWaypoint = &model.Waypoint{Latitude: 56.34, Longitude: 44.0}
+
+// the ship passes the waypoint about 5 meters to the east
+func arrivalPosition(latitude float64) *model.Position {
+	return &model.Position{Latitude: latitude, Longitude: 44.000081}
+}
+
+func TestArrivalDetectorRadius(t *testing.T) {
+	detector := newArrivalDetector(model.ArrivalModeDefault, 0.0)
+
+	position := arrivalPosition(56.34005)
+	if detector.arrived(position, arrivalLegStart, arrivalWaypoint, position.DistanceMeters(arrivalWaypoint), 0.5) {
+		t.Error("Expected waypoint not to be reached outside of the arrival radius")
+	}
+	if !detector.arrived(position, arrivalLegStart, arrivalWaypoint, 0.4, 0.5) {
+		t.Error("Expected waypoint to be reached inside of the arrival radius")
+	}
+}
+
+func TestArrivalDetectorLine(t *testing.T) {
+	detector := newArrivalDetector(model.ArrivalModeLine, 15.0)
+
+	position := arrivalPosition(56.3399)
+	if detector.arrived(position, arrivalLegStart, arrivalWaypoint, position.DistanceMeters(arrivalWaypoint), 0.5) {
+		t.Error("Expected waypoint not to be reached before the line")
+	}
+
+	// overshoot, the ship never entered the arrival radius
+	position = arrivalPosition(56.34005)
+	if !detector.arrived(position, arrivalLegStart, arrivalWaypoint, position.DistanceMeters(arrivalWaypoint), 0.5) {
+		t.Error("Expected waypoint to be reached after crossing the line")
+	}
+
+	// the line is crossed too far from the waypoint
+	detector = newArrivalDetector(model.ArrivalModeLine, 5.0)
+	if detector.arrived(position, arrivalLegStart, arrivalWaypoint, position.DistanceMeters(arrivalWaypoint), 0.5) {
+		t.Error("Expected waypoint not to be reached outside of the maximum miss distance")
+	}
+}
+
+func TestArrivalDetectorClosestApproach(t *testing.T) {
+	detector := newArrivalDetector(model.ArrivalModeClosestApproach, 15.0)
+
+	tests := []struct {
+		distance float64
+		arrived  bool
+	}{
+		{20.0, false},
+		{10.0, false},
+		{6.0, false},
+		// position noise
+		{6.3, false},
+		{7.0, true},
+	}
+
+	for i, test := range tests {
+		if detector.arrived(nil, arrivalLegStart, arrivalWaypoint, test.distance, 0.5) != test.arrived {
+			t.Errorf("Expected arrival at distance %d to be %t", i, test.arrived)
+		}
+	}
+
+	// the closest approach is too far from the waypoint
+	detector.reset()
+	if detector.arrived(nil, arrivalLegStart, arrivalWaypoint, 20.0, 0.5) ||
+		detector.arrived(nil, arrivalLegStart, arrivalWaypoint, 25.0, 0.5) {
+		t.Error("Expected waypoint not to be reached outside of the maximum miss distance")
+	}
+}
+
+func TestArrivalDetectorWaypointMode(t *testing.T) {
+	detector := newArrivalDetector(model.ArrivalModeRadius, 0.0)
+
+	waypoint := &model.Waypoint{
+		Latitude:  arrivalWaypoint.Latitude,
+		Longitude: arrivalWaypoint.Longitude,
+		Arrival:   model.ArrivalModeLine,
+	}
+	position := arrivalPosition(56.34005)
+	if !detector.arrived(position, arrivalLegStart, waypoint, position.DistanceMeters(waypoint), 0.5) {
+		t.Error("Expected waypoint arrival mode to override the configured mode")
+	}
+}
+
+func TestMovingArrivalOvershoot(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	waypoints := model.NewWaypoints()
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  arrivalWaypoint.Latitude,
+		Longitude: arrivalWaypoint.Longitude,
+	})
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.35,
+		Longitude: 44.0,
+	})
+
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  arrivalLegStart.Latitude,
+			Longitude: arrivalLegStart.Longitude,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		waypoints:     waypoints,
+	}
+
+	shipControl := &mockShipControl{}
+
+	handler := newMovingHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil,
+		newArrivalDetector(model.ArrivalModeLine, 15.0))
+	handler.OnEnter()
+
+	coreData.position = arrivalPosition(56.3399)
+	transition := handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+
+	coreData.position = arrivalPosition(56.34005)
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "waypoint" {
+		t.Errorf("Expected waypoint transition, got %s", transition)
+	}
+	if coreData.waypoints.GetNextWaypoint().Latitude != 56.35 {
+		t.Errorf("Expected next waypoint latitude to be 56.35, got %f",
+			coreData.waypoints.GetNextWaypoint().Latitude)
+	}
+}
@@ -1,10 +1,13 @@
 package core
 
 import (
+	"strings"
 	"time"
 
+	"github.com/moosethebrown/ship-nav/core/estimator"
 	"github.com/moosethebrown/ship-nav/core/fsm"
 	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/moosethebrown/ship-nav/core/planner"
 	"github.com/rs/zerolog"
 )
 
@@ -15,45 +18,164 @@ const (
 type Configurer interface {
 	Declination() float64
 	UpdateBufSize() int
-	TurningSpeed() string
-	TurningSteeringLeft() string
-	TurningSteeringRight() string
-	ApproachSpeed() string
-	FullSpeed() string
+	TurningSpeed() model.Speed
+	TurningSteeringLeft() model.Steering
+	TurningSteeringRight() model.Steering
+	ApproachSpeed() model.Speed
+	FullSpeed() model.Speed
 	ApproachDistance() float64
+	DecelerationDistance() float64
 	DistanceInaccuracy() float64
+	HeadingKp() float64
+	HeadingKi() float64
+	HeadingKd() float64
+	HeadingIntegralLimit() float64
+	HeadingMaxSteering() int
+	HeadingSteeringStep() int
+	CrossTrackGain() float64
+	MaxCrossTrackCorrection() float64
+	MaxCrossTrack() float64
+	GeodesicModel() string
+	EstimatorEnabled() bool
+	EstimatorGpsNoise() float64
+	EstimatorSpeedNoise() float64
+	EstimatorHeadingNoise() float64
+	EstimatorAccelNoise() float64
+	EstimatorYawAccelNoise() float64
+	FixMinSatellites() int
+	FixMaxHdop() float64
+	FixMinType() int
+	FixMaxAge() int64
+	MaxPositionAge() int64
+	MaxBearingAge() int64
+	MaxShipDataAge() int64
+	SpeedAccelRate() float64
+	SpeedDecelRate() float64
+	SpeedRampInterval() int64
+	SogControlEnabled() bool
+	SogKp() float64
+	SogKi() float64
+	SogIntegralLimit() float64
+	SogTargetSpeed() float64
+	SogApproachSpeed() float64
+	SogMinThrottle() model.Speed
+	SogMaxThrottle() model.Speed
+	SogMaxAge() int64
+	MissionEnd() string
+	ArrivalMode() string
+	ArrivalMaxMiss() float64
+	MissionRepeat() int
+	LoiterRadius() float64
+	LoiterSpeed() model.Speed
+	TurnTolerance() float64
+	TurnDwell() int64
+	TurnLeadTime() int64
+	TurnTimeout() int64
+	NoGoZones() []*model.Polygon
+	NoGoZoneMargin() float64
+	GeofenceKeepIn() *model.Polygon
+	GeofenceMaxHomeDistance() float64
+	MaxLegLength() float64
 }
 
 const (
 	waypointCmdSet = iota
 	waypointCmdAdd
 	waypointCmdClear
+	waypointCmdSetMissionEnd
+	waypointCmdSetNoGoZones
 )
 
 type waypointsCmd struct {
-	cmd uint8
-	arg []*model.Waypoint
+	cmd        uint8
+	arg        []*model.Waypoint
+	missionEnd model.MissionEnd
+	zones      []*model.Polygon
+	// problems found in the route are sent back to the caller of set
+	reply chan []*model.MissionProblem
+}
+
+type navCmd struct {
+	start bool
+	// problems found in the route are sent back to the caller of start
+	reply chan []*model.MissionProblem
+}
+
+const (
+	serviceShipControl = iota
+	servicePosition
+)
+
+type connectionState struct {
+	service   uint8
+	connected bool
+}
+
+type shipCommandFailure struct {
+	cmd  string
+	data string
+	err  error
 }
 
 type coreData struct {
 	declination   float64
+	geodesic      model.Geodesic
 	position      *model.Position
+	rawPosition   *model.Position
 	homeWaypoint  *model.Waypoint
 	curBearing    *model.Bearing
+	rawBearing    *model.Bearing
 	targetBearing *model.Bearing
 	shipData      *model.ShipData
 	waypoints     *model.Waypoints
+	missionLog    *missionLog
+	missionEnd    model.MissionEnd
+	repeated      int
+	fixLost       bool
+	sensorStale   bool
+	// a usable position was received, the position is zero until then
+	positionReceived bool
+	// connection state of the services
+	shipControlLost bool
+	positionLost    bool
+	// navigation is heading to the home waypoint
+	homeBound bool
+	// detour waypoints followed by the home waypoint, nil until the route home is planned
+	homeRoute []*model.Waypoint
+	// position is held around the loiter point, the current position is used if it is not set
+	loitering   bool
+	loiterPoint *model.Waypoint
+	// routes around the no-go zones, nil if there are no zones
+	planner  *planner.Planner
+	geofence model.GeofenceStatus
+	// closest distance from home on the way back after the geofence breach, zero until measured
+	breachHomeDistance float64
+	// checks the route before it is set and navigation is started
+	validator *missionValidator
+	// problems found by the validation of the last nav start
+	missionProblems []*model.MissionProblem
 }
 
 type Core struct {
 	data           *coreData
+	missionEnd     model.MissionEnd
+	noGoZoneMargin float64
+	geofence       *geofence
+	estimator      *estimator.Estimator
+	fixChecker     *fixChecker
+	watchdog       *sensorWatchdog
+	speedRamp      *speedRamp
+	rampInterval   time.Duration
 	positionCh     chan *model.Position
 	homeWaypointCh chan *model.Waypoint
 	bearingCh      chan *model.Bearing
 	shipDataCh     chan *model.ShipData
 	waypointsCh    chan *waypointsCmd
-	navCh          chan bool
+	navCh          chan navCmd
 	netLossCh      chan bool
+	holdCh         chan *model.Waypoint
+	connectionCh   chan connectionState
+	cmdFailureCh   chan shipCommandFailure
 	stopCh         chan bool
 	fsm            *fsm.FSM[Event]
 	logger         *zerolog.Logger
@@ -65,13 +187,51 @@ func NewCore(configurer Configurer, shipControl ShipControl, logger *zerolog.Log
 		updateBufSize = defaultUpdateBufSize
 	}
 
+	geodesic, err := model.ParseGeodesic(configurer.GeodesicModel())
+	if err != nil {
+		logger.Error().Err(err).Msgf("Using %s geodesic model", geodesic)
+	}
+
 	coreData := &coreData{
 		declination:   configurer.Declination(),
+		geodesic:      geodesic,
 		position:      &model.Position{},
+		rawPosition:   &model.Position{},
 		curBearing:    model.NewBearing(configurer.Declination()),
+		rawBearing:    model.NewBearing(configurer.Declination()),
 		targetBearing: model.NewBearing(configurer.Declination()),
 		shipData:      &model.ShipData{},
 		waypoints:     model.NewWaypoints(),
+		missionLog:    newMissionLog(defaultMissionLogSize),
+		planner:       planner.NewPlanner(configurer.NoGoZones(), configurer.NoGoZoneMargin()),
+	}
+
+	geofence := newGeofence(configurer.GeofenceKeepIn(), configurer.GeofenceMaxHomeDistance())
+	coreData.geofence.Enabled = geofence != nil
+	coreData.validator = newMissionValidator(configurer.MaxLegLength(), geofence, geodesic)
+
+	missionEndAction, err := model.ParseMissionEndAction(configurer.MissionEnd())
+	if err != nil {
+		logger.Error().Err(err).Msgf("Using %s mission end", missionEndAction)
+	}
+	missionEnd := model.MissionEnd{
+		Action: missionEndAction,
+		Repeat: configurer.MissionRepeat(),
+	}
+	coreData.missionEnd = missionEnd
+
+	fixChecker := newFixChecker(configurer.FixMinSatellites(), configurer.FixMaxHdop(),
+		configurer.FixMinType(), configurer.FixMaxAge())
+	coreData.fixLost = !fixChecker.usable(coreData.position)
+
+	// handlers command the speed through the ramp if it is configured
+	speedRamp := newSpeedRamp(shipControl, configurer.SpeedAccelRate(), configurer.SpeedDecelRate())
+	if speedRamp != nil {
+		shipControl = speedRamp
+	}
+	rampInterval := configurer.SpeedRampInterval()
+	if rampInterval <= 0 {
+		rampInterval = defaultSpeedRampInterval
 	}
 
 	idleLogger := logger.With().Str("state", "idle").Logger()
@@ -80,62 +240,211 @@ func NewCore(configurer Configurer, shipControl ShipControl, logger *zerolog.Log
 	turningHomeLogger := logger.With().Str("state", "turning home").Logger()
 	movingHomeLogger := logger.With().Str("state", "moving home").Logger()
 	stoppingLogger := logger.With().Str("state", "stopping").Logger()
+	holdingLogger := logger.With().Str("state", "holding").Logger()
+	waypointHoldLogger := logger.With().Str("state", "waypoint hold").Logger()
+	loiteringLogger := logger.With().Str("state", "loitering").Logger()
+
+	newHeadingCtrl := func() *headingController {
+		return newHeadingController(configurer.HeadingKp(), configurer.HeadingKi(),
+			configurer.HeadingKd(), configurer.HeadingIntegralLimit(),
+			configurer.HeadingMaxSteering(), configurer.HeadingSteeringStep())
+	}
+
+	newTurnCompletion := func() *turnCompletion {
+		return newTurnCompletion(configurer.TurnTolerance(), configurer.TurnDwell(),
+			configurer.TurnLeadTime(), configurer.TurnTimeout())
+	}
+
+	crossTrack := newCrossTrackCorrector(configurer.CrossTrackGain(),
+		configurer.MaxCrossTrackCorrection(), configurer.MaxCrossTrack())
+	if configurer.HeadingKp() == 0 && configurer.CrossTrackGain() != 0 {
+		logger.Warn().Msg("cross-track correction is not applied without the heading controller")
+	}
+
+	newSogCtrl := func() *sogController {
+		return newSogController(configurer.SogControlEnabled(), configurer.SogKp(),
+			configurer.SogKi(), configurer.SogIntegralLimit(), configurer.SogTargetSpeed(),
+			configurer.SogApproachSpeed(), configurer.SogMinThrottle(), configurer.SogMaxThrottle(),
+			configurer.SogMaxAge())
+	}
+
+	arrivalMode, err := model.ParseArrivalMode(configurer.ArrivalMode())
+	if err != nil {
+		logger.Error().Err(err).Msgf("Using %s arrival mode", model.ArrivalModeRadius)
+	}
+	newArrival := func() *arrivalDetector {
+		return newArrivalDetector(arrivalMode, configurer.ArrivalMaxMiss())
+	}
 
 	idleHandler := newIdleHandler(&idleLogger, coreData)
 	turningHandler := newTurningHandler(&turningLogger, coreData, shipControl,
 		configurer.TurningSpeed(), configurer.TurningSteeringLeft(),
-		configurer.TurningSteeringRight())
+		configurer.TurningSteeringRight(), newHeadingCtrl(), newTurnCompletion())
 	movingHandler := newMovingHandler(&movingLogger, coreData, shipControl, configurer.ApproachSpeed(),
-		configurer.FullSpeed(), configurer.ApproachDistance(), configurer.DistanceInaccuracy())
+		configurer.FullSpeed(), configurer.ApproachDistance(), configurer.DecelerationDistance(),
+		configurer.DistanceInaccuracy(), newHeadingCtrl(), crossTrack, newSogCtrl(), newArrival())
 	turningHomeHandler := newTurningHomeHandler(&turningHomeLogger, coreData, shipControl,
-		configurer.TurningSpeed(), configurer.TurningSteeringLeft(), configurer.TurningSteeringRight())
+		configurer.TurningSpeed(), configurer.TurningSteeringLeft(), configurer.TurningSteeringRight(),
+		newHeadingCtrl(), newTurnCompletion())
 	movingHomeHandler := newMovingHomeHandler(&movingHomeLogger, coreData, shipControl,
 		configurer.ApproachSpeed(), configurer.FullSpeed(), configurer.ApproachDistance(),
-		configurer.DistanceInaccuracy())
+		configurer.DecelerationDistance(), configurer.DistanceInaccuracy(), newHeadingCtrl(), crossTrack,
+		newSogCtrl(), newArrival())
 	stoppingHandler := newStoppingHandler(&stoppingLogger, coreData, shipControl)
+	holdingHandler := newHoldingHandler(&holdingLogger, coreData, shipControl)
+	waypointHoldHandler := newWaypointHoldHandler(&waypointHoldLogger, coreData, shipControl)
+	loiteringHandler := newLoiteringHandler(&loiteringLogger, coreData, shipControl,
+		configurer.TurningSpeed(), configurer.TurningSteeringLeft(), configurer.TurningSteeringRight(),
+		configurer.LoiterSpeed(), configurer.LoiterRadius())
+
+	watchdog := newSensorWatchdog(configurer.MaxPositionAge(), configurer.MaxBearingAge(),
+		configurer.MaxShipDataAge())
+
+	var positionEstimator *estimator.Estimator
+	if configurer.EstimatorEnabled() {
+		positionEstimator = estimator.NewEstimator(configurer.EstimatorGpsNoise(),
+			configurer.EstimatorSpeedNoise(), configurer.EstimatorHeadingNoise(),
+			configurer.EstimatorAccelNoise(), configurer.EstimatorYawAccelNoise())
+	}
 
 	return &Core{
 		data:           coreData,
+		missionEnd:     missionEnd,
+		noGoZoneMargin: configurer.NoGoZoneMargin(),
+		geofence:       geofence,
+		estimator:      positionEstimator,
+		fixChecker:     fixChecker,
+		watchdog:       watchdog,
+		speedRamp:      speedRamp,
+		rampInterval:   time.Duration(rampInterval) * time.Millisecond,
 		positionCh:     make(chan *model.Position, updateBufSize),
 		homeWaypointCh: make(chan *model.Waypoint, updateBufSize),
 		bearingCh:      make(chan *model.Bearing, updateBufSize),
 		shipDataCh:     make(chan *model.ShipData, updateBufSize),
 		waypointsCh:    make(chan *waypointsCmd, updateBufSize),
-		navCh:          make(chan bool, updateBufSize),
+		navCh:          make(chan navCmd, updateBufSize),
 		netLossCh:      make(chan bool, updateBufSize),
+		holdCh:         make(chan *model.Waypoint, updateBufSize),
+		connectionCh:   make(chan connectionState, updateBufSize),
+		cmdFailureCh:   make(chan shipCommandFailure, updateBufSize),
 		stopCh:         make(chan bool, 1),
 		fsm: fsm.NewFSM(map[string]*fsm.State[Event]{
 			"idle": fsm.NewState(idleHandler, map[string]string{
 				"nav start":     "turning",
 				"net loss home": "turning home",
+				"hold":          "holding",
+				"hold position": "loitering",
 			}),
 			"turning": fsm.NewState(turningHandler, map[string]string{
 				"nav stop":          "idle",
 				"bearing adjust":    "moving",
+				"turn timeout":      "stopping",
+				"route blocked":     "stopping",
 				"net loss stop":     "stopping",
 				"waypoints cleared": "stopping",
 				"net loss home":     "turning home",
+				"fix lost":          "holding",
+				"sensor stale":      "holding",
+				"position lost":     "holding",
+				"ship control lost": "stopping",
+				"command failed":    "stopping",
+				"hold position":     "loitering",
+				"geofence home":     "turning home",
+				"geofence stop":     "stopping",
 			}),
 			"moving": fsm.NewState(movingHandler, map[string]string{
 				"nav stop":          "idle",
 				"waypoint":          "turning",
+				"waypoint hold":     "waypoint hold",
+				"mission loiter":    "loitering",
+				"mission home":      "turning home",
 				"waypoints set":     "turning",
+				"zones set":         "turning",
+				"off track":         "turning",
 				"last waypoint":     "stopping",
 				"net loss stop":     "stopping",
 				"waypoints cleared": "stopping",
 				"net loss home":     "turning home",
+				"fix lost":          "holding",
+				"sensor stale":      "holding",
+				"position lost":     "holding",
+				"ship control lost": "stopping",
+				"command failed":    "stopping",
+				"hold position":     "loitering",
+				"geofence home":     "turning home",
+				"geofence stop":     "stopping",
 			}),
 			"turning home": fsm.NewState(turningHomeHandler, map[string]string{
-				"nav stop":       "idle",
-				"bearing adjust": "moving home",
+				"nav stop":          "idle",
+				"bearing adjust":    "moving home",
+				"turn timeout":      "stopping",
+				"route blocked":     "stopping",
+				"fix lost":          "holding",
+				"sensor stale":      "holding",
+				"position lost":     "holding",
+				"ship control lost": "stopping",
+				"command failed":    "stopping",
+				"hold position":     "loitering",
+				"geofence stop":     "stopping",
 			}),
 			"moving home": fsm.NewState(movingHomeHandler, map[string]string{
-				"nav stop":     "idle",
-				"home reached": "stopping",
+				"nav stop":          "idle",
+				"home reached":      "stopping",
+				"detour reached":    "turning home",
+				"zones set":         "turning home",
+				"off track":         "turning home",
+				"fix lost":          "holding",
+				"sensor stale":      "holding",
+				"position lost":     "holding",
+				"ship control lost": "stopping",
+				"command failed":    "stopping",
+				"hold position":     "loitering",
+				"geofence stop":     "stopping",
 			}),
 			"stopping": fsm.NewState(stoppingHandler, map[string]string{
 				"ship stopped": "idle",
 			}),
+			"holding": fsm.NewState(holdingHandler, map[string]string{
+				"resume":            "turning",
+				"resume home":       "turning home",
+				"resume loiter":     "loitering",
+				"nav stop":          "idle",
+				"net loss stop":     "stopping",
+				"waypoints cleared": "stopping",
+				"ship control lost": "stopping",
+				"command failed":    "stopping",
+			}),
+			"waypoint hold": fsm.NewState(waypointHoldHandler, map[string]string{
+				"hold done":         "turning",
+				"last waypoint":     "stopping",
+				"mission loiter":    "loitering",
+				"mission home":      "turning home",
+				"nav stop":          "idle",
+				"waypoints set":     "turning",
+				"waypoints cleared": "stopping",
+				"net loss stop":     "stopping",
+				"net loss home":     "turning home",
+				"fix lost":          "holding",
+				"sensor stale":      "holding",
+				"position lost":     "holding",
+				"ship control lost": "stopping",
+				"command failed":    "stopping",
+				"hold position":     "loitering",
+				"geofence home":     "turning home",
+				"geofence stop":     "stopping",
+			}),
+			"loitering": fsm.NewState(loiteringHandler, map[string]string{
+				"nav stop":          "idle",
+				"nav start":         "turning",
+				"net loss home":     "turning home",
+				"fix lost":          "holding",
+				"sensor stale":      "holding",
+				"position lost":     "holding",
+				"ship control lost": "stopping",
+				"command failed":    "stopping",
+				"geofence home":     "turning home",
+				"geofence stop":     "stopping",
+			}),
 		}, "idle"),
 		logger: logger,
 	}
@@ -159,13 +468,15 @@ func (c *Core) UpdateShipData(shipData *model.ShipData) {
 	}
 }
 
-func (c *Core) SetWaypoints(waypoints []*model.Waypoint) {
-	if len(waypoints) > 0 {
-		c.waypointsCh <- &waypointsCmd{
-			cmd: waypointCmdSet,
-			arg: waypoints,
-		}
+// the route is not set if problems are found in it
+func (c *Core) SetWaypoints(waypoints []*model.Waypoint) []*model.MissionProblem {
+	reply := make(chan []*model.MissionProblem, 1)
+	c.waypointsCh <- &waypointsCmd{
+		cmd:   waypointCmdSet,
+		arg:   waypoints,
+		reply: reply,
 	}
+	return <-reply
 }
 
 func (c *Core) AddWaypoint(waypoint *model.Waypoint) {
@@ -179,6 +490,22 @@ func (c *Core) AddWaypoint(waypoint *model.Waypoint) {
 	}
 }
 
+// mission end action for the current route, the configured action is used for the new routes
+func (c *Core) SetMissionEnd(missionEnd model.MissionEnd) {
+	c.waypointsCh <- &waypointsCmd{
+		cmd:        waypointCmdSetMissionEnd,
+		missionEnd: missionEnd,
+	}
+}
+
+// replaces the no-go zones, the current leg is replanned if it crosses a zone
+func (c *Core) SetNoGoZones(zones []*model.Polygon) {
+	c.waypointsCh <- &waypointsCmd{
+		cmd:   waypointCmdSetNoGoZones,
+		zones: zones,
+	}
+}
+
 func (c *Core) ClearWaypoints() {
 	c.waypointsCh <- &waypointsCmd{
 		cmd: waypointCmdClear,
@@ -186,12 +513,20 @@ func (c *Core) ClearWaypoints() {
 	}
 }
 
-func (c *Core) StartNavigation() {
-	c.navCh <- true
+// navigation is not started if problems are found in the remaining route
+func (c *Core) StartNavigation() []*model.MissionProblem {
+	reply := make(chan []*model.MissionProblem, 1)
+	c.navCh <- navCmd{
+		start: true,
+		reply: reply,
+	}
+	return <-reply
 }
 
 func (c *Core) StopNavigation() {
-	c.navCh <- false
+	c.navCh <- navCmd{
+		start: false,
+	}
 }
 
 func (c *Core) SetHomeWaypoint(homeWaypoint *model.Waypoint) {
@@ -202,40 +537,95 @@ func (c *Core) NetworkLost() {
 	c.netLossCh <- true
 }
 
+// hold position around the waypoint, nil waypoint holds the current position
+func (c *Core) HoldPosition(waypoint *model.Waypoint) {
+	c.holdCh <- waypoint
+}
+
+func (c *Core) UpdateShipConnection(connected bool) {
+	c.connectionCh <- connectionState{
+		service:   serviceShipControl,
+		connected: connected,
+	}
+}
+
+func (c *Core) UpdatePositionConnection(connected bool) {
+	c.connectionCh <- connectionState{
+		service:   servicePosition,
+		connected: connected,
+	}
+}
+
+func (c *Core) ShipCommandFailed(cmd string, data string, err error) {
+	c.cmdFailureCh <- shipCommandFailure{
+		cmd:  cmd,
+		data: data,
+		err:  err,
+	}
+}
+
 func (c *Core) Run() {
 	ticker := time.NewTicker(time.Duration(3 * time.Second))
 	defer ticker.Stop()
 
+	var watchdogCh <-chan time.Time
+	if c.watchdog.enabled() {
+		c.watchdog.start(time.Now())
+		watchdogTicker := time.NewTicker(watchdogInterval)
+		defer watchdogTicker.Stop()
+		watchdogCh = watchdogTicker.C
+	}
+
+	var rampCh <-chan time.Time
+	if c.speedRamp != nil {
+		rampTicker := time.NewTicker(c.rampInterval)
+		defer rampTicker.Stop()
+		rampCh = rampTicker.C
+	}
+
 core_loop:
 	for {
 		evt := Event(eventUndefined)
 		select {
 		case <-ticker.C:
 			c.logger.Info().Msgf("current state = %s", c.fsm.CurrentState())
+		case now := <-watchdogCh:
+			evt = c.checkSensors(now)
+		case now := <-rampCh:
+			c.speedRamp.step(now)
 		case newPosition := <-c.positionCh:
-			c.data.position = newPosition
-			evt = eventPositionUpdate
+			evt = c.updatePosition(newPosition)
 		case newHomeWaypoint := <-c.homeWaypointCh:
 			c.data.homeWaypoint = newHomeWaypoint
+			c.data.homeRoute = nil
 			evt = eventHomeWaypointUpdate
 		case newBearing := <-c.bearingCh:
-			c.data.curBearing = newBearing
+			c.updateBearing(newBearing)
 			evt = eventBearingUpdate
 		case newShipData := <-c.shipDataCh:
 			c.data.shipData = newShipData
+			c.watchdog.shipDataUpdated(newShipData.Timestamp)
 			evt = eventShipDataUpdate
 		case waypointCmd := <-c.waypointsCh:
 			evt = c.handleWaypointsCmd(waypointCmd)
-		case startNav := <-c.navCh:
-			if startNav {
-				evt = eventNavStart
+		case cmd := <-c.navCh:
+			if cmd.start {
+				c.startNavigation(cmd.reply)
 			} else {
 				evt = eventNavStop
 			}
+		case loiterPoint := <-c.holdCh:
+			c.data.loiterPoint = loiterPoint
+			evt = eventHoldPosition
 		case netLoss := <-c.netLossCh:
 			if netLoss {
 				evt = eventNetLoss
 			}
+		case state := <-c.connectionCh:
+			evt = c.updateConnection(state)
+		case failure := <-c.cmdFailureCh:
+			c.logger.Error().Err(failure.err).Msgf("ship command %s %s failed", failure.cmd, failure.data)
+			evt = eventShipCommandFailed
 		case <-c.stopCh:
 			break core_loop
 		}
@@ -257,6 +647,17 @@ func (c *Core) GetPositionData() (*model.Bearing, *model.Position) {
 	return &bearing, &position
 }
 
+// sensor data as reported by the position adapter, before filtering
+func (c *Core) GetRawPositionData() (*model.Bearing, *model.Position) {
+	var bearing model.Bearing
+	bearing = *c.data.rawBearing
+
+	var position model.Position
+	position = *c.data.rawPosition
+
+	return &bearing, &position
+}
+
 func (c *Core) GetShipData() *model.ShipData {
 	var shipData model.ShipData
 	shipData = *c.data.shipData
@@ -264,17 +665,168 @@ func (c *Core) GetShipData() *model.ShipData {
 }
 
 func (c *Core) GetWaypoints() []*model.Waypoint {
-	waypoints := make([]*model.Waypoint, 0)
-	for waypoint := c.data.waypoints.GetNextWaypoint(); waypoint != nil; waypoint = c.data.waypoints.GetNextWaypoint() {
-		waypoints = append(waypoints, waypoint)
+	return c.data.waypoints.GetRemainingWaypoints()
+}
+
+func (c *Core) GetGeofenceStatus() *model.GeofenceStatus {
+	status := c.data.geofence
+	return &status
+}
+
+func (c *Core) GetMissionEvents() []*model.MissionEvent {
+	return c.data.missionLog.snapshot()
+}
+
+// raw sensor data is kept as is, navigation uses the estimated values if the estimator is enabled,
+// positions with unusable fix are not used for navigation
+func (c *Core) updatePosition(position *model.Position) Event {
+	c.data.rawPosition = position
+	c.watchdog.positionUpdated(position.Timestamp)
+	if !c.fixChecker.usable(position) {
+		if !c.data.fixLost {
+			c.logger.Warn().Msgf("position fix lost: satellites = %d, hdop = %f, fix type = %d, fix age = %s",
+				position.NumSatellites, position.Hdop, position.FixType, position.FixAge)
+			c.data.fixLost = true
+			return eventFixLost
+		}
+		return eventUndefined
 	}
-	return waypoints
+
+	if c.estimator == nil {
+		c.data.position = position
+	} else {
+		c.estimator.UpdatePosition(position, sampleTime(position.Timestamp))
+		c.data.position = c.estimator.Position(position)
+	}
+	c.data.positionReceived = true
+	c.checkGeofence()
+
+	if c.data.fixLost {
+		c.logger.Info().Msg("position fix restored")
+		c.data.fixLost = false
+		return eventFixRestored
+	}
+	return eventPositionUpdate
+}
+
+func (c *Core) updateBearing(bearing *model.Bearing) {
+	c.data.rawBearing = bearing
+	c.watchdog.bearingUpdated(bearing.Timestamp)
+	if c.estimator == nil {
+		c.data.curBearing = bearing
+		return
+	}
+	c.estimator.UpdateHeading(bearing.AngleDeg(), sampleTime(bearing.Timestamp))
+	c.data.curBearing = c.estimator.Bearing()
+	c.data.curBearing.Timestamp = bearing.Timestamp
+}
+
+func sampleTime(timestamp time.Time) time.Time {
+	if timestamp.IsZero() {
+		return time.Now()
+	}
+	return timestamp
+}
+
+func (c *Core) updateConnection(state connectionState) Event {
+	switch state.service {
+	case serviceShipControl:
+		if c.data.shipControlLost == !state.connected {
+			return eventUndefined
+		}
+		c.data.shipControlLost = !state.connected
+		if state.connected {
+			c.logger.Info().Msg("ship control connected")
+			return eventShipControlRestored
+		}
+		c.logger.Warn().Msg("ship control connection lost")
+		return eventShipControlLost
+	case servicePosition:
+		if c.data.positionLost == !state.connected {
+			return eventUndefined
+		}
+		c.data.positionLost = !state.connected
+		if state.connected {
+			c.logger.Info().Msg("position service connected")
+			return eventPositionRestored
+		}
+		c.logger.Warn().Msg("position service connection lost")
+		return eventPositionLost
+	}
+
+	return eventUndefined
+}
+
+func (c *Core) checkSensors(now time.Time) Event {
+	stale := c.watchdog.stale(now)
+	if len(stale) > 0 && !c.data.sensorStale {
+		c.logger.Warn().Msgf("stale sensor data: %s", strings.Join(stale, ", "))
+		c.data.sensorStale = true
+		return eventSensorStale
+	}
+	if len(stale) == 0 && c.data.sensorStale {
+		c.logger.Info().Msg("sensor data recovered")
+		c.data.sensorStale = false
+		return eventSensorRecovered
+	}
+	return eventUndefined
+}
+
+// updates the geofence status reported to the clients, the state handlers act on the breach
+func (c *Core) checkGeofence() {
+	if c.geofence == nil {
+		return
+	}
+
+	reason := c.geofence.check(c.data)
+	if reason != "" && !c.data.geofence.Breached {
+		c.logger.Warn().Msgf("geofence breach, %s", reason)
+		c.data.geofence.Timestamp = time.Now()
+	} else if reason == "" && c.data.geofence.Breached {
+		c.logger.Info().Msg("ship is back inside of the geofence")
+	}
+	c.data.geofence.Breached = reason != ""
+	c.data.geofence.Reason = reason
+	if !c.data.geofence.Breached {
+		c.data.breachHomeDistance = 0
+	}
+}
+
+// navigation can not continue until the position data is available
+func (d *coreData) navigationPaused() bool {
+	return d.fixLost || d.sensorStale || d.positionLost
+}
+
+// distance from the current position to the waypoint using the configured geodesic model
+func (d *coreData) distanceMeters(waypoint *model.Waypoint) float64 {
+	return d.position.DistanceMetersWith(d.geodesic, waypoint)
+}
+
+// shortest turn from the current bearing to the target bearing in degrees,
+// positive when the target is to the right
+func (d *coreData) headingErrorDeg() float64 {
+	return model.AngleDiff(d.targetBearing.Heading(), d.curBearing.Heading()).Deg()
+}
+
+// the route is validated by the state handlers which can start navigation
+func (c *Core) startNavigation(reply chan []*model.MissionProblem) {
+	c.data.missionProblems = nil
+	c.fsm.HandleEvent(eventNavStart)
+	reply <- c.data.missionProblems
 }
 
 func (c *Core) handleWaypointsCmd(cmd *waypointsCmd) Event {
 	switch cmd.cmd {
 	case waypointCmdSet:
+		problems := c.data.validator.validate(nil, cmd.arg, c.data.homeWaypoint)
+		cmd.reply <- problems
+		if len(problems) > 0 {
+			c.logger.Warn().Msgf("waypoints rejected, %d problems found", len(problems))
+			return eventUndefined
+		}
 		c.data.waypoints.SetWaypoints(cmd.arg)
+		c.data.missionEnd = c.missionEnd
+		c.data.repeated = 0
 		return eventWaypointsSet
 	case waypointCmdAdd:
 		if len(cmd.arg) > 0 {
@@ -283,7 +835,16 @@ func (c *Core) handleWaypointsCmd(cmd *waypointsCmd) Event {
 		}
 	case waypointCmdClear:
 		c.data.waypoints = model.NewWaypoints()
+		c.data.repeated = 0
 		return eventWaypointsCleared
+	case waypointCmdSetMissionEnd:
+		c.logger.Info().Msgf("mission end %s, repeat %d", cmd.missionEnd.Action, cmd.missionEnd.Repeat)
+		c.data.missionEnd = cmd.missionEnd
+		c.data.repeated = 0
+	case waypointCmdSetNoGoZones:
+		c.logger.Info().Msgf("%d no-go zones set", len(cmd.zones))
+		c.data.planner = planner.NewPlanner(cmd.zones, c.noGoZoneMargin)
+		return eventNoGoZonesSet
 	}
 
 	return eventUndefined
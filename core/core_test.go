@@ -1,6 +1,7 @@
 package core
 
 import (
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -9,7 +10,14 @@ import (
 	"github.com/rs/zerolog"
 )
 
-type mockCoreConfigurer struct{}
+type mockCoreConfigurer struct {
+	fixMinSatellites        int
+	maxBearingAge           int64
+	missionEnd              string
+	geofenceKeepIn          *model.Polygon
+	geofenceMaxHomeDistance float64
+	maxLegLength            float64
+}
 
 func (m *mockCoreConfigurer) Declination() float64 {
 	return 0.0
@@ -19,34 +27,238 @@ func (m *mockCoreConfigurer) UpdateBufSize() int {
 	return 100
 }
 
-func (m *mockCoreConfigurer) TurningSpeed() string {
-	return "fwd40"
+func (m *mockCoreConfigurer) TurningSpeed() model.Speed {
+	return model.Forward(40)
 }
 
-func (m *mockCoreConfigurer) TurningSteeringLeft() string {
-	return "left50"
+func (m *mockCoreConfigurer) TurningSteeringLeft() model.Steering {
+	return model.Left(50)
 }
 
-func (m *mockCoreConfigurer) TurningSteeringRight() string {
-	return "right40"
+func (m *mockCoreConfigurer) TurningSteeringRight() model.Steering {
+	return model.Right(40)
 }
 
-func (m *mockCoreConfigurer) ApproachSpeed() string {
-	return "fwd30"
+func (m *mockCoreConfigurer) ApproachSpeed() model.Speed {
+	return model.Forward(30)
 }
 
-func (m *mockCoreConfigurer) FullSpeed() string {
-	return "fwd100"
+func (m *mockCoreConfigurer) FullSpeed() model.Speed {
+	return model.Forward(100)
 }
 
 func (m *mockCoreConfigurer) ApproachDistance() float64 {
 	return 5.0
 }
 
+func (m *mockCoreConfigurer) DecelerationDistance() float64 {
+	return 0.0
+}
+
 func (m *mockCoreConfigurer) DistanceInaccuracy() float64 {
 	return 0.1
 }
 
+func (m *mockCoreConfigurer) HeadingKp() float64 {
+	return 0.0
+}
+
+func (m *mockCoreConfigurer) HeadingKi() float64 {
+	return 0.0
+}
+
+func (m *mockCoreConfigurer) HeadingKd() float64 {
+	return 0.0
+}
+
+func (m *mockCoreConfigurer) HeadingIntegralLimit() float64 {
+	return 0.0
+}
+
+func (m *mockCoreConfigurer) HeadingMaxSteering() int {
+	return 0
+}
+
+func (m *mockCoreConfigurer) HeadingSteeringStep() int {
+	return 0
+}
+
+func (m *mockCoreConfigurer) CrossTrackGain() float64 {
+	return 0.0
+}
+
+func (m *mockCoreConfigurer) MaxCrossTrackCorrection() float64 {
+	return 0.0
+}
+
+func (m *mockCoreConfigurer) MaxCrossTrack() float64 {
+	return 0.0
+}
+
+func (m *mockCoreConfigurer) GeodesicModel() string {
+	return "vincenty"
+}
+
+func (m *mockCoreConfigurer) EstimatorEnabled() bool {
+	return false
+}
+
+func (m *mockCoreConfigurer) EstimatorGpsNoise() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) EstimatorSpeedNoise() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) EstimatorHeadingNoise() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) EstimatorAccelNoise() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) EstimatorYawAccelNoise() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) FixMinSatellites() int {
+	return m.fixMinSatellites
+}
+
+func (m *mockCoreConfigurer) FixMaxHdop() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) FixMinType() int {
+	return 0
+}
+
+func (m *mockCoreConfigurer) FixMaxAge() int64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) MaxPositionAge() int64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) MaxBearingAge() int64 {
+	return m.maxBearingAge
+}
+
+func (m *mockCoreConfigurer) SpeedAccelRate() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) SpeedDecelRate() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) SpeedRampInterval() int64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) SogControlEnabled() bool {
+	return false
+}
+
+func (m *mockCoreConfigurer) SogKp() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) SogKi() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) SogIntegralLimit() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) SogTargetSpeed() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) SogApproachSpeed() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) SogMinThrottle() model.Speed {
+	return model.SpeedStop
+}
+
+func (m *mockCoreConfigurer) SogMaxThrottle() model.Speed {
+	return model.SpeedStop
+}
+
+func (m *mockCoreConfigurer) SogMaxAge() int64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) MissionEnd() string {
+	return m.missionEnd
+}
+
+func (m *mockCoreConfigurer) MissionRepeat() int {
+	return 0
+}
+
+func (m *mockCoreConfigurer) ArrivalMode() string {
+	return ""
+}
+
+func (m *mockCoreConfigurer) ArrivalMaxMiss() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) LoiterRadius() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) LoiterSpeed() model.Speed {
+	return model.Forward(30)
+}
+
+func (m *mockCoreConfigurer) TurnTolerance() float64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) TurnDwell() int64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) TurnLeadTime() int64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) TurnTimeout() int64 {
+	return 0
+}
+
+func (m *mockCoreConfigurer) NoGoZones() []*model.Polygon {
+	return nil
+}
+
+func (m *mockCoreConfigurer) NoGoZoneMargin() float64 {
+	return 5.0
+}
+
+func (m *mockCoreConfigurer) GeofenceKeepIn() *model.Polygon {
+	return m.geofenceKeepIn
+}
+
+func (m *mockCoreConfigurer) GeofenceMaxHomeDistance() float64 {
+	return m.geofenceMaxHomeDistance
+}
+
+func (m *mockCoreConfigurer) MaxLegLength() float64 {
+	return m.maxLegLength
+}
+
+func (m *mockCoreConfigurer) MaxShipDataAge() int64 {
+	return 0
+}
+
 func TestCore(t *testing.T) {
 	mockShipControl := &mockShipControl{}
 	mockCoreConfigurer := &mockCoreConfigurer{}
@@ -62,16 +274,18 @@ func TestCore(t *testing.T) {
 	}
 	core.UpdatePosition(position)
 
-	waypoint := &model.Waypoint{
-		Latitude:  56.402099,
-		Longitude: 43.859839,
-	}
-	core.AddWaypoint(waypoint)
-	waypoint = &model.Waypoint{
-		Latitude:  56.376828,
-		Longitude: 43.876562,
-	}
-	core.AddWaypoint(waypoint)
+	waypoints := []*model.Waypoint{
+		{
+			Latitude:  56.402099,
+			Longitude: 43.859839,
+		},
+		{
+			Latitude:  56.376828,
+			Longitude: 43.876562,
+		},
+	}
+	core.AddWaypoint(waypoints[0])
+	core.AddWaypoint(waypoints[1])
 	homeWaypoint := &model.Waypoint{
 		Latitude:  56.412695,
 		Longitude: 43.843618,
@@ -107,7 +321,7 @@ func TestCore(t *testing.T) {
 
 	// moving to the first waypoint
 	bearing = model.NewBearing(0.0)
-	bearing.SetFloat((56.402099 - 56.412695), (43.859839 - 43.843618))
+	bearing.SetAngleDeg(model.InitialBearing(position.Waypoint(), waypoints[0]))
 	core.UpdateBearing(bearing)
 	time.Sleep(10 * time.Millisecond)
 
@@ -175,14 +389,15 @@ func TestCore(t *testing.T) {
 	if mockShipControl.speed != "fwd40" {
 		t.Errorf("Expected speed to be fwd40, got %s", mockShipControl.speed)
 	}
-	if mockShipControl.steering != "right40" {
-		t.Errorf("Expected steering to be right40, got %s",
+	// home is slightly less than 180 degrees to the left of the current bearing
+	if mockShipControl.steering != "left50" {
+		t.Errorf("Expected steering to be left50, got %s",
 			mockShipControl.steering)
 	}
 
 	// complete turn to the home waypoint, start moving home
 	bearing = model.NewBearing(0.0)
-	bearing.SetFloat((56.412695 - 56.402099), (43.843618 - 43.859839))
+	bearing.SetAngleDeg(model.InitialBearing(position.Waypoint(), homeWaypoint))
 	core.UpdateBearing(bearing)
 	time.Sleep(10 * time.Millisecond)
 
@@ -240,8 +455,8 @@ func TestCore(t *testing.T) {
 
 	// move to idle state when the ship is stopped
 	shipData := &model.ShipData{
-		Speed:    "stop",
-		Steering: "straight",
+		Speed:    model.SpeedStop,
+		Steering: model.SteeringStraight,
 	}
 	core.UpdateShipData(shipData)
 	time.Sleep(10 * time.Millisecond)
@@ -257,3 +472,364 @@ func TestCore(t *testing.T) {
 			mockShipControl.steering)
 	}
 }
+
+func TestCoreFixLoss(t *testing.T) {
+	mockShipControl := &mockShipControl{}
+	mockCoreConfigurer := &mockCoreConfigurer{
+		fixMinSatellites: 4,
+	}
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+
+	core := NewCore(mockCoreConfigurer, mockShipControl, &logger)
+	go core.Run()
+	defer core.Stop()
+
+	core.AddWaypoint(&model.Waypoint{
+		Latitude:  56.402099,
+		Longitude: 43.859839,
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	// no fix yet, navigation is on hold
+	core.StartNavigation()
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "holding" {
+		t.Errorf("Expected core state to be holding, got %s",
+			core.fsm.CurrentState())
+	}
+	if mockShipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", mockShipControl.speed)
+	}
+
+	// fix acquired
+	position := &model.Position{
+		NumSatellites: 7,
+		Latitude:      56.412695,
+		Longitude:     43.843618,
+	}
+	core.UpdatePosition(position)
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "turning" {
+		t.Errorf("Expected core state to be turning, got %s",
+			core.fsm.CurrentState())
+	}
+	if mockShipControl.speed != "fwd40" {
+		t.Errorf("Expected speed to be fwd40, got %s", mockShipControl.speed)
+	}
+
+	// fix lost, navigation is paused and the bad position is not used
+	core.UpdatePosition(&model.Position{
+		NumSatellites: 2,
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "holding" {
+		t.Errorf("Expected core state to be holding, got %s",
+			core.fsm.CurrentState())
+	}
+	if mockShipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", mockShipControl.speed)
+	}
+	_, current := core.GetPositionData()
+	if current.Latitude != position.Latitude || current.Longitude != position.Longitude {
+		t.Errorf("Expected position to remain %f, %f, got %f, %f", position.Latitude,
+			position.Longitude, current.Latitude, current.Longitude)
+	}
+	_, raw := core.GetRawPositionData()
+	if raw.NumSatellites != 2 {
+		t.Errorf("Expected raw position with 2 satellites, got %d", raw.NumSatellites)
+	}
+
+	// fix restored, navigation resumes
+	core.UpdatePosition(position)
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "turning" {
+		t.Errorf("Expected core state to be turning, got %s",
+			core.fsm.CurrentState())
+	}
+}
+
+func TestCoreSensorStale(t *testing.T) {
+	mockShipControl := &mockShipControl{}
+	mockCoreConfigurer := &mockCoreConfigurer{
+		maxBearingAge: 200,
+	}
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+
+	core := NewCore(mockCoreConfigurer, mockShipControl, &logger)
+	go core.Run()
+	defer core.Stop()
+
+	core.UpdatePosition(&model.Position{
+		Latitude:  56.412695,
+		Longitude: 43.843618,
+	})
+	core.AddWaypoint(&model.Waypoint{
+		Latitude:  56.402099,
+		Longitude: 43.859839,
+	})
+	time.Sleep(10 * time.Millisecond)
+	core.StartNavigation()
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "turning" {
+		t.Errorf("Expected core state to be turning, got %s",
+			core.fsm.CurrentState())
+	}
+
+	// no bearing updates, ship is stopped
+	time.Sleep(400 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "holding" {
+		t.Errorf("Expected core state to be holding, got %s",
+			core.fsm.CurrentState())
+	}
+	if mockShipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", mockShipControl.speed)
+	}
+
+	// bearing updates resumed
+	bearing := model.NewBearing(0.0)
+	bearing.Timestamp = time.Now()
+	core.UpdateBearing(bearing)
+	time.Sleep(150 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "turning" {
+		t.Errorf("Expected core state to be turning, got %s",
+			core.fsm.CurrentState())
+	}
+	if mockShipControl.speed != "fwd40" {
+		t.Errorf("Expected speed to be fwd40, got %s", mockShipControl.speed)
+	}
+}
+
+func TestCoreConnectionLoss(t *testing.T) {
+	mockShipControl := &mockShipControl{}
+	mockCoreConfigurer := &mockCoreConfigurer{}
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+
+	core := NewCore(mockCoreConfigurer, mockShipControl, &logger)
+	go core.Run()
+	defer core.Stop()
+
+	core.UpdatePosition(&model.Position{
+		Latitude:  56.412695,
+		Longitude: 43.843618,
+	})
+	core.AddWaypoint(&model.Waypoint{
+		Latitude:  56.402099,
+		Longitude: 43.859839,
+	})
+	time.Sleep(10 * time.Millisecond)
+	core.StartNavigation()
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "turning" {
+		t.Errorf("Expected core state to be turning, got %s",
+			core.fsm.CurrentState())
+	}
+
+	// position service restarts
+	core.UpdatePositionConnection(false)
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "holding" {
+		t.Errorf("Expected core state to be holding, got %s",
+			core.fsm.CurrentState())
+	}
+
+	core.UpdatePositionConnection(true)
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "turning" {
+		t.Errorf("Expected core state to be turning, got %s",
+			core.fsm.CurrentState())
+	}
+
+	// ship control is unreachable
+	core.UpdateShipConnection(false)
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "stopping" {
+		t.Errorf("Expected core state to be stopping, got %s",
+			core.fsm.CurrentState())
+	}
+
+	// stop command is repeated after reconnection
+	mockShipControl.speed = ""
+	core.UpdateShipConnection(true)
+	time.Sleep(10 * time.Millisecond)
+
+	if mockShipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", mockShipControl.speed)
+	}
+
+	core.UpdateShipData(&model.ShipData{
+		Speed:    model.SpeedStop,
+		Steering: model.SteeringStraight,
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "idle" {
+		t.Errorf("Expected core state to be idle, got %s",
+			core.fsm.CurrentState())
+	}
+
+	// navigation can not start without ship control
+	core.UpdateShipConnection(false)
+	time.Sleep(10 * time.Millisecond)
+	core.StartNavigation()
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "idle" {
+		t.Errorf("Expected core state to be idle, got %s",
+			core.fsm.CurrentState())
+	}
+}
+
+func TestCoreShipCommandFailed(t *testing.T) {
+	mockShipControl := &mockShipControl{}
+	mockCoreConfigurer := &mockCoreConfigurer{}
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+
+	core := NewCore(mockCoreConfigurer, mockShipControl, &logger)
+	go core.Run()
+	defer core.Stop()
+
+	core.UpdatePosition(&model.Position{
+		Latitude:  56.412695,
+		Longitude: 43.843618,
+	})
+	core.AddWaypoint(&model.Waypoint{
+		Latitude:  56.402099,
+		Longitude: 43.859839,
+	})
+	time.Sleep(10 * time.Millisecond)
+	core.StartNavigation()
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "turning" {
+		t.Errorf("Expected core state to be turning, got %s",
+			core.fsm.CurrentState())
+	}
+
+	core.ShipCommandFailed("set_steering", "right40", errors.New("no response after 3 attempts"))
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "stopping" {
+		t.Errorf("Expected core state to be stopping, got %s",
+			core.fsm.CurrentState())
+	}
+	if mockShipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", mockShipControl.speed)
+	}
+
+	core.UpdateShipData(&model.ShipData{
+		Speed:    model.SpeedStop,
+		Steering: model.SteeringStraight,
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "idle" {
+		t.Errorf("Expected core state to be idle, got %s",
+			core.fsm.CurrentState())
+	}
+}
+
+func TestCoreHoldPosition(t *testing.T) {
+	mockShipControl := &mockShipControl{}
+	mockCoreConfigurer := &mockCoreConfigurer{}
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+
+	core := NewCore(mockCoreConfigurer, mockShipControl, &logger)
+	go core.Run()
+	defer core.Stop()
+
+	core.UpdatePosition(&model.Position{
+		Latitude:  56.412695,
+		Longitude: 43.843618,
+	})
+	core.AddWaypoint(&model.Waypoint{
+		Latitude:  56.402099,
+		Longitude: 43.859839,
+	})
+	time.Sleep(10 * time.Millisecond)
+	core.StartNavigation()
+	time.Sleep(10 * time.Millisecond)
+
+	core.HoldPosition(nil)
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "loitering" {
+		t.Errorf("Expected core state to be loitering, got %s",
+			core.fsm.CurrentState())
+	}
+	if mockShipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", mockShipControl.speed)
+	}
+
+	// drifting away from the loiter point
+	core.UpdatePosition(&model.Position{
+		Latitude:  56.4130,
+		Longitude: 43.843618,
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	if mockShipControl.speed == "stop" {
+		t.Errorf("Expected the ship to return to the loiter point")
+	}
+
+	// mission is resumed with nav start
+	core.StartNavigation()
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "turning" {
+		t.Errorf("Expected core state to be turning, got %s",
+			core.fsm.CurrentState())
+	}
+
+	core.StopNavigation()
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "idle" {
+		t.Errorf("Expected core state to be idle, got %s",
+			core.fsm.CurrentState())
+	}
+}
+
+func TestCoreMissionEnd(t *testing.T) {
+	mockCoreConfigurer := &mockCoreConfigurer{
+		missionEnd: "loiter",
+	}
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+
+	core := NewCore(mockCoreConfigurer, &mockShipControl{}, &logger)
+	go core.Run()
+	defer core.Stop()
+
+	waypoints := []*model.Waypoint{
+		{
+			Latitude:  56.402099,
+			Longitude: 43.859839,
+		},
+	}
+	core.SetWaypoints(waypoints)
+	core.SetMissionEnd(model.MissionEnd{Action: model.MissionEndLoop, Repeat: 2})
+	time.Sleep(10 * time.Millisecond)
+
+	if core.data.missionEnd.Action != model.MissionEndLoop || core.data.missionEnd.Repeat != 2 {
+		t.Errorf("Expected mission end loop 2 times, got %s %d", core.data.missionEnd.Action,
+			core.data.missionEnd.Repeat)
+	}
+
+	// new route uses the configured mission end
+	core.SetWaypoints(waypoints)
+	time.Sleep(10 * time.Millisecond)
+
+	if core.data.missionEnd.Action != model.MissionEndLoiter {
+		t.Errorf("Expected mission end loiter, got %s", core.data.missionEnd.Action)
+	}
+}
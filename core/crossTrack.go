@@ -0,0 +1,39 @@
+package core
+
+import "math"
+
+// steers the ship back to the line between the previous and the next waypoint
+type crossTrackCorrector struct {
+	gain          float64
+	maxCorrection float64
+	maxCrossTrack float64
+}
+
+func newCrossTrackCorrector(gain float64, maxCorrection float64, maxCrossTrack float64) *crossTrackCorrector {
+	return &crossTrackCorrector{
+		gain:          gain,
+		maxCorrection: maxCorrection,
+		maxCrossTrack: maxCrossTrack,
+	}
+}
+
+// heading correction in degrees for the given cross-track distance,
+// the distance is positive when the ship is to the right of the leg
+func (c *crossTrackCorrector) correction(crossTrack float64) float64 {
+	if c == nil {
+		return 0
+	}
+
+	correction := -c.gain * crossTrack
+	if c.maxCorrection > 0 {
+		correction = math.Max(-c.maxCorrection, math.Min(c.maxCorrection, correction))
+	}
+	return correction
+}
+
+func (c *crossTrackCorrector) exceeded(crossTrack float64) bool {
+	if c == nil || c.maxCrossTrack <= 0 {
+		return false
+	}
+	return math.Abs(crossTrack) > c.maxCrossTrack
+}
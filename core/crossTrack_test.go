@@ -0,0 +1,37 @@
+package core
+
+import "testing"
+
+func TestCrossTrackCorrection(t *testing.T) {
+	corrector := newCrossTrackCorrector(2.0, 30.0, 20.0)
+
+	tests := []struct {
+		crossTrack float64
+		correction float64
+		exceeded   bool
+	}{
+		{0.0, 0.0, false},
+		{5.0, -10.0, false},
+		{-5.0, 10.0, false},
+		{19.0, -30.0, false},
+		{-25.0, 30.0, true},
+	}
+
+	for _, test := range tests {
+		correction := corrector.correction(test.crossTrack)
+		if correction != test.correction {
+			t.Errorf("Expected correction for %f to be %f, got %f",
+				test.crossTrack, test.correction, correction)
+		}
+		exceeded := corrector.exceeded(test.crossTrack)
+		if exceeded != test.exceeded {
+			t.Errorf("Expected exceeded for %f to be %t, got %t",
+				test.crossTrack, test.exceeded, exceeded)
+		}
+	}
+
+	var disabled *crossTrackCorrector
+	if disabled.correction(10.0) != 0 || disabled.exceeded(1000.0) {
+		t.Error("Expected nil corrector to be disabled")
+	}
+}
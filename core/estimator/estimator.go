@@ -0,0 +1,227 @@
+package estimator
+
+import (
+	"math"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+const (
+	stateSize = 5
+
+	// state vector indices
+	idxEast     = 0
+	idxNorth    = 1
+	idxSpeed    = 2
+	idxHeading  = 3
+	idxTurnRate = 4
+
+	initialVariance = 1e6
+	knotsToMps      = 0.514444
+	metersPerDegree = 111319.49
+)
+
+type matrix [stateSize][stateSize]float64
+
+// Extended Kalman filter over position (local east/north plane in meters), speed over ground (m/s),
+// heading (radians from true North) and turn rate (rad/s), fusing GPS fixes, GPS speed and compass heading
+type Estimator struct {
+	gpsNoise      float64
+	speedNoise    float64
+	headingNoise  float64
+	accelNoise    float64
+	yawAccelNoise float64
+
+	x           [stateSize]float64
+	p           matrix
+	lastUpdate  time.Time
+	originLat   float64
+	originLong  float64
+	hasPosition bool
+}
+
+// gpsNoise is in meters, speedNoise in knots, headingNoise in degrees,
+// accelNoise in m/s^2 and yawAccelNoise in deg/s^2
+func NewEstimator(gpsNoise, speedNoise, headingNoise, accelNoise, yawAccelNoise float64) *Estimator {
+	e := &Estimator{
+		gpsNoise:      gpsNoise,
+		speedNoise:    speedNoise * knotsToMps,
+		headingNoise:  headingNoise * math.Pi / 180,
+		accelNoise:    accelNoise,
+		yawAccelNoise: yawAccelNoise * math.Pi / 180,
+	}
+	for i := 0; i < stateSize; i++ {
+		e.p[i][i] = initialVariance
+	}
+	e.p[idxTurnRate][idxTurnRate] = 1
+
+	return e
+}
+
+func (e *Estimator) UpdatePosition(position *model.Position, t time.Time) {
+	if !e.hasPosition {
+		e.originLat = position.Latitude
+		e.originLong = position.Longitude
+		e.hasPosition = true
+	}
+	e.predict(t)
+
+	east, north := e.toLocal(position.Latitude, position.Longitude)
+	e.update(idxEast, east, e.gpsNoise*e.gpsNoise)
+	e.update(idxNorth, north, e.gpsNoise*e.gpsNoise)
+	e.update(idxSpeed, position.SpeedKnots*knotsToMps, e.speedNoise*e.speedNoise)
+}
+
+// heading is relative to true North in degrees
+func (e *Estimator) UpdateHeading(heading float64, t time.Time) {
+	e.predict(t)
+	e.update(idxHeading, heading*math.Pi/180, e.headingNoise*e.headingNoise)
+}
+
+// filtered copy of the raw position
+func (e *Estimator) Position(raw *model.Position) *model.Position {
+	position := *raw
+	if e.hasPosition {
+		position.Latitude, position.Longitude = e.toGlobal(e.x[idxEast], e.x[idxNorth])
+		position.SpeedKnots = e.x[idxSpeed] / knotsToMps
+		position.SpeedKm = e.x[idxSpeed] * 3.6
+	}
+	return &position
+}
+
+func (e *Estimator) Bearing() *model.Bearing {
+	bearing := model.NewBearing(0.0)
+	bearing.SetAngleDeg(e.HeadingDeg())
+	return bearing
+}
+
+func (e *Estimator) HeadingDeg() float64 {
+	return e.x[idxHeading] * 180 / math.Pi
+}
+
+// turn rate in degrees per second, positive clockwise
+func (e *Estimator) TurnRateDeg() float64 {
+	return e.x[idxTurnRate] * 180 / math.Pi
+}
+
+func (e *Estimator) predict(t time.Time) {
+	if e.lastUpdate.IsZero() {
+		e.lastUpdate = t
+		return
+	}
+	dt := t.Sub(e.lastUpdate).Seconds()
+	if dt <= 0 {
+		return
+	}
+	e.lastUpdate = t
+
+	speed := e.x[idxSpeed]
+	sinHeading, cosHeading := math.Sincos(e.x[idxHeading])
+
+	e.x[idxEast] += speed * sinHeading * dt
+	e.x[idxNorth] += speed * cosHeading * dt
+	e.x[idxHeading] = normalizeAngle(e.x[idxHeading] + e.x[idxTurnRate]*dt)
+
+	// Jacobian of the state transition
+	f := identity()
+	f[idxEast][idxSpeed] = sinHeading * dt
+	f[idxEast][idxHeading] = speed * cosHeading * dt
+	f[idxNorth][idxSpeed] = cosHeading * dt
+	f[idxNorth][idxHeading] = -speed * sinHeading * dt
+	f[idxHeading][idxTurnRate] = dt
+
+	var q matrix
+	posNoise := 0.5 * e.accelNoise * dt * dt
+	headingNoise := 0.5 * e.yawAccelNoise * dt * dt
+	q[idxEast][idxEast] = posNoise * posNoise
+	q[idxNorth][idxNorth] = posNoise * posNoise
+	q[idxSpeed][idxSpeed] = (e.accelNoise * dt) * (e.accelNoise * dt)
+	q[idxHeading][idxHeading] = headingNoise * headingNoise
+	q[idxTurnRate][idxTurnRate] = (e.yawAccelNoise * dt) * (e.yawAccelNoise * dt)
+
+	e.p = add(multiply(multiply(f, e.p), transpose(f)), q)
+}
+
+// sequential scalar measurement update of a single directly observed state variable
+func (e *Estimator) update(idx int, measurement float64, variance float64) {
+	innovation := measurement - e.x[idx]
+	if idx == idxHeading {
+		innovation = normalizeAngle(innovation)
+	}
+	s := e.p[idx][idx] + variance
+	if s <= 0 {
+		return
+	}
+
+	var k [stateSize]float64
+	for i := 0; i < stateSize; i++ {
+		k[i] = e.p[i][idx] / s
+		e.x[i] += k[i] * innovation
+	}
+	e.x[idxHeading] = normalizeAngle(e.x[idxHeading])
+
+	var p matrix
+	for i := 0; i < stateSize; i++ {
+		for j := 0; j < stateSize; j++ {
+			p[i][j] = e.p[i][j] - k[i]*e.p[idx][j]
+		}
+	}
+	e.p = p
+}
+
+func (e *Estimator) toLocal(lat, long float64) (float64, float64) {
+	east := (long - e.originLong) * metersPerDegree * math.Cos(e.originLat*math.Pi/180)
+	north := (lat - e.originLat) * metersPerDegree
+	return east, north
+}
+
+func (e *Estimator) toGlobal(east, north float64) (float64, float64) {
+	lat := e.originLat + north/metersPerDegree
+	long := e.originLong + east/(metersPerDegree*math.Cos(e.originLat*math.Pi/180))
+	return lat, long
+}
+
+func normalizeAngle(angle float64) float64 {
+	return math.Atan2(math.Sin(angle), math.Cos(angle))
+}
+
+func identity() matrix {
+	var m matrix
+	for i := 0; i < stateSize; i++ {
+		m[i][i] = 1
+	}
+	return m
+}
+
+func multiply(a, b matrix) matrix {
+	var m matrix
+	for i := 0; i < stateSize; i++ {
+		for j := 0; j < stateSize; j++ {
+			for k := 0; k < stateSize; k++ {
+				m[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return m
+}
+
+func transpose(a matrix) matrix {
+	var m matrix
+	for i := 0; i < stateSize; i++ {
+		for j := 0; j < stateSize; j++ {
+			m[i][j] = a[j][i]
+		}
+	}
+	return m
+}
+
+func add(a, b matrix) matrix {
+	var m matrix
+	for i := 0; i < stateSize; i++ {
+		for j := 0; j < stateSize; j++ {
+			m[i][j] = a[i][j] + b[i][j]
+		}
+	}
+	return m
+}
@@ -0,0 +1,102 @@
+package estimator
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+func TestStraightMotion(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	estimator := NewEstimator(3.0, 0.2, 3.0, 0.5, 1.0)
+
+	start := &model.Waypoint{
+		Latitude:  56.30,
+		Longitude: 44.00,
+	}
+	heading := 45.0
+	speedKnots := 6.0
+	now := time.Unix(1700000000, 0)
+
+	rawError := 0.0
+	filteredError := 0.0
+	headingError := 0.0
+	samples := 0
+	for i := 0; i < 240; i++ {
+		now = now.Add(500 * time.Millisecond)
+		distance := float64(i+1) * 0.5 * speedKnots * knotsToMps
+		truth := model.DestinationPoint(start, heading, distance)
+
+		raw := &model.Position{
+			Latitude:   truth.Latitude + rng.NormFloat64()*3.0/metersPerDegree,
+			Longitude:  truth.Longitude + rng.NormFloat64()*3.0/(metersPerDegree*math.Cos(truth.Latitude*math.Pi/180)),
+			SpeedKnots: speedKnots + rng.NormFloat64()*0.2,
+		}
+		estimator.UpdatePosition(raw, now)
+		estimator.UpdateHeading(heading+rng.NormFloat64()*3.0, now)
+
+		// let the filter converge before comparing
+		if i < 40 {
+			continue
+		}
+		filtered := estimator.Position(raw)
+		rawDistance := raw.DistanceMeters(truth)
+		filteredDistance := filtered.DistanceMeters(truth)
+		headingDelta := estimator.HeadingDeg() - heading
+		headingError += headingDelta * headingDelta
+		rawError += rawDistance * rawDistance
+		filteredError += filteredDistance * filteredDistance
+		samples++
+	}
+
+	rawRms := math.Sqrt(rawError / float64(samples))
+	filteredRms := math.Sqrt(filteredError / float64(samples))
+	if filteredRms >= rawRms/2 {
+		t.Errorf("Expected filtered position error %f to be less than half of raw error %f",
+			filteredRms, rawRms)
+	}
+	headingRms := math.Sqrt(headingError / float64(samples))
+	if headingRms >= 2.0 {
+		t.Errorf("Expected filtered heading error to be less than 2.0 deg, got %f", headingRms)
+	}
+	if math.Abs(estimator.Position(&model.Position{}).SpeedKnots-speedKnots) > 0.3 {
+		t.Errorf("Expected speed to be close to %f knots, got %f",
+			speedKnots, estimator.Position(&model.Position{}).SpeedKnots)
+	}
+}
+
+func TestTurnRate(t *testing.T) {
+	estimator := NewEstimator(3.0, 0.2, 1.0, 0.5, 5.0)
+	position := &model.Position{
+		Latitude:  56.30,
+		Longitude: 44.00,
+	}
+	now := time.Unix(1700000000, 0)
+
+	// turning left through South with 6 deg/s, heading wraps from -180 to 180
+	heading := -120.0
+	for i := 0; i < 60; i++ {
+		now = now.Add(250 * time.Millisecond)
+		heading -= 1.5
+		if heading <= -180 {
+			heading += 360
+		}
+		estimator.UpdatePosition(position, now)
+		estimator.UpdateHeading(heading, now)
+	}
+
+	if math.Abs(estimator.TurnRateDeg()+6.0) > 0.5 {
+		t.Errorf("Expected turn rate to be -6.0 deg/s, got %f", estimator.TurnRateDeg())
+	}
+	delta := math.Mod(estimator.HeadingDeg()-heading+540, 360) - 180
+	if math.Abs(delta) > 1.0 {
+		t.Errorf("Expected heading to be %f, got %f", heading, estimator.HeadingDeg())
+	}
+	if math.Abs(estimator.Bearing().AngleDeg()-estimator.HeadingDeg()) > 1e-9 {
+		t.Errorf("Expected bearing angle to match heading %f, got %f",
+			estimator.HeadingDeg(), estimator.Bearing().AngleDeg())
+	}
+}
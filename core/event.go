@@ -12,6 +12,17 @@ const (
 	eventNavStart
 	eventNavStop
 	eventNetLoss
+	eventFixLost
+	eventFixRestored
+	eventSensorStale
+	eventSensorRecovered
+	eventShipControlLost
+	eventShipControlRestored
+	eventPositionLost
+	eventPositionRestored
+	eventShipCommandFailed
+	eventHoldPosition
+	eventNoGoZonesSet
 )
 
 type Event uint16
@@ -38,6 +49,28 @@ func (e Event) String() string {
 		return "eventNavStop"
 	case eventNetLoss:
 		return "eventNetLoss"
+	case eventFixLost:
+		return "eventFixLost"
+	case eventFixRestored:
+		return "eventFixRestored"
+	case eventSensorStale:
+		return "eventSensorStale"
+	case eventSensorRecovered:
+		return "eventSensorRecovered"
+	case eventShipControlLost:
+		return "eventShipControlLost"
+	case eventShipControlRestored:
+		return "eventShipControlRestored"
+	case eventPositionLost:
+		return "eventPositionLost"
+	case eventPositionRestored:
+		return "eventPositionRestored"
+	case eventShipCommandFailed:
+		return "eventShipCommandFailed"
+	case eventHoldPosition:
+		return "eventHoldPosition"
+	case eventNoGoZonesSet:
+		return "eventNoGoZonesSet"
 	default:
 		return "undefined"
 	}
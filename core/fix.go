@@ -0,0 +1,41 @@
+package core
+
+import (
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+// checks GPS fix quality against the configured thresholds, zero threshold disables the check
+type fixChecker struct {
+	minSatellites int
+	maxHdop       float64
+	minFixType    model.FixType
+	maxFixAge     time.Duration
+}
+
+func newFixChecker(minSatellites int, maxHdop float64, minFixType int, maxFixAgeMs int64) *fixChecker {
+	return &fixChecker{
+		minSatellites: minSatellites,
+		maxHdop:       maxHdop,
+		minFixType:    model.FixType(minFixType),
+		maxFixAge:     time.Duration(maxFixAgeMs) * time.Millisecond,
+	}
+}
+
+// hdop and fix type are not checked if the position service does not report them
+func (c *fixChecker) usable(position *model.Position) bool {
+	if c.minSatellites > 0 && int(position.NumSatellites) < c.minSatellites {
+		return false
+	}
+	if c.maxHdop > 0 && position.Hdop > c.maxHdop {
+		return false
+	}
+	if c.minFixType > 0 && position.FixType != model.FixUnknown && position.FixType < c.minFixType {
+		return false
+	}
+	if c.maxFixAge > 0 && position.FixAge > c.maxFixAge {
+		return false
+	}
+	return true
+}
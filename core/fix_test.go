@@ -0,0 +1,40 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+func TestFixChecker(t *testing.T) {
+	checker := newFixChecker(4, 5.0, 2, 2000)
+
+	tests := []struct {
+		name     string
+		position *model.Position
+		usable   bool
+	}{
+		{"good fix", &model.Position{NumSatellites: 8, Hdop: 1.2, FixType: model.Fix3D, FixAge: time.Second}, true},
+		{"no satellites", &model.Position{NumSatellites: 0, Hdop: 1.2, FixType: model.Fix3D}, false},
+		{"high hdop", &model.Position{NumSatellites: 8, Hdop: 7.5, FixType: model.Fix3D}, false},
+		{"no hdop", &model.Position{NumSatellites: 8, FixType: model.Fix3D}, true},
+		{"no fix type", &model.Position{NumSatellites: 8, Hdop: 1.2}, true},
+		{"no hdop and fix type", &model.Position{NumSatellites: 8}, true},
+		{"no fix", &model.Position{NumSatellites: 8, Hdop: 1.2, FixType: model.FixNone}, false},
+		{"2D fix", &model.Position{NumSatellites: 8, Hdop: 1.2, FixType: model.Fix2D}, true},
+		{"stale fix", &model.Position{NumSatellites: 8, Hdop: 1.2, FixType: model.Fix3D, FixAge: 3 * time.Second}, false},
+	}
+
+	for _, test := range tests {
+		if checker.usable(test.position) != test.usable {
+			t.Errorf("%s: expected usable to be %t", test.name, test.usable)
+		}
+	}
+
+	// disabled checks
+	checker = newFixChecker(0, 0, 0, 0)
+	if !checker.usable(&model.Position{}) {
+		t.Errorf("Expected any fix to be usable with checks disabled")
+	}
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+// distance in meters the ship may move away from home during the breach before it is stopped,
+// covers the turn towards home and the detours around the no-go zones
+const geofenceHomeMargin = 50.0
+
+// keeps the ship inside of the test area, checked on every position update
+type geofence struct {
+	keepIn *model.Polygon
+	// zero disables the limit, the limit is not checked until the home waypoint is set
+	maxHomeDistance float64
+}
+
+// returns nil if neither the keep-in area nor the maximum distance from home is configured
+func newGeofence(keepIn *model.Polygon, maxHomeDistance float64) *geofence {
+	if keepIn == nil && maxHomeDistance <= 0 {
+		return nil
+	}
+
+	return &geofence{
+		keepIn:          keepIn,
+		maxHomeDistance: maxHomeDistance,
+	}
+}
+
+// reason of the breach, empty if the position is inside of the geofence
+func (g *geofence) check(coreData *coreData) string {
+	return g.checkWaypoint(coreData.position.Waypoint(), coreData.homeWaypoint, coreData.geodesic)
+}
+
+// reason why the waypoint is outside of the geofence, empty if it is inside
+func (g *geofence) checkWaypoint(waypoint *model.Waypoint, home *model.Waypoint,
+	geodesic model.Geodesic) string {
+	if g.keepIn != nil && !g.keepIn.Contains(waypoint) {
+		return "outside of the keep-in area"
+	}
+	if g.maxHomeDistance > 0 && home != nil {
+		distance := geodesic.Distance(home.Latitude, home.Longitude, waypoint.Latitude, waypoint.Longitude)
+		if distance > g.maxHomeDistance {
+			return fmt.Sprintf("%.1f meters from home exceeds %.1f meters", distance, g.maxHomeDistance)
+		}
+	}
+	return ""
+}
+
+// transition after the ship left the geofence, the ship returns home if the home waypoint is set
+// and stops otherwise
+func geofenceTransition(logger *zerolog.Logger, coreData *coreData) string {
+	if !coreData.geofence.Breached {
+		return ""
+	}
+	if coreData.homeWaypoint != nil {
+		logger.Warn().Msgf("geofence breach, %s, returning home", coreData.geofence.Reason)
+		return "geofence home"
+	}
+	logger.Warn().Msgf("geofence breach, %s, stopping", coreData.geofence.Reason)
+	return "geofence stop"
+}
+
+// the ship returning home after the breach is stopped if the breach gets worse, that is
+// the distance from home grows past the closest distance since the breach
+func geofenceHomeTransition(logger *zerolog.Logger, coreData *coreData) string {
+	if !coreData.geofence.Breached || coreData.homeWaypoint == nil {
+		return ""
+	}
+
+	distance := coreData.distanceMeters(coreData.homeWaypoint)
+	if coreData.breachHomeDistance == 0 || distance < coreData.breachHomeDistance {
+		coreData.breachHomeDistance = distance
+		return ""
+	}
+	if distance > coreData.breachHomeDistance+geofenceHomeMargin {
+		logger.Warn().Msgf("geofence breach, %s, moving away from home, stopping", coreData.geofence.Reason)
+		return "geofence stop"
+	}
+	return ""
+}
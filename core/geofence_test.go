@@ -0,0 +1,247 @@
+package core
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+func geofenceTestArea(t *testing.T) *model.Polygon {
+	keepIn, err := model.NewPolygon([]*model.Waypoint{
+		{Latitude: 56.40, Longitude: 43.83},
+		{Latitude: 56.40, Longitude: 43.87},
+		{Latitude: 56.42, Longitude: 43.87},
+		{Latitude: 56.42, Longitude: 43.83},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create polygon: %s", err.Error())
+	}
+	return keepIn
+}
+
+func TestNewGeofenceDisabled(t *testing.T) {
+	if newGeofence(nil, 0.0) != nil {
+		t.Error("Expected geofence to be disabled")
+	}
+}
+
+func TestGeofenceCheck(t *testing.T) {
+	fence := newGeofence(geofenceTestArea(t), 500.0)
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  56.41,
+			Longitude: 43.85,
+		},
+	}
+
+	if reason := fence.check(coreData); reason != "" {
+		t.Errorf("Expected position to be inside of the geofence, got %s", reason)
+	}
+
+	coreData.position.Latitude = 56.43
+	if reason := fence.check(coreData); reason != "outside of the keep-in area" {
+		t.Errorf("Expected position to be outside of the keep-in area, got %s", reason)
+	}
+
+	// about 1100 meters from home
+	coreData.position.Latitude = 56.41
+	coreData.homeWaypoint = &model.Waypoint{
+		Latitude:  56.401,
+		Longitude: 43.85,
+	}
+	if reason := fence.check(coreData); reason == "" {
+		t.Error("Expected position to be too far from home")
+	}
+
+	// the distance is not limited without the keep-in area
+	fence = newGeofence(nil, 2000.0)
+	if reason := fence.check(coreData); reason != "" {
+		t.Errorf("Expected position to be inside of the geofence, got %s", reason)
+	}
+}
+
+func TestGeofenceTransition(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	waypoints := model.NewWaypoints()
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.41,
+		Longitude: 43.86,
+	})
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  56.41,
+			Longitude: 43.85,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		waypoints:     waypoints,
+	}
+
+	handler := newMovingHandler(&logger, coreData, &mockShipControl{},
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
+	handler.OnEnter()
+
+	transition := handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+
+	coreData.geofence.Breached = true
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "geofence stop" {
+		t.Errorf("Expected geofence stop transition, got %s", transition)
+	}
+
+	coreData.homeWaypoint = &model.Waypoint{
+		Latitude:  56.405,
+		Longitude: 43.85,
+	}
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "geofence home" {
+		t.Errorf("Expected geofence home transition, got %s", transition)
+	}
+}
+
+func TestGeofenceHomeTransition(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  56.43,
+			Longitude: 43.85,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		waypoints:     model.NewWaypoints(),
+		homeWaypoint: &model.Waypoint{
+			Latitude:  56.405,
+			Longitude: 43.85,
+		},
+	}
+	coreData.geofence.Breached = true
+
+	handler := newMovingHomeHandler(&logger, coreData, &mockShipControl{},
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
+	handler.OnEnter()
+
+	transition := handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+
+	// about 30 meters further from home is tolerated
+	coreData.position.Latitude = 56.4303
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+
+	// about 110 meters further from home
+	coreData.position.Latitude = 56.431
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "geofence stop" {
+		t.Errorf("Expected geofence stop transition, got %s", transition)
+	}
+
+	// the ship moving home is not stopped
+	coreData.breachHomeDistance = 0
+	turningHome := newTurningHomeHandler(&logger, coreData, &mockShipControl{}, model.Forward(30),
+		model.Left(40), model.Right(40), nil, nil)
+	turningHome.OnEnter()
+	for _, latitude := range []float64{56.431, 56.430, 56.425} {
+		coreData.position.Latitude = latitude
+		transition = turningHome.HandleEvent(Event(eventPositionUpdate))
+		if transition != "" {
+			t.Errorf("Expected empty transition at latitude %f, got %s", latitude, transition)
+		}
+	}
+	coreData.position.Latitude = 56.427
+	transition = turningHome.HandleEvent(Event(eventPositionUpdate))
+	if transition != "geofence stop" {
+		t.Errorf("Expected geofence stop transition, got %s", transition)
+	}
+}
+
+func TestCoreGeofence(t *testing.T) {
+	mockShipControl := &mockShipControl{}
+	mockCoreConfigurer := &mockCoreConfigurer{
+		geofenceKeepIn: geofenceTestArea(t),
+	}
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+
+	core := NewCore(mockCoreConfigurer, mockShipControl, &logger)
+	go core.Run()
+	defer core.Stop()
+
+	if !core.GetGeofenceStatus().Enabled {
+		t.Error("Expected geofence to be enabled")
+	}
+
+	core.AddWaypoint(&model.Waypoint{
+		Latitude:  56.41,
+		Longitude: 43.86,
+	})
+	core.UpdatePosition(&model.Position{
+		Latitude:  56.41,
+		Longitude: 43.85,
+	})
+	time.Sleep(10 * time.Millisecond)
+	core.StartNavigation()
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "turning" {
+		t.Errorf("Expected core state to be turning, got %s", core.fsm.CurrentState())
+	}
+
+	// the ship leaves the test area without the home waypoint
+	core.UpdatePosition(&model.Position{
+		Latitude:  56.43,
+		Longitude: 43.85,
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "stopping" {
+		t.Errorf("Expected core state to be stopping, got %s", core.fsm.CurrentState())
+	}
+	status := core.GetGeofenceStatus()
+	if !status.Breached || status.Reason == "" || status.Timestamp.IsZero() {
+		t.Errorf("Expected geofence breach to be reported, got %v", status)
+	}
+
+	core.UpdatePosition(&model.Position{
+		Latitude:  56.41,
+		Longitude: 43.85,
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	if core.GetGeofenceStatus().Breached {
+		t.Error("Expected geofence breach to be cleared")
+	}
+
+	core.UpdateShipData(&model.ShipData{
+		Speed:    model.SpeedStop,
+		Steering: model.SteeringStraight,
+	})
+
+	// the ship returns home
+	core.SetHomeWaypoint(&model.Waypoint{
+		Latitude:  56.405,
+		Longitude: 43.85,
+	})
+	time.Sleep(10 * time.Millisecond)
+	core.StartNavigation()
+	time.Sleep(10 * time.Millisecond)
+	core.UpdatePosition(&model.Position{
+		Latitude:  56.43,
+		Longitude: 43.85,
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	if core.fsm.CurrentState() != "turning home" {
+		t.Errorf("Expected core state to be turning home, got %s", core.fsm.CurrentState())
+	}
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"math"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+const (
+	defaultHeadingMaxSteering  = 100
+	defaultHeadingSteeringStep = 10
+)
+
+// maps heading error to a graded steering command (left10..left100, right10..right100)
+type headingController struct {
+	pid          *pidController
+	maxSteering  int
+	steeringStep int
+	lastUpdate   time.Time
+}
+
+func newHeadingController(kp, ki, kd, integralLimit float64, maxSteering int,
+	steeringStep int) *headingController {
+	if kp == 0 {
+		return nil
+	}
+	if maxSteering <= 0 || maxSteering > 100 {
+		maxSteering = defaultHeadingMaxSteering
+	}
+	if steeringStep <= 0 {
+		steeringStep = defaultHeadingSteeringStep
+	}
+
+	return &headingController{
+		pid:          newPIDController(kp, ki, kd, integralLimit, float64(maxSteering)),
+		maxSteering:  maxSteering,
+		steeringStep: steeringStep,
+	}
+}
+
+func (c *headingController) reset() {
+	c.pid.reset()
+	c.lastUpdate = time.Time{}
+}
+
+// errorDeg is positive when the target is to the right of the current heading
+func (c *headingController) steering(errorDeg float64, now time.Time) model.Steering {
+	dt := 0.0
+	if !c.lastUpdate.IsZero() {
+		dt = now.Sub(c.lastUpdate).Seconds()
+	}
+	c.lastUpdate = now
+
+	output := c.pid.update(errorDeg, dt)
+	value := int(math.Round(math.Abs(output)/float64(c.steeringStep))) * c.steeringStep
+	if value > c.maxSteering {
+		value = c.maxSteering
+	}
+
+	if output < 0 {
+		return model.Left(value)
+	}
+	return model.Right(value)
+}
+
+// shortest signed difference between target and current angles in degrees
+func headingErrorDeg(target, current float64) float64 {
+	return model.AngleDiff(model.Angle(target), model.Angle(current)).Deg()
+}
+
+// target bearing is relative to true North, current bearing is corrected with magnetic declination
+func setTargetBearing(coreData *coreData, waypoint *model.Waypoint) {
+	coreData.targetBearing.SetAngleDeg(model.InitialBearing(coreData.position.Waypoint(), waypoint))
+}
+
+// target bearing along the leg between the given waypoints adjusted by the correction angle in degrees
+func setLegTargetBearing(coreData *coreData, start *model.Waypoint, end *model.Waypoint, correction float64) {
+	if start == nil {
+		setTargetBearing(coreData, end)
+	} else {
+		coreData.targetBearing.SetAngleDeg(model.InitialBearing(start, end))
+	}
+	coreData.targetBearing.RotateDeg(correction)
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewHeadingControllerDisabled(t *testing.T) {
+	controller := newHeadingController(0.0, 1.0, 1.0, 10.0, 100, 10)
+	if controller != nil {
+		t.Error("Expected heading controller to be disabled with zero Kp")
+	}
+}
+
+func TestHeadingControllerSteering(t *testing.T) {
+	controller := newHeadingController(2.0, 0.0, 0.0, 0.0, 80, 10)
+	now := time.Now()
+
+	tests := []struct {
+		errorDeg float64
+		steering string
+	}{
+		{0.0, "straight"},
+		{2.0, "straight"},
+		{3.0, "right10"},
+		{-3.0, "left10"},
+		{16.0, "right30"},
+		{-24.0, "left50"},
+		{90.0, "right80"},
+		{-179.0, "left80"},
+	}
+
+	for _, test := range tests {
+		controller.reset()
+		steering := controller.steering(test.errorDeg, now)
+		if steering.String() != test.steering {
+			t.Errorf("Expected steering to be %s for error %f, got %s",
+				test.steering, test.errorDeg, steering)
+		}
+	}
+}
+
+func TestHeadingErrorDeg(t *testing.T) {
+	tests := []struct {
+		target  float64
+		current float64
+		delta   float64
+	}{
+		{10.0, 0.0, 10.0},
+		{0.0, 10.0, -10.0},
+		{179.0, -179.0, -2.0},
+		{-179.0, 179.0, 2.0},
+		{-92.0, 100.0, 168.0},
+		{180.0, 0.0, 180.0},
+	}
+
+	for _, test := range tests {
+		delta := headingErrorDeg(test.target, test.current)
+		if delta != test.delta {
+			t.Errorf("Expected delta for %f/%f to be %f, got %f",
+				test.target, test.current, test.delta, delta)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+// navigation is paused with the motors stopped until the position fix is usable
+// and the position service delivers fresh data again
+type holdingHandler struct {
+	logger      *zerolog.Logger
+	coreData    *coreData
+	shipControl ShipControl
+}
+
+func newHoldingHandler(logger *zerolog.Logger, coreData *coreData,
+	shipControl ShipControl) *holdingHandler {
+	return &holdingHandler{
+		logger:      logger,
+		coreData:    coreData,
+		shipControl: shipControl,
+	}
+}
+
+func (handler *holdingHandler) OnEnter() {
+	handler.logger.Debug().Msg("OnEnter")
+
+	emergencyStop(handler.shipControl)
+	handler.shipControl.SetSteering(model.SteeringStraight)
+}
+
+func (handler *holdingHandler) OnExit() {
+	handler.logger.Debug().Msg("OnExit")
+}
+
+func (handler *holdingHandler) HandleEvent(event Event) string {
+	handler.logger.Debug().Msgf("HandleEvent event=%s", event.String())
+
+	switch event {
+	case eventFixRestored, eventSensorRecovered, eventPositionRestored:
+		return handler.resume()
+	case eventShipControlLost:
+		return "ship control lost"
+	case eventShipCommandFailed:
+		return "command failed"
+	case eventNetLoss:
+		if handler.coreData.homeBound {
+			return ""
+		}
+		if handler.coreData.homeWaypoint != nil {
+			handler.logger.Info().Msg("net loss home, waiting for position fix")
+			handler.coreData.homeBound = true
+			return ""
+		}
+		if handler.coreData.loitering {
+			return ""
+		}
+		return "net loss stop"
+	case eventHoldPosition:
+		handler.coreData.homeBound = false
+		handler.coreData.loitering = true
+	case eventNavStop:
+		return "nav stop"
+	case eventWaypointsCleared:
+		if !handler.coreData.homeBound && !handler.coreData.loitering {
+			return "waypoints cleared"
+		}
+	}
+
+	return ""
+}
+
+func (handler *holdingHandler) resume() string {
+	if handler.coreData.navigationPaused() {
+		return ""
+	}
+	if handler.coreData.homeBound {
+		handler.logger.Info().Msg("resume home")
+		return "resume home"
+	}
+	if handler.coreData.loitering {
+		handler.logger.Info().Msg("resume loiter")
+		return "resume loiter"
+	}
+	handler.logger.Info().Msg("resume")
+	return "resume"
+}
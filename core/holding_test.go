@@ -0,0 +1,152 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+func TestHoldingOnEnter(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := &coreData{
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+	}
+
+	shipControl := &mockShipControl{}
+
+	handler := newHoldingHandler(&logger, coreData, shipControl)
+	handler.OnEnter()
+
+	if shipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", shipControl.speed)
+	}
+	if shipControl.steering != "straight" {
+		t.Errorf("Expected steering to be straight, got %s", shipControl.steering)
+	}
+}
+
+func TestHoldingEventFixRestored(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	tests := []struct {
+		homeBound  bool
+		transition string
+	}{
+		{false, "resume"},
+		{true, "resume home"},
+	}
+
+	for _, test := range tests {
+		coreData := &coreData{
+			curBearing:    model.NewBearing(0.0),
+			targetBearing: model.NewBearing(0.0),
+			homeBound:     test.homeBound,
+		}
+
+		handler := newHoldingHandler(&logger, coreData, &mockShipControl{})
+		handler.OnEnter()
+
+		transition := handler.HandleEvent(Event(eventPositionUpdate))
+		if transition != "" {
+			t.Errorf("Expected empty transition, got %s", transition)
+		}
+		transition = handler.HandleEvent(Event(eventFixRestored))
+		if transition != test.transition {
+			t.Errorf("Expected %s transition, got %s", test.transition, transition)
+		}
+	}
+}
+
+func TestHoldingEventNetLoss(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := &coreData{
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+	}
+
+	handler := newHoldingHandler(&logger, coreData, &mockShipControl{})
+	handler.OnEnter()
+
+	transition := handler.HandleEvent(Event(eventNetLoss))
+	if transition != "net loss stop" {
+		t.Errorf("Expected net loss stop transition, got %s", transition)
+	}
+
+	// with the home waypoint the ship returns home once the fix is restored
+	coreData.homeWaypoint = &model.Waypoint{
+		Latitude:  56.333284,
+		Longitude: 44.008402,
+	}
+	transition = handler.HandleEvent(Event(eventNetLoss))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	transition = handler.HandleEvent(Event(eventFixRestored))
+	if transition != "resume home" {
+		t.Errorf("Expected resume home transition, got %s", transition)
+	}
+}
+
+func TestHoldingWaitsForAllSensors(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := &coreData{
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		fixLost:       true,
+		sensorStale:   true,
+	}
+
+	handler := newHoldingHandler(&logger, coreData, &mockShipControl{})
+	handler.OnEnter()
+
+	coreData.sensorStale = false
+	transition := handler.HandleEvent(Event(eventSensorRecovered))
+	if transition != "" {
+		t.Errorf("Expected empty transition while the fix is lost, got %s", transition)
+	}
+
+	coreData.fixLost = false
+	transition = handler.HandleEvent(Event(eventFixRestored))
+	if transition != "resume" {
+		t.Errorf("Expected resume transition, got %s", transition)
+	}
+}
+
+func TestHoldingResumeLoiter(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := &coreData{
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		fixLost:       true,
+	}
+
+	handler := newHoldingHandler(&logger, coreData, &mockShipControl{})
+	handler.OnEnter()
+
+	transition := handler.HandleEvent(Event(eventHoldPosition))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+
+	// net loss and cleared waypoints do not stop the ship holding position
+	transition = handler.HandleEvent(Event(eventNetLoss))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	transition = handler.HandleEvent(Event(eventWaypointsCleared))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+
+	coreData.fixLost = false
+	transition = handler.HandleEvent(Event(eventFixRestored))
+	if transition != "resume loiter" {
+		t.Errorf("Expected resume loiter transition, got %s", transition)
+	}
+}
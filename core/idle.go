@@ -18,6 +18,10 @@ func newIdleHandler(logger *zerolog.Logger, coreData *coreData) *idleHandler {
 
 func (handler *idleHandler) OnEnter() {
 	handler.logger.Debug().Msg("OnEnter")
+
+	handler.coreData.loitering = false
+	handler.coreData.loiterPoint = nil
+	handler.coreData.homeRoute = nil
 }
 
 func (handler *idleHandler) OnExit() {
@@ -28,10 +32,43 @@ func (handler *idleHandler) HandleEvent(event Event) string {
 	handler.logger.Debug().Msgf("HandleEvent event=%s", event.String())
 	switch event {
 	case eventNavStart:
+		if handler.coreData.shipControlLost {
+			handler.logger.Warn().Msg("nav start ignored, ship control is not connected")
+			return ""
+		}
+		if !validateNavStart(handler.logger, handler.coreData) {
+			return ""
+		}
+		if handler.coreData.waypoints.GetNextWaypoint() == nil {
+			handler.logger.Warn().Msg("nav start ignored, no waypoints")
+			return ""
+		}
+		handler.coreData.homeBound = false
+		if handler.coreData.navigationPaused() {
+			handler.logger.Info().Msg("nav start, waiting for sensor data")
+			return "hold"
+		}
 		handler.logger.Info().Msg("nav start")
 		return "nav start"
+	case eventHoldPosition:
+		if handler.coreData.shipControlLost {
+			handler.logger.Warn().Msg("hold position ignored, ship control is not connected")
+			return ""
+		}
+		handler.coreData.homeBound = false
+		handler.coreData.loitering = true
+		if handler.coreData.navigationPaused() {
+			handler.logger.Info().Msg("hold position, waiting for sensor data")
+			return "hold"
+		}
+		return "hold position"
 	case eventNetLoss:
-		if handler.coreData.homeWaypoint != nil {
+		if handler.coreData.homeWaypoint != nil && !handler.coreData.shipControlLost {
+			handler.coreData.homeBound = true
+			if handler.coreData.navigationPaused() {
+				handler.logger.Info().Msg("net loss home, waiting for sensor data")
+				return "hold"
+			}
 			handler.logger.Info().Msg("net loss home")
 			return "net loss home"
 		}
@@ -13,12 +13,23 @@ func TestIdleEventNavStart(t *testing.T) {
 	coreData := &coreData{
 		curBearing:    model.NewBearing(0.0),
 		targetBearing: model.NewBearing(0.0),
+		waypoints:     model.NewWaypoints(),
 	}
 
 	handler := newIdleHandler(&logger, coreData)
 	handler.OnEnter()
 
+	// navigation is not started without waypoints
 	transition := handler.HandleEvent(Event(eventNavStart))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+
+	coreData.waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.333284,
+		Longitude: 44.008402,
+	})
+	transition = handler.HandleEvent(Event(eventNavStart))
 	if transition != "nav start" {
 		t.Errorf("Expected nav start transition, got %s", transition)
 	}
@@ -44,3 +55,68 @@ func TestIdleEventNetLoss(t *testing.T) {
 		t.Errorf("Expected net loss home transition, got %s", transition)
 	}
 }
+
+func TestIdleNoFix(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := &coreData{
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		homeWaypoint: &model.Waypoint{
+			Latitude:  56.333284,
+			Longitude: 44.008402,
+		},
+		waypoints: model.NewWaypoints(),
+		fixLost:   true,
+	}
+	coreData.waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.333284,
+		Longitude: 44.008402,
+	})
+
+	handler := newIdleHandler(&logger, coreData)
+	handler.OnEnter()
+
+	transition := handler.HandleEvent(Event(eventNavStart))
+	if transition != "hold" {
+		t.Errorf("Expected hold transition, got %s", transition)
+	}
+	if coreData.homeBound {
+		t.Errorf("Expected navigation not to be home bound")
+	}
+
+	transition = handler.HandleEvent(Event(eventNetLoss))
+	if transition != "hold" {
+		t.Errorf("Expected hold transition, got %s", transition)
+	}
+	if !coreData.homeBound {
+		t.Errorf("Expected navigation to be home bound")
+	}
+}
+
+func TestIdleEventHoldPosition(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := &coreData{
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+	}
+
+	handler := newIdleHandler(&logger, coreData)
+	handler.OnEnter()
+
+	transition := handler.HandleEvent(Event(eventHoldPosition))
+	if transition != "hold position" {
+		t.Errorf("Expected hold position transition, got %s", transition)
+	}
+
+	// position is held once the fix is usable
+	coreData.fixLost = true
+	transition = handler.HandleEvent(Event(eventHoldPosition))
+	if transition != "hold" {
+		t.Errorf("Expected hold transition, got %s", transition)
+	}
+	if !coreData.loitering {
+		t.Errorf("Expected navigation to be loitering")
+	}
+}
@@ -17,21 +17,40 @@ type ShipDataUpdater interface {
 	UpdateShipData(*model.ShipData)
 }
 
+// connection state of the services ship-nav depends on
+type ShipConnectionUpdater interface {
+	UpdateShipConnection(connected bool)
+}
+
+type PositionConnectionUpdater interface {
+	UpdatePositionConnection(connected bool)
+}
+
+// commands ship control did not execute after all retries
+type ShipCommandFailureUpdater interface {
+	ShipCommandFailed(cmd string, data string, err error)
+}
+
 type WaypointsUpdater interface {
-	SetWaypoints([]*model.Waypoint)
+	SetWaypoints([]*model.Waypoint) []*model.MissionProblem
 	AddWaypoint(*model.Waypoint)
 	ClearWaypoints()
 	SetHomeWaypoint(*model.Waypoint)
+	SetMissionEnd(model.MissionEnd)
+	SetNoGoZones([]*model.Polygon)
 }
 
 type NavigationController interface {
-	StartNavigation()
+	StartNavigation() []*model.MissionProblem
 	StopNavigation()
 	NetworkLost()
+	HoldPosition(*model.Waypoint)
 }
 
 type PositionDataProvider interface {
 	GetPositionData() (*model.Bearing, *model.Position)
+	GetRawPositionData() (*model.Bearing, *model.Position)
+	GetGeofenceStatus() *model.GeofenceStatus
 }
 
 type ShipDataProvider interface {
@@ -40,15 +59,17 @@ type ShipDataProvider interface {
 
 type WaypointDataProvider interface {
 	GetWaypoints() []*model.Waypoint
+	GetMissionEvents() []*model.MissionEvent
 }
 
 // interfaces required by the core
 type ShipControl interface {
-	SetSpeed(string)
-	SetSteering(string)
+	SetSpeed(model.Speed)
+	SetSteering(model.Steering)
 }
 
 type PositionCalibrator interface {
 	StartCalibration()
 	StopCalibration()
+	CalibrationStatus() *model.CalibrationStatus
 }
@@ -0,0 +1,164 @@
+package core
+
+import (
+	"math"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultLoiterRadius = 10.0
+	// correction stops when the ship is back inside this part of the loiter radius
+	loiterReturnRatio = 0.5
+	// the ship turns on the spot towards the loiter point if the heading error is larger
+	loiterHeadingTolerance = 20.0
+)
+
+// the ship drifts with the motors stopped while it stays inside the loiter radius,
+// it returns to the loiter point with short turning and moving bursts when it drifts away
+type loiteringHandler struct {
+	logger               *zerolog.Logger
+	coreData             *coreData
+	shipControl          ShipControl
+	turningSpeed         model.Speed
+	turningSteeringLeft  model.Steering
+	turningSteeringRight model.Steering
+	loiterSpeed          model.Speed
+	radius               float64
+	correcting           bool
+}
+
+func newLoiteringHandler(logger *zerolog.Logger, coreData *coreData, shipControl ShipControl,
+	turningSpeed model.Speed, turningSteeringLeft model.Steering, turningSteeringRight model.Steering,
+	loiterSpeed model.Speed, radius float64) *loiteringHandler {
+	if radius <= 0 {
+		radius = defaultLoiterRadius
+	}
+	if !loiterSpeed.IsForward() {
+		loiterSpeed = turningSpeed
+	}
+
+	return &loiteringHandler{
+		logger:               logger,
+		coreData:             coreData,
+		shipControl:          shipControl,
+		turningSpeed:         turningSpeed,
+		turningSteeringLeft:  turningSteeringLeft,
+		turningSteeringRight: turningSteeringRight,
+		loiterSpeed:          loiterSpeed,
+		radius:               radius,
+	}
+}
+
+func (handler *loiteringHandler) OnEnter() {
+	handler.logger.Debug().Msg("OnEnter")
+
+	handler.coreData.homeBound = false
+	handler.coreData.homeRoute = nil
+	handler.coreData.loitering = true
+	if handler.coreData.loiterPoint == nil {
+		handler.coreData.loiterPoint = handler.coreData.position.Waypoint()
+	}
+	handler.logger.Info().Msgf("holding position %f, %f", handler.coreData.loiterPoint.Latitude,
+		handler.coreData.loiterPoint.Longitude)
+
+	handler.correcting = false
+	handler.stop()
+	handler.correct()
+}
+
+func (handler *loiteringHandler) OnExit() {
+	handler.logger.Debug().Msg("OnExit")
+
+	handler.coreData.targetBearing.SetInt(0, 0)
+}
+
+func (handler *loiteringHandler) HandleEvent(event Event) string {
+	handler.logger.Debug().Msgf("HandleEvent event=%s", event.String())
+
+	switch event {
+	case eventPositionUpdate, eventBearingUpdate:
+		if event == eventPositionUpdate {
+			if transition := geofenceTransition(handler.logger, handler.coreData); transition != "" {
+				return transition
+			}
+		}
+		handler.correct()
+	case eventHoldPosition:
+		if handler.coreData.loiterPoint == nil {
+			handler.coreData.loiterPoint = handler.coreData.position.Waypoint()
+		}
+		handler.logger.Info().Msgf("holding position %f, %f", handler.coreData.loiterPoint.Latitude,
+			handler.coreData.loiterPoint.Longitude)
+		handler.correct()
+	case eventNavStart:
+		if !validateNavStart(handler.logger, handler.coreData) {
+			return ""
+		}
+		if handler.coreData.waypoints.GetNextWaypoint() == nil {
+			handler.logger.Warn().Msg("nav start ignored, no waypoints")
+			return ""
+		}
+		return "nav start"
+	case eventNetLoss:
+		// keep holding position if there is no home waypoint to return to
+		if handler.coreData.homeWaypoint != nil {
+			handler.logger.Info().Msg("net loss home")
+			return "net loss home"
+		}
+	case eventNavStop:
+		return "nav stop"
+	case eventFixLost:
+		return "fix lost"
+	case eventSensorStale:
+		return "sensor stale"
+	case eventPositionLost:
+		return "position lost"
+	case eventShipControlLost:
+		return "ship control lost"
+	case eventShipCommandFailed:
+		return "command failed"
+	}
+
+	return ""
+}
+
+// correction starts when the ship leaves the loiter radius and continues until the ship is close
+// to the loiter point again so that it does not oscillate on the boundary
+func (handler *loiteringHandler) correct() {
+	distance := handler.coreData.distanceMeters(handler.coreData.loiterPoint)
+	handler.logger.Debug().Msgf("distance to loiter point = %f", distance)
+	if !handler.correcting {
+		if distance <= handler.radius {
+			return
+		}
+		handler.logger.Info().Msgf("drifted %f meters from the loiter point, correcting", distance)
+		handler.correcting = true
+	} else if distance <= handler.radius*loiterReturnRatio {
+		handler.logger.Info().Msg("back at the loiter point")
+		handler.correcting = false
+		handler.stop()
+		return
+	}
+
+	setTargetBearing(handler.coreData, handler.coreData.loiterPoint)
+	deltaAngle := handler.coreData.headingErrorDeg()
+	if math.Abs(deltaAngle) > loiterHeadingTolerance {
+		if deltaAngle < 0 {
+			handler.shipControl.SetSteering(handler.turningSteeringLeft)
+		} else {
+			handler.shipControl.SetSteering(handler.turningSteeringRight)
+		}
+		handler.shipControl.SetSpeed(handler.turningSpeed)
+		return
+	}
+
+	handler.shipControl.SetSteering(model.SteeringStraight)
+	handler.shipControl.SetSpeed(handler.loiterSpeed)
+}
+
+func (handler *loiteringHandler) stop() {
+	handler.shipControl.SetSpeed(model.SpeedStop)
+	handler.shipControl.SetSteering(model.SteeringStraight)
+}
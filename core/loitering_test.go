@@ -0,0 +1,216 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+func newLoiteringTestHandler(coreData *coreData, shipControl ShipControl) *loiteringHandler {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+	return newLoiteringHandler(&logger, coreData, shipControl, model.Forward(30), model.Left(40),
+		model.Right(40), model.Forward(20), 10.0)
+}
+
+func TestLoiteringOnEnter(t *testing.T) {
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  56.34,
+			Longitude: 43.99,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		homeBound:     true,
+	}
+	shipControl := &mockShipControl{}
+
+	handler := newLoiteringTestHandler(coreData, shipControl)
+	handler.OnEnter()
+
+	if shipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", shipControl.speed)
+	}
+	if shipControl.steering != "straight" {
+		t.Errorf("Expected steering to be straight, got %s", shipControl.steering)
+	}
+	if coreData.loiterPoint == nil || coreData.loiterPoint.Latitude != 56.34 ||
+		coreData.loiterPoint.Longitude != 43.99 {
+		t.Errorf("Expected current position to be the loiter point, got %v", coreData.loiterPoint)
+	}
+	if !coreData.loitering || coreData.homeBound {
+		t.Errorf("Expected loitering without heading home")
+	}
+}
+
+func TestLoiteringCorrection(t *testing.T) {
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  56.34,
+			Longitude: 43.99,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		loiterPoint: &model.Waypoint{
+			Latitude:  56.34,
+			Longitude: 43.99,
+		},
+	}
+	shipControl := &mockShipControl{}
+
+	handler := newLoiteringTestHandler(coreData, shipControl)
+	handler.OnEnter()
+
+	// drifting inside the loiter radius
+	coreData.position.Latitude = 56.34007
+	handler.HandleEvent(Event(eventPositionUpdate))
+	if shipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop inside the loiter radius, got %s", shipControl.speed)
+	}
+
+	// drifted north of the loiter point heading east, turn right towards it
+	coreData.position.Latitude = 56.3402
+	coreData.curBearing.SetAngleDeg(90.0)
+	handler.HandleEvent(Event(eventPositionUpdate))
+	if shipControl.speed != "fwd30" {
+		t.Errorf("Expected speed to be fwd30, got %s", shipControl.speed)
+	}
+	if shipControl.steering != "right40" {
+		t.Errorf("Expected steering to be right40, got %s", shipControl.steering)
+	}
+
+	// heading west, turn left
+	coreData.curBearing.SetAngleDeg(-90.0)
+	handler.HandleEvent(Event(eventBearingUpdate))
+	if shipControl.steering != "left40" {
+		t.Errorf("Expected steering to be left40, got %s", shipControl.steering)
+	}
+
+	// heading to the loiter point, move straight
+	coreData.curBearing.SetAngleDeg(180.0)
+	handler.HandleEvent(Event(eventBearingUpdate))
+	if shipControl.speed != "fwd20" {
+		t.Errorf("Expected speed to be fwd20, got %s", shipControl.speed)
+	}
+	if shipControl.steering != "straight" {
+		t.Errorf("Expected steering to be straight, got %s", shipControl.steering)
+	}
+
+	// correction continues inside the loiter radius until the ship is close to the loiter point
+	coreData.position.Latitude = 56.34007
+	handler.HandleEvent(Event(eventPositionUpdate))
+	if shipControl.speed != "fwd20" {
+		t.Errorf("Expected speed to be fwd20, got %s", shipControl.speed)
+	}
+
+	coreData.position.Latitude = 56.34003
+	handler.HandleEvent(Event(eventPositionUpdate))
+	if shipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop at the loiter point, got %s", shipControl.speed)
+	}
+}
+
+func TestLoiteringDefaultSpeed(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  56.3402,
+			Longitude: 43.99,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		loiterPoint: &model.Waypoint{
+			Latitude:  56.34,
+			Longitude: 43.99,
+		},
+	}
+	coreData.curBearing.SetAngleDeg(180.0)
+	shipControl := &mockShipControl{}
+
+	// turning speed is used if the loiter speed is not configured
+	handler := newLoiteringHandler(&logger, coreData, shipControl, model.Forward(30), model.Left(40),
+		model.Right(40), model.SpeedStop, 10.0)
+	handler.OnEnter()
+	handler.HandleEvent(Event(eventPositionUpdate))
+	if shipControl.speed != "fwd30" {
+		t.Errorf("Expected speed to be fwd30, got %s", shipControl.speed)
+	}
+}
+
+func TestLoiteringEvents(t *testing.T) {
+	tests := []struct {
+		event        Event
+		homeWaypoint *model.Waypoint
+		waypoints    bool
+		transition   string
+	}{
+		{eventNavStop, nil, false, "nav stop"},
+		{eventNavStart, nil, false, ""},
+		{eventNavStart, nil, true, "nav start"},
+		{eventNetLoss, nil, false, ""},
+		{eventNetLoss, &model.Waypoint{Latitude: 56.33, Longitude: 43.98}, false, "net loss home"},
+		{eventFixLost, nil, false, "fix lost"},
+		{eventSensorStale, nil, false, "sensor stale"},
+		{eventPositionLost, nil, false, "position lost"},
+		{eventShipControlLost, nil, false, "ship control lost"},
+		{eventShipCommandFailed, nil, false, "command failed"},
+		{eventWaypointsCleared, nil, false, ""},
+	}
+
+	for _, test := range tests {
+		waypoints := model.NewWaypoints()
+		if test.waypoints {
+			waypoints.AddWaypoint(&model.Waypoint{Latitude: 56.33, Longitude: 43.98})
+		}
+		coreData := &coreData{
+			position: &model.Position{
+				Latitude:  56.34,
+				Longitude: 43.99,
+			},
+			curBearing:    model.NewBearing(0.0),
+			targetBearing: model.NewBearing(0.0),
+			homeWaypoint:  test.homeWaypoint,
+			waypoints:     waypoints,
+		}
+
+		handler := newLoiteringTestHandler(coreData, &mockShipControl{})
+		handler.OnEnter()
+
+		transition := handler.HandleEvent(test.event)
+		if transition != test.transition {
+			t.Errorf("Expected %q transition on %s, got %q", test.transition, test.event, transition)
+		}
+	}
+}
+
+func TestLoiteringNewLoiterPoint(t *testing.T) {
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  56.34,
+			Longitude: 43.99,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+	}
+	shipControl := &mockShipControl{}
+
+	handler := newLoiteringTestHandler(coreData, shipControl)
+	handler.OnEnter()
+
+	// new loiter point to the north, the ship is heading north
+	coreData.loiterPoint = &model.Waypoint{
+		Latitude:  56.3405,
+		Longitude: 43.99,
+	}
+	handler.HandleEvent(Event(eventHoldPosition))
+	if shipControl.speed != "fwd20" {
+		t.Errorf("Expected speed to be fwd20, got %s", shipControl.speed)
+	}
+
+	// hold position without a loiter point holds the current position
+	coreData.loiterPoint = nil
+	handler.HandleEvent(Event(eventHoldPosition))
+	if coreData.loiterPoint == nil || coreData.loiterPoint.Latitude != 56.34 {
+		t.Errorf("Expected current position to be the loiter point, got %v", coreData.loiterPoint)
+	}
+}
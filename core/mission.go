@@ -0,0 +1,36 @@
+package core
+
+import (
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+// transition after the last waypoint of the route is reached according to the mission end action,
+// empty transition if the route is repeated
+func missionEndTransition(logger *zerolog.Logger, coreData *coreData) string {
+	missionEnd := coreData.missionEnd
+	if missionEnd.Repeats(coreData.waypoints.Len(), coreData.repeated) {
+		coreData.repeated++
+		if missionEnd.Action == model.MissionEndLoop {
+			coreData.waypoints.Restart()
+		} else {
+			coreData.waypoints.Reverse()
+		}
+		logger.Info().Msgf("mission end %s, repeated %d times", missionEnd.Action, coreData.repeated)
+		return ""
+	}
+
+	switch missionEnd.Action {
+	case model.MissionEndLoiter:
+		coreData.loiterPoint = coreData.waypoints.GetPreviousWaypoint()
+		logger.Info().Msg("mission end, holding position at the last waypoint")
+		return "mission loiter"
+	case model.MissionEndReturnHome:
+		if coreData.homeWaypoint != nil {
+			logger.Info().Msg("mission end, returning home")
+			return "mission home"
+		}
+		logger.Warn().Msg("mission end, home waypoint is not set")
+	}
+	return "last waypoint"
+}
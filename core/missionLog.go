@@ -0,0 +1,59 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+const defaultMissionLogSize = 100
+
+// waypoint actions reported to the clients, the oldest events are dropped when the log is full,
+// the log is written by the core and read by the adapters
+type missionLog struct {
+	mutex  sync.Mutex
+	size   int
+	seq    uint64
+	events []*model.MissionEvent
+}
+
+func newMissionLog(size int) *missionLog {
+	if size <= 0 {
+		size = defaultMissionLogSize
+	}
+	return &missionLog{
+		size:   size,
+		events: make([]*model.MissionEvent, 0, size),
+	}
+}
+
+func (l *missionLog) add(index int, waypoint *model.Waypoint, now time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.seq++
+	if len(l.events) == l.size {
+		l.events = append(l.events[:0], l.events[1:]...)
+	}
+	l.events = append(l.events, &model.MissionEvent{
+		Seq:           l.seq,
+		Timestamp:     now,
+		WaypointIndex: index,
+		Latitude:      waypoint.Latitude,
+		Longitude:     waypoint.Longitude,
+		Action:        waypoint.Action,
+	})
+}
+
+func (l *missionLog) snapshot() []*model.MissionEvent {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	events := make([]*model.MissionEvent, len(l.events))
+	for i, event := range l.events {
+		eventCopy := *event
+		events[i] = &eventCopy
+	}
+	return events
+}
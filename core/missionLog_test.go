@@ -0,0 +1,39 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+func TestMissionLog(t *testing.T) {
+	log := newMissionLog(2)
+	now := time.Now()
+
+	if len(log.snapshot()) != 0 {
+		t.Errorf("Expected empty mission log")
+	}
+
+	log.add(0, &model.Waypoint{Latitude: 56.0, Longitude: 44.0, Action: "sample"}, now)
+	log.add(1, &model.Waypoint{Latitude: 56.1, Longitude: 44.1, Action: "photo"}, now)
+	log.add(2, &model.Waypoint{Latitude: 56.2, Longitude: 44.2, Action: "sample"}, now)
+
+	events := log.snapshot()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	// the oldest event is dropped
+	if events[0].Seq != 2 || events[0].WaypointIndex != 1 || events[0].Action != "photo" {
+		t.Errorf("Unexpected first event %+v", events[0])
+	}
+	if events[1].Seq != 3 || events[1].WaypointIndex != 2 || events[1].Latitude != 56.2 {
+		t.Errorf("Unexpected second event %+v", events[1])
+	}
+
+	// snapshot is not affected by the changes of the log
+	log.add(3, &model.Waypoint{Action: "photo"}, now)
+	if events[1].Seq != 3 {
+		t.Errorf("Expected snapshot not to change")
+	}
+}
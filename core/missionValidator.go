@@ -0,0 +1,117 @@
+package core
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+// checks the route before it is accepted or navigation is started
+type missionValidator struct {
+	// zero disables the limit
+	maxLegLength float64
+	// nil if the geofence is not configured
+	geofence *geofence
+	geodesic model.Geodesic
+}
+
+func newMissionValidator(maxLegLength float64, geofence *geofence, geodesic model.Geodesic) *missionValidator {
+	return &missionValidator{
+		maxLegLength: maxLegLength,
+		geofence:     geofence,
+		geodesic:     geodesic,
+	}
+}
+
+// all problems found in the route, empty if the route can be navigated,
+// the first leg is checked from the start if it is not nil
+func (v *missionValidator) validate(start *model.Waypoint, waypoints []*model.Waypoint,
+	home *model.Waypoint) []*model.MissionProblem {
+	problems := []*model.MissionProblem{}
+	if len(waypoints) == 0 {
+		return append(problems, &model.MissionProblem{
+			WaypointIndex: -1,
+			Code:          model.MissionProblemEmptyRoute,
+			Message:       "no waypoints provided",
+		})
+	}
+
+	previous := start
+	for i, waypoint := range waypoints {
+		if !validCoordinates(waypoint) {
+			problems = append(problems, &model.MissionProblem{
+				WaypointIndex: i,
+				Code:          model.MissionProblemInvalidCoordinates,
+				Message:       invalidCoordinatesMessage(waypoint),
+			})
+			// the legs to and from the waypoint can not be measured
+			previous = nil
+			continue
+		}
+
+		if v.geofence != nil {
+			if reason := v.geofence.checkWaypoint(waypoint, home, v.geodesic); reason != "" {
+				problems = append(problems, &model.MissionProblem{
+					WaypointIndex: i,
+					Code:          model.MissionProblemOutsideGeofence,
+					Message:       reason,
+				})
+			}
+		}
+
+		if v.maxLegLength > 0 && previous != nil {
+			length := v.geodesic.Distance(previous.Latitude, previous.Longitude, waypoint.Latitude,
+				waypoint.Longitude)
+			if length > v.maxLegLength {
+				problems = append(problems, &model.MissionProblem{
+					WaypointIndex: i,
+					Code:          model.MissionProblemLegTooLong,
+					Message: fmt.Sprintf("leg of %.1f meters to the waypoint exceeds %.1f meters",
+						length, v.maxLegLength),
+				})
+			}
+		}
+		previous = waypoint
+	}
+
+	return problems
+}
+
+// validates the remaining route from the current position, the problems are sent back
+// to the caller of nav start, the position is not used before it is received or while
+// the navigation is paused
+func validateNavStart(logger *zerolog.Logger, coreData *coreData) bool {
+	if coreData.validator == nil {
+		return true
+	}
+
+	var start *model.Waypoint
+	if coreData.positionReceived && !coreData.navigationPaused() {
+		start = coreData.position.Waypoint()
+	}
+	problems := coreData.validator.validate(start, coreData.waypoints.GetRemainingWaypoints(),
+		coreData.homeWaypoint)
+	if len(problems) > 0 {
+		logger.Warn().Msgf("nav start rejected, %d problems found", len(problems))
+		coreData.missionProblems = problems
+		return false
+	}
+	return true
+}
+
+func validCoordinates(waypoint *model.Waypoint) bool {
+	if waypoint == nil {
+		return false
+	}
+	return !math.IsNaN(waypoint.Latitude) && !math.IsNaN(waypoint.Longitude) &&
+		math.Abs(waypoint.Latitude) <= 90 && math.Abs(waypoint.Longitude) <= 180
+}
+
+func invalidCoordinatesMessage(waypoint *model.Waypoint) string {
+	if waypoint == nil {
+		return "waypoint is not defined"
+	}
+	return fmt.Sprintf("latitude %f or longitude %f is out of range", waypoint.Latitude, waypoint.Longitude)
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+func TestMissionValidatorEmptyRoute(t *testing.T) {
+	validator := newMissionValidator(0.0, nil, model.GeodesicHaversine)
+
+	problems := validator.validate(nil, nil, nil)
+	if len(problems) != 1 {
+		t.Fatalf("Expected 1 problem, got %d", len(problems))
+	}
+	if problems[0].Code != model.MissionProblemEmptyRoute || problems[0].WaypointIndex != -1 {
+		t.Errorf("Expected empty route problem, got %v", problems[0])
+	}
+}
+
+func TestMissionValidator(t *testing.T) {
+	validator := newMissionValidator(1000.0, newGeofence(geofenceTestArea(t), 0.0),
+		model.GeodesicHaversine)
+
+	problems := validator.validate(nil, []*model.Waypoint{
+		{Latitude: 56.405, Longitude: 43.84},
+		{Latitude: 56.410, Longitude: 43.85},
+		nil,
+		{Latitude: 96.410, Longitude: 43.85},
+		// about 2200 meters from the previous waypoint
+		{Latitude: 56.415, Longitude: 43.85},
+		{Latitude: 56.435, Longitude: 43.85},
+	}, nil)
+
+	expected := []struct {
+		index int
+		code  string
+	}{
+		{2, model.MissionProblemInvalidCoordinates},
+		{3, model.MissionProblemInvalidCoordinates},
+		{5, model.MissionProblemOutsideGeofence},
+		{5, model.MissionProblemLegTooLong},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d", len(expected), len(problems))
+	}
+	for i, problem := range problems {
+		if problem.WaypointIndex != expected[i].index || problem.Code != expected[i].code {
+			t.Errorf("Expected problem %d to be %s at waypoint %d, got %s at waypoint %d", i,
+				expected[i].code, expected[i].index, problem.Code, problem.WaypointIndex)
+		}
+		if problem.Message == "" {
+			t.Errorf("Expected problem %d to have a message", i)
+		}
+	}
+
+	// the distance from home is checked once the home waypoint is set
+	validator = newMissionValidator(0.0, newGeofence(nil, 500.0), model.GeodesicHaversine)
+	waypoints := []*model.Waypoint{
+		{Latitude: 56.410, Longitude: 43.85},
+	}
+	if problems := validator.validate(nil, waypoints, nil); len(problems) != 0 {
+		t.Errorf("Expected no problems without home waypoint, got %d", len(problems))
+	}
+	problems = validator.validate(nil, waypoints, &model.Waypoint{Latitude: 56.400, Longitude: 43.85})
+	if len(problems) != 1 || problems[0].Code != model.MissionProblemOutsideGeofence {
+		t.Errorf("Expected waypoint to be too far from home, got %v", problems)
+	}
+}
+
+func TestMissionValidatorFirstLeg(t *testing.T) {
+	validator := newMissionValidator(1000.0, nil, model.GeodesicHaversine)
+	waypoints := []*model.Waypoint{
+		{Latitude: 56.410, Longitude: 43.85},
+	}
+
+	// about 2200 meters from the start
+	start := &model.Waypoint{Latitude: 56.430, Longitude: 43.85}
+	problems := validator.validate(start, waypoints, nil)
+	if len(problems) != 1 || problems[0].Code != model.MissionProblemLegTooLong ||
+		problems[0].WaypointIndex != 0 {
+		t.Errorf("Expected first leg to be too long, got %v", problems)
+	}
+	if problems := validator.validate(nil, waypoints, nil); len(problems) != 0 {
+		t.Errorf("Expected no problems without start, got %d", len(problems))
+	}
+}
+
+func TestCoreMissionValidation(t *testing.T) {
+	mockCoreConfigurer := &mockCoreConfigurer{
+		maxLegLength: 1000.0,
+	}
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+
+	core := NewCore(mockCoreConfigurer, &mockShipControl{}, &logger)
+	go core.Run()
+	defer core.Stop()
+
+	problems := core.StartNavigation()
+	if len(problems) != 1 || problems[0].Code != model.MissionProblemEmptyRoute {
+		t.Errorf("Expected empty route to be reported, got %v", problems)
+	}
+	if core.fsm.CurrentState() != "idle" {
+		t.Errorf("Expected core state to be idle, got %s", core.fsm.CurrentState())
+	}
+
+	problems = core.SetWaypoints([]*model.Waypoint{
+		{Latitude: 56.410, Longitude: 43.85},
+		{Latitude: 56.430, Longitude: 43.85},
+	})
+	if len(problems) != 1 || problems[0].Code != model.MissionProblemLegTooLong {
+		t.Errorf("Expected leg to be too long, got %v", problems)
+	}
+	if len(core.GetWaypoints()) != 0 {
+		t.Error("Expected waypoints not to be set")
+	}
+
+	problems = core.SetWaypoints([]*model.Waypoint{
+		{Latitude: 56.410, Longitude: 43.85},
+	})
+	if len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+
+	// the first leg is not checked before the position is received
+	problems = core.StartNavigation()
+	if len(problems) != 0 {
+		t.Errorf("Expected no problems before the first position, got %v", problems)
+	}
+	core.StopNavigation()
+	time.Sleep(10 * time.Millisecond)
+
+	// the first leg from the current position is too long
+	core.UpdatePosition(&model.Position{
+		Latitude:  56.430,
+		Longitude: 43.85,
+	})
+	time.Sleep(10 * time.Millisecond)
+	problems = core.StartNavigation()
+	if len(problems) != 1 || problems[0].Code != model.MissionProblemLegTooLong {
+		t.Errorf("Expected first leg to be too long, got %v", problems)
+	}
+	if core.fsm.CurrentState() != "idle" {
+		t.Errorf("Expected core state to be idle, got %s", core.fsm.CurrentState())
+	}
+
+	core.UpdatePosition(&model.Position{
+		Latitude:  56.415,
+		Longitude: 43.85,
+	})
+	time.Sleep(10 * time.Millisecond)
+	problems = core.StartNavigation()
+	if len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if core.fsm.CurrentState() != "turning" {
+		t.Errorf("Expected core state to be turning, got %s", core.fsm.CurrentState())
+	}
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+func newMissionTestData(missionEnd model.MissionEnd) *coreData {
+	waypoints := model.NewWaypoints()
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.33956,
+		Longitude: 43.98449,
+	})
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.333015,
+		Longitude: 44.007853,
+	})
+
+	return &coreData{
+		position: &model.Position{
+			Latitude:  56.34,
+			Longitude: 43.99394,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		waypoints:     waypoints,
+		missionEnd:    missionEnd,
+	}
+}
+
+// moves the ship through the route and returns the transition at the last waypoint
+func finishRoute(handler *movingHandler, coreData *coreData) string {
+	transition := ""
+	for i := 0; i < 2; i++ {
+		waypoint := coreData.waypoints.GetNextWaypoint()
+		handler.OnEnter()
+		coreData.position.Latitude = waypoint.Latitude
+		coreData.position.Longitude = waypoint.Longitude
+		transition = handler.HandleEvent(Event(eventPositionUpdate))
+	}
+	return transition
+}
+
+func TestMissionEndStop(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	tests := []model.MissionEnd{
+		{Action: model.MissionEndStop},
+		// home waypoint is not set
+		{Action: model.MissionEndReturnHome},
+	}
+
+	for _, missionEnd := range tests {
+		coreData := newMissionTestData(missionEnd)
+		handler := newMovingHandler(&logger, coreData, &mockShipControl{},
+			model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
+
+		transition := finishRoute(handler, coreData)
+		if transition != "last waypoint" {
+			t.Errorf("Expected last waypoint transition for %s, got %s", missionEnd.Action, transition)
+		}
+	}
+}
+
+func TestMissionEndReturnHome(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := newMissionTestData(model.MissionEnd{Action: model.MissionEndReturnHome})
+	coreData.homeWaypoint = &model.Waypoint{
+		Latitude:  56.34,
+		Longitude: 43.99394,
+	}
+	handler := newMovingHandler(&logger, coreData, &mockShipControl{},
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
+
+	transition := finishRoute(handler, coreData)
+	if transition != "mission home" {
+		t.Errorf("Expected mission home transition, got %s", transition)
+	}
+}
+
+func TestMissionEndLoiter(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := newMissionTestData(model.MissionEnd{Action: model.MissionEndLoiter})
+	handler := newMovingHandler(&logger, coreData, &mockShipControl{},
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
+
+	transition := finishRoute(handler, coreData)
+	if transition != "mission loiter" {
+		t.Errorf("Expected mission loiter transition, got %s", transition)
+	}
+	if coreData.loiterPoint == nil || coreData.loiterPoint.Latitude != 56.333015 {
+		t.Errorf("Expected the last waypoint to be the loiter point, got %v", coreData.loiterPoint)
+	}
+}
+
+func TestMissionEndLoop(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := newMissionTestData(model.MissionEnd{Action: model.MissionEndLoop, Repeat: 2})
+	handler := newMovingHandler(&logger, coreData, &mockShipControl{},
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
+
+	for i := 0; i < 2; i++ {
+		transition := finishRoute(handler, coreData)
+		if transition != "waypoint" {
+			t.Errorf("Expected waypoint transition on lap %d, got %s", i, transition)
+		}
+		if coreData.waypoints.GetNextWaypoint().Latitude != 56.33956 {
+			t.Errorf("Expected route to restart from the first waypoint on lap %d", i)
+		}
+	}
+
+	transition := finishRoute(handler, coreData)
+	if transition != "last waypoint" {
+		t.Errorf("Expected last waypoint transition, got %s", transition)
+	}
+}
+
+func TestMissionEndReverse(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	// reversed forever
+	coreData := newMissionTestData(model.MissionEnd{Action: model.MissionEndReverse})
+	handler := newMovingHandler(&logger, coreData, &mockShipControl{},
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
+
+	transition := finishRoute(handler, coreData)
+	if transition != "waypoint" {
+		t.Errorf("Expected waypoint transition, got %s", transition)
+	}
+	if coreData.waypoints.GetNextWaypoint().Latitude != 56.33956 {
+		t.Errorf("Expected route back to the first waypoint, got %f",
+			coreData.waypoints.GetNextWaypoint().Latitude)
+	}
+
+	handler.OnEnter()
+	coreData.position.Latitude = 56.33956
+	coreData.position.Longitude = 43.98449
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "waypoint" {
+		t.Errorf("Expected waypoint transition, got %s", transition)
+	}
+	if coreData.waypoints.GetNextWaypoint().Latitude != 56.333015 {
+		t.Errorf("Expected route to be reversed again, got %f",
+			coreData.waypoints.GetNextWaypoint().Latitude)
+	}
+	if coreData.repeated != 2 {
+		t.Errorf("Expected route to be repeated 2 times, got %d", coreData.repeated)
+	}
+}
+
+func TestMissionEndAfterWaypointHold(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := newMissionTestData(model.MissionEnd{Action: model.MissionEndLoop})
+	coreData.waypoints.WaypointReached()
+	coreData.waypoints.WaypointReached()
+
+	handler := newWaypointHoldHandler(&logger, coreData, &mockShipControl{})
+	handler.OnEnter()
+
+	transition := handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "hold done" {
+		t.Errorf("Expected hold done transition, got %s", transition)
+	}
+	if coreData.waypoints.GetNextWaypoint().Latitude != 56.33956 {
+		t.Errorf("Expected route to restart from the first waypoint")
+	}
+}
@@ -0,0 +1,47 @@
+package model
+
+import "math"
+
+// angle in degrees clockwise from North, arithmetic on angles has to take the wrap-around
+// at North into account, 179 and -179 degrees are 2 degrees apart
+type Angle float64
+
+func AngleFromRad(rad float64) Angle {
+	return Angle(rad * 180 / math.Pi)
+}
+
+func (a Angle) Deg() float64 {
+	return float64(a)
+}
+
+func (a Angle) Rad() float64 {
+	return float64(a) * math.Pi / 180
+}
+
+// equivalent angle in [0, 360)
+func (a Angle) Normalized() Angle {
+	n := math.Mod(float64(a), 360)
+	if n < 0 {
+		n += 360
+	}
+	// -tiny + 360 rounds to 360
+	if n >= 360 {
+		n = 0
+	}
+	return Angle(n)
+}
+
+// equivalent angle in (-180, 180]
+func (a Angle) Signed() Angle {
+	n := a.Normalized()
+	if n > 180 {
+		n -= 360
+	}
+	return n
+}
+
+// shortest signed rotation from the current angle to the target angle in (-180, 180],
+// positive is clockwise
+func AngleDiff(target Angle, current Angle) Angle {
+	return (target - current).Signed()
+}
@@ -0,0 +1,131 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"testing/quick"
+)
+
+const angleTolerance = 1e-9
+
+// angles up to a few full turns, larger values lose precision in the normalization
+func quickAngle(deg float64) Angle {
+	return Angle(math.Mod(deg, 3600))
+}
+
+func sameDirection(a, b Angle) bool {
+	d := math.Abs((a - b).Normalized().Deg())
+	return d < angleTolerance || 360-d < angleTolerance
+}
+
+func TestAngleNormalized(t *testing.T) {
+	tests := []struct {
+		angle      Angle
+		normalized Angle
+		signed     Angle
+	}{
+		{0, 0, 0},
+		{360, 0, 0},
+		{-90, 270, -90},
+		{180, 180, 180},
+		{-180, 180, 180},
+		{540, 180, 180},
+		{-181, 179, 179},
+		{725, 5, 5},
+		{359.5, 359.5, -0.5},
+	}
+
+	for _, test := range tests {
+		if math.Abs((test.angle.Normalized() - test.normalized).Deg()) > angleTolerance {
+			t.Errorf("Expected %f to be normalized to %f, got %f", test.angle, test.normalized,
+				test.angle.Normalized())
+		}
+		if math.Abs((test.angle.Signed() - test.signed).Deg()) > angleTolerance {
+			t.Errorf("Expected %f to be signed %f, got %f", test.angle, test.signed, test.angle.Signed())
+		}
+	}
+}
+
+func TestAngleDiff(t *testing.T) {
+	tests := []struct {
+		target  Angle
+		current Angle
+		diff    Angle
+	}{
+		{10, 0, 10},
+		{0, 10, -10},
+		{-179, 179, 2},
+		{179, -179, -2},
+		{1, 359, 2},
+		{359, 1, -2},
+		{180, 0, 180},
+		{0, 180, 180},
+		{90, 270, 180},
+	}
+
+	for _, test := range tests {
+		diff := AngleDiff(test.target, test.current)
+		if math.Abs((diff - test.diff).Deg()) > angleTolerance {
+			t.Errorf("Expected difference from %f to %f to be %f, got %f", test.current, test.target,
+				test.diff, diff)
+		}
+	}
+}
+
+func TestAngleProperties(t *testing.T) {
+	normalizedRange := func(deg float64) bool {
+		n := quickAngle(deg).Normalized()
+		return n >= 0 && n < 360
+	}
+	signedRange := func(deg float64) bool {
+		s := quickAngle(deg).Signed()
+		return s > -180 && s <= 180
+	}
+	idempotent := func(deg float64) bool {
+		n := quickAngle(deg).Normalized()
+		return n.Normalized() == n && n.Signed().Normalized() == n
+	}
+	sameAngle := func(deg float64) bool {
+		a := quickAngle(deg)
+		return sameDirection(a.Normalized(), a) && sameDirection(a.Signed(), a)
+	}
+	fullTurns := func(deg float64, turns int8) bool {
+		a := quickAngle(deg)
+		return sameDirection((a + Angle(360*float64(turns))).Normalized(), a.Normalized())
+	}
+	diffRange := func(target, current float64) bool {
+		d := AngleDiff(quickAngle(target), quickAngle(current))
+		return d > -180 && d <= 180
+	}
+	diffReachesTarget := func(target, current float64) bool {
+		d := AngleDiff(quickAngle(target), quickAngle(current))
+		return sameDirection(quickAngle(current)+d, quickAngle(target))
+	}
+	diffAntisymmetric := func(target, current float64) bool {
+		d := AngleDiff(quickAngle(target), quickAngle(current))
+		r := AngleDiff(quickAngle(current), quickAngle(target))
+		// opposite directions are 180 degrees apart both ways
+		return math.Abs((d+r).Deg()) < angleTolerance || math.Abs(d.Deg()-180) < angleTolerance
+	}
+	radians := func(deg float64) bool {
+		a := quickAngle(deg)
+		return math.Abs((AngleFromRad(a.Rad()) - a).Deg()) < angleTolerance
+	}
+
+	properties := map[string]interface{}{
+		"normalized range":   normalizedRange,
+		"signed range":       signedRange,
+		"idempotent":         idempotent,
+		"same angle":         sameAngle,
+		"full turns":         fullTurns,
+		"diff range":         diffRange,
+		"diff reaches":       diffReachesTarget,
+		"diff antisymmetric": diffAntisymmetric,
+		"radians":            radians,
+	}
+	for name, property := range properties {
+		if err := quick.Check(property, nil); err != nil {
+			t.Errorf("Property %s does not hold: %s", name, err.Error())
+		}
+	}
+}
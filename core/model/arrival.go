@@ -0,0 +1,53 @@
+package model
+
+import "fmt"
+
+// criterion used to decide that a waypoint is reached, the arrival radius is always checked
+type ArrivalMode uint8
+
+const (
+	// use the configured arrival mode
+	ArrivalModeDefault ArrivalMode = iota
+	// the ship is inside the arrival radius
+	ArrivalModeRadius
+	// the ship crossed the line through the waypoint perpendicular to the leg
+	ArrivalModeLine
+	// the ship passed the closest point of approach to the waypoint
+	ArrivalModeClosestApproach
+)
+
+func ParseArrivalMode(name string) (ArrivalMode, error) {
+	switch name {
+	case "":
+		return ArrivalModeDefault, nil
+	case "radius":
+		return ArrivalModeRadius, nil
+	case "line":
+		return ArrivalModeLine, nil
+	case "cpa":
+		return ArrivalModeClosestApproach, nil
+	default:
+		return ArrivalModeDefault, fmt.Errorf("unknown arrival mode %s", name)
+	}
+}
+
+func (m ArrivalMode) String() string {
+	switch m {
+	case ArrivalModeRadius:
+		return "radius"
+	case ArrivalModeLine:
+		return "line"
+	case ArrivalModeClosestApproach:
+		return "cpa"
+	default:
+		return ""
+	}
+}
+
+// arrival mode of the waypoint, the default mode if the waypoint does not specify it
+func (w *Waypoint) ArrivalModeOr(defaultMode ArrivalMode) ArrivalMode {
+	if w == nil || w.Arrival == ArrivalModeDefault {
+		return defaultMode
+	}
+	return w.Arrival
+}
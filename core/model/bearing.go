@@ -2,16 +2,20 @@ package model
 
 import (
 	"math"
+	"time"
 )
 
 type Bearing struct {
 	angle       float64
 	declination float64
+	// time the sensor data was received from the position service
+	Timestamp time.Time
 }
 
+// declination is in degrees, it is added to magnetic sensor readings to get the true bearing
 func NewBearing(declination float64) *Bearing {
 	bearing := &Bearing{
-		declination: declination,
+		declination: declination * math.Pi / 180,
 	}
 
 	return bearing
@@ -19,19 +23,52 @@ func NewBearing(declination float64) *Bearing {
 
 // update bearing with new sensor data
 func (b *Bearing) SetInt(x, y int32) {
-	b.angle = math.Atan2(float64(y), float64(x)) + b.declination
+	b.SetFloat(float64(x), float64(y))
 }
 
 func (b *Bearing) SetFloat(x, y float64) {
-	b.angle = math.Atan2(y, x) + b.declination
+	b.angle = AngleFromRad(math.Atan2(y, x) + b.declination).Signed().Rad()
 }
 
-// bearing angle from North in radians
+// update bearing with magnetometer readings compensated for the hull roll and pitch
+// calculated from accelerometer readings, falls back to the 2-axis calculation
+// if the acceleration vector is not available
+func (b *Bearing) SetTiltCompensated(mx, my, mz, ax, ay, az float64) {
+	if ax == 0 && ay == 0 && az == 0 {
+		b.SetFloat(mx, my)
+		return
+	}
+
+	sinRoll, cosRoll := math.Sincos(math.Atan2(ay, az))
+	sinPitch, cosPitch := math.Sincos(math.Atan2(-ax, ay*sinRoll+az*cosRoll))
+
+	x := mx*cosPitch + (my*sinRoll+mz*cosRoll)*sinPitch
+	y := my*cosRoll - mz*sinRoll
+	b.SetFloat(x, y)
+}
+
+// set true bearing from North in degrees, declination is not applied
+func (b *Bearing) SetAngleDeg(deg float64) {
+	b.angle = 0
+	b.RotateDeg(deg)
+}
+
+func (b *Bearing) RotateDeg(deg float64) {
+	angle := b.angle + deg*math.Pi/180
+	b.angle = math.Atan2(math.Sin(angle), math.Cos(angle))
+}
+
+// bearing angle from North in radians, (-pi, pi]
 func (b *Bearing) Angle() float64 {
 	return b.angle
 }
 
-// bearing angle from North in degrees
+// bearing angle from North in degrees, (-180, 180]
 func (b *Bearing) AngleDeg() float64 {
 	return b.angle * (180 / math.Pi)
 }
+
+// bearing angle from North in [0, 360)
+func (b *Bearing) Heading() Angle {
+	return AngleFromRad(b.angle).Normalized()
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBearingDeclination(t *testing.T) {
+	bearing := NewBearing(13.62)
+	bearing.SetInt(1, 0)
+
+	tolerance := 0.000001
+	if math.Abs(bearing.AngleDeg()-13.62) > tolerance {
+		t.Errorf("Expected bearing to be 13.62, got %f", bearing.AngleDeg())
+	}
+
+	// true bearing is not corrected with declination
+	bearing.SetAngleDeg(-94.8)
+	if math.Abs(bearing.AngleDeg()-(-94.8)) > tolerance {
+		t.Errorf("Expected bearing to be -94.8, got %f", bearing.AngleDeg())
+	}
+
+	bearing.RotateDeg(-90)
+	if math.Abs(bearing.AngleDeg()-175.2) > tolerance {
+		t.Errorf("Expected bearing to be 175.2, got %f", bearing.AngleDeg())
+	}
+}
+
+func TestBearingWrapAround(t *testing.T) {
+	bearing := NewBearing(20.0)
+	// magnetic bearing 170 degrees
+	bearing.SetFloat(math.Cos(170*math.Pi/180), math.Sin(170*math.Pi/180))
+
+	tolerance := 0.000001
+	if math.Abs(bearing.AngleDeg()-(-170.0)) > tolerance {
+		t.Errorf("Expected bearing to be -170.0, got %f", bearing.AngleDeg())
+	}
+	if math.Abs(bearing.Heading().Deg()-190.0) > tolerance {
+		t.Errorf("Expected heading to be 190.0, got %f", bearing.Heading().Deg())
+	}
+
+	bearing.SetAngleDeg(-0.5)
+	if math.Abs(bearing.Heading().Deg()-359.5) > tolerance {
+		t.Errorf("Expected heading to be 359.5, got %f", bearing.Heading().Deg())
+	}
+}
+
+func TestBearingTiltCompensated(t *testing.T) {
+	// magnetic field pointing 30 degrees from the sensor X axis with downward inclination
+	heading := 30.0 * math.Pi / 180
+	field := [3]float64{math.Cos(heading), math.Sin(heading), 1.5}
+	gravity := [3]float64{0, 0, 1}
+
+	level := NewBearing(0.0)
+	level.SetFloat(field[0], field[1])
+
+	tolerance := 0.000001
+	tests := []struct {
+		roll  float64
+		pitch float64
+	}{
+		{0, 0},
+		{15, 0},
+		{0, -20},
+		{-25, 10},
+		{30, 30},
+	}
+	for _, test := range tests {
+		roll := test.roll * math.Pi / 180
+		pitch := test.pitch * math.Pi / 180
+		m := rotateToSensor(field, roll, pitch)
+		a := rotateToSensor(gravity, roll, pitch)
+
+		bearing := NewBearing(0.0)
+		bearing.SetTiltCompensated(m[0], m[1], m[2], a[0], a[1], a[2])
+		if math.Abs(bearing.AngleDeg()-level.AngleDeg()) > tolerance {
+			t.Errorf("Expected tilt compensated bearing with roll %f, pitch %f to be %f, got %f",
+				test.roll, test.pitch, level.AngleDeg(), bearing.AngleDeg())
+		}
+
+		uncompensated := NewBearing(0.0)
+		uncompensated.SetFloat(m[0], m[1])
+		if (test.roll != 0 || test.pitch != 0) &&
+			math.Abs(uncompensated.AngleDeg()-level.AngleDeg()) < 1 {
+			t.Errorf("Expected 2-axis bearing with roll %f, pitch %f to be distorted", test.roll, test.pitch)
+		}
+	}
+
+	// no accelerometer data
+	bearing := NewBearing(0.0)
+	bearing.SetTiltCompensated(field[0], field[1], field[2], 0, 0, 0)
+	if math.Abs(bearing.AngleDeg()-level.AngleDeg()) > tolerance {
+		t.Errorf("Expected fallback bearing to be %f, got %f", level.AngleDeg(), bearing.AngleDeg())
+	}
+}
+
+// express a vector given in the level frame in the frame of a sensor rotated by roll around X
+// and then by pitch around Y
+func rotateToSensor(v [3]float64, roll, pitch float64) [3]float64 {
+	sinRoll, cosRoll := math.Sincos(roll)
+	sinPitch, cosPitch := math.Sincos(pitch)
+
+	// inverse pitch rotation
+	x := v[0]*cosPitch - v[2]*sinPitch
+	y := v[1]
+	z := v[0]*sinPitch + v[2]*cosPitch
+
+	// inverse roll rotation
+	return [3]float64{x, y*cosRoll + z*sinRoll, -y*sinRoll + z*cosRoll}
+}
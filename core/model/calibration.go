@@ -0,0 +1,213 @@
+package model
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	MinCalibrationSamples = 50
+
+	jacobiMaxSweeps = 50
+)
+
+// hard-iron offset and soft-iron correction matrix for magnetometer readings
+type MagCalibration struct {
+	Offset [3]float64    `json:"offset"`
+	Matrix [3][3]float64 `json:"matrix"`
+}
+
+type CalibrationStatus struct {
+	Active     bool
+	Samples    int
+	Calibrated bool
+	Offset     [3]float64
+	Error      string
+}
+
+func NewMagCalibration() *MagCalibration {
+	return &MagCalibration{
+		Matrix: [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+	}
+}
+
+func (c *MagCalibration) Apply(x, y, z float64) (float64, float64, float64) {
+	if c == nil {
+		return x, y, z
+	}
+
+	v := [3]float64{x - c.Offset[0], y - c.Offset[1], z - c.Offset[2]}
+	var r [3]float64
+	for i := 0; i < 3; i++ {
+		r[i] = c.Matrix[i][0]*v[0] + c.Matrix[i][1]*v[1] + c.Matrix[i][2]*v[2]
+	}
+	return r[0], r[1], r[2]
+}
+
+// fit an ellipsoid to the samples with linear least squares, hard-iron offset is the ellipsoid center
+// and soft-iron correction maps the ellipsoid to a sphere with the geometric mean radius
+func FitMagCalibration(samples [][3]float64) (*MagCalibration, error) {
+	if len(samples) < MinCalibrationSamples {
+		return nil, errors.New("not enough calibration samples")
+	}
+
+	// normalize samples to keep the normal equations well conditioned
+	var mean [3]float64
+	for _, sample := range samples {
+		for k := 0; k < 3; k++ {
+			mean[k] += sample[k] / float64(len(samples))
+		}
+	}
+	scale := 0.0
+	for _, sample := range samples {
+		for k := 0; k < 3; k++ {
+			scale = math.Max(scale, math.Abs(sample[k]-mean[k]))
+		}
+	}
+	if scale == 0 {
+		return nil, errors.New("calibration samples do not cover all axes")
+	}
+
+	// A*x^2 + B*y^2 + C*z^2 + 2D*xy + 2E*xz + 2F*yz + 2G*x + 2H*y + 2I*z = 1
+	var normal [9][9]float64
+	var rhs [9]float64
+	for _, sample := range samples {
+		x := (sample[0] - mean[0]) / scale
+		y := (sample[1] - mean[1]) / scale
+		z := (sample[2] - mean[2]) / scale
+		row := [9]float64{x * x, y * y, z * z, 2 * x * y, 2 * x * z, 2 * y * z, 2 * x, 2 * y, 2 * z}
+		for i := 0; i < 9; i++ {
+			for j := 0; j < 9; j++ {
+				normal[i][j] += row[i] * row[j]
+			}
+			rhs[i] += row[i]
+		}
+	}
+	p, err := solveLinear(normal, rhs)
+	if err != nil {
+		return nil, errors.New("calibration samples do not cover all axes")
+	}
+
+	m := [3][3]float64{{p[0], p[3], p[4]}, {p[3], p[1], p[5]}, {p[4], p[5], p[2]}}
+	g := [3]float64{p[6], p[7], p[8]}
+	values, vectors := symmetricEigen(m)
+
+	// center = -M^-1 * g
+	var center [3]float64
+	for i := 0; i < 3; i++ {
+		if values[i] <= 0 {
+			return nil, errors.New("calibration samples do not fit an ellipsoid")
+		}
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				center[i] -= vectors[i][k] * vectors[j][k] / values[k] * g[j]
+			}
+		}
+	}
+	k := 1.0
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			k += center[i] * m[i][j] * center[j]
+		}
+	}
+
+	// ellipsoid semi-axes are sqrt(k / value)
+	radius := 1.0
+	for i := 0; i < 3; i++ {
+		values[i] /= k
+		radius *= 1 / math.Sqrt(values[i])
+	}
+	radius = math.Cbrt(radius)
+
+	calibration := NewMagCalibration()
+	for i := 0; i < 3; i++ {
+		calibration.Offset[i] = mean[i] + center[i]*scale
+		for j := 0; j < 3; j++ {
+			calibration.Matrix[i][j] = 0
+			for l := 0; l < 3; l++ {
+				calibration.Matrix[i][j] += vectors[i][l] * vectors[j][l] * math.Sqrt(values[l]) * radius
+			}
+		}
+	}
+
+	return calibration, nil
+}
+
+// solve linear equations system with Gaussian elimination and partial pivoting
+func solveLinear(a [9][9]float64, b [9]float64) ([9]float64, error) {
+	n := len(b)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return b, errors.New("singular matrix")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+
+		for row := col + 1; row < n; row++ {
+			factor := a[row][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[row][k] -= factor * a[col][k]
+			}
+			b[row] -= factor * b[col]
+		}
+	}
+
+	var x [9]float64
+	for row := n - 1; row >= 0; row-- {
+		sum := b[row]
+		for k := row + 1; k < n; k++ {
+			sum -= a[row][k] * x[k]
+		}
+		x[row] = sum / a[row][row]
+	}
+	return x, nil
+}
+
+// eigenvalues and eigenvectors (columns) of a symmetric matrix using Jacobi rotations
+func symmetricEigen(m [3][3]float64) ([3]float64, [3][3]float64) {
+	a := m
+	v := [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+	for sweep := 0; sweep < jacobiMaxSweeps; sweep++ {
+		offDiag := math.Abs(a[0][1]) + math.Abs(a[0][2]) + math.Abs(a[1][2])
+		if offDiag < 1e-15 {
+			break
+		}
+		for p := 0; p < 2; p++ {
+			for q := p + 1; q < 3; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := math.Copysign(1, theta) / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < 3; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < 3; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+				for k := 0; k < 3; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	return [3]float64{a[0][0], a[1][1], a[2][2]}, v
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFitMagCalibration(t *testing.T) {
+	offset := [3]float64{120, -340, 55}
+	// soft-iron distortion: scaling along rotated axes
+	distortion := [3][3]float64{
+		{1.2, 0.1, 0.0},
+		{0.1, 0.8, 0.05},
+		{0.0, 0.05, 1.0},
+	}
+	fieldStrength := 500.0
+
+	samples := make([][3]float64, 0)
+	for i := 0; i < 20; i++ {
+		theta := math.Pi * (float64(i) + 0.5) / 20
+		for j := 0; j < 40; j++ {
+			phi := 2 * math.Pi * float64(j) / 40
+			v := [3]float64{math.Sin(theta) * math.Cos(phi), math.Sin(theta) * math.Sin(phi), math.Cos(theta)}
+			var sample [3]float64
+			for k := 0; k < 3; k++ {
+				sample[k] = offset[k] + fieldStrength*(distortion[k][0]*v[0]+distortion[k][1]*v[1]+
+					distortion[k][2]*v[2])
+			}
+			samples = append(samples, sample)
+		}
+	}
+
+	calibration, err := FitMagCalibration(samples)
+	if err != nil {
+		t.Fatalf("Failed to fit calibration: %s", err.Error())
+	}
+
+	for k := 0; k < 3; k++ {
+		if math.Abs(calibration.Offset[k]-offset[k]) > 0.01 {
+			t.Errorf("Expected offset %d to be %f, got %f", k, offset[k], calibration.Offset[k])
+		}
+	}
+
+	// corrected samples should lie on a sphere
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, sample := range samples {
+		x, y, z := calibration.Apply(sample[0], sample[1], sample[2])
+		r := math.Sqrt(x*x + y*y + z*z)
+		min = math.Min(min, r)
+		max = math.Max(max, r)
+	}
+	if (max-min)/max > 0.001 {
+		t.Errorf("Expected corrected samples to have the same radius, got %f..%f", min, max)
+	}
+}
+
+func TestFitMagCalibrationErrors(t *testing.T) {
+	_, err := FitMagCalibration(make([][3]float64, 10))
+	if err == nil {
+		t.Error("Expected error with not enough samples")
+	}
+
+	// rotation around Z axis only
+	samples := make([][3]float64, 0)
+	for i := 0; i < 100; i++ {
+		phi := 2 * math.Pi * float64(i) / 100
+		samples = append(samples, [3]float64{math.Cos(phi), math.Sin(phi), 0.3})
+	}
+	_, err = FitMagCalibration(samples)
+	if err == nil {
+		t.Error("Expected error with samples not covering all axes")
+	}
+}
+
+func TestMagCalibrationApply(t *testing.T) {
+	var calibration *MagCalibration
+	x, y, z := calibration.Apply(1, 2, 3)
+	if x != 1 || y != 2 || z != 3 {
+		t.Errorf("Expected nil calibration to keep readings, got %f, %f, %f", x, y, z)
+	}
+
+	calibration = NewMagCalibration()
+	calibration.Offset = [3]float64{1, 1, 1}
+	x, y, z = calibration.Apply(1, 2, 3)
+	if x != 0 || y != 1 || z != 2 {
+		t.Errorf("Expected offset to be subtracted, got %f, %f, %f", x, y, z)
+	}
+}
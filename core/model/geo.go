@@ -0,0 +1,113 @@
+package model
+
+import "math"
+
+const (
+	earthRadiusMeters = 6372795
+)
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func toDegrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// central angle between two points in radians, haversine formula keeps precision for short distances
+func angularDistance(lat1, long1, lat2, long2 float64) float64 {
+	lat1, long1, lat2, long2 = toRadians(lat1), toRadians(long1), toRadians(lat2), toRadians(long2)
+	sinLat := math.Sin((lat2 - lat1) / 2)
+	sinLong := math.Sin((long2 - long1) / 2)
+	h := sinLat*sinLat + math.Cos(lat1)*math.Cos(lat2)*sinLong*sinLong
+	return 2 * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+// initial great-circle bearing from the first point to the second one in radians
+func initialBearing(lat1, long1, lat2, long2 float64) float64 {
+	lat1, long1, lat2, long2 = toRadians(lat1), toRadians(long1), toRadians(lat2), toRadians(long2)
+	y := math.Sin(long2-long1) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(long2-long1)
+	return math.Atan2(y, x)
+}
+
+// distance from the position to the great circle passing through the leg start and end waypoints,
+// positive if the position is to the right of the leg
+func (p *Position) CrossTrackMeters(start *Waypoint, end *Waypoint) float64 {
+	if (p == nil) || (start == nil) || (end == nil) {
+		return 0
+	}
+
+	distStart := angularDistance(start.Latitude, start.Longitude, p.Latitude, p.Longitude)
+	bearingStart := initialBearing(start.Latitude, start.Longitude, p.Latitude, p.Longitude)
+	bearingLeg := initialBearing(start.Latitude, start.Longitude, end.Latitude, end.Longitude)
+
+	return math.Asin(math.Sin(distStart)*math.Sin(bearingStart-bearingLeg)) * earthRadiusMeters
+}
+
+// signed distance from the line through the end waypoint perpendicular to the leg, positive once
+// the position is past the line, negative infinity if the leg is not defined
+func (p *Position) PastLineMeters(start *Waypoint, end *Waypoint) float64 {
+	if (p == nil) || (start == nil) || (end == nil) {
+		return math.Inf(-1)
+	}
+	if angularDistance(start.Latitude, start.Longitude, end.Latitude, end.Longitude) == 0 {
+		return math.Inf(-1)
+	}
+
+	distEnd := angularDistance(end.Latitude, end.Longitude, p.Latitude, p.Longitude)
+	bearingEnd := initialBearing(end.Latitude, end.Longitude, p.Latitude, p.Longitude)
+	// direction of the leg continued past the end waypoint
+	bearingLeg := initialBearing(end.Latitude, end.Longitude, start.Latitude, start.Longitude) + math.Pi
+
+	return math.Atan(math.Tan(distEnd)*math.Cos(bearingEnd-bearingLeg)) * earthRadiusMeters
+}
+
+// initial great-circle bearing from one waypoint to another in degrees from true North, (-180, 180]
+func InitialBearing(from *Waypoint, to *Waypoint) float64 {
+	return toDegrees(initialBearing(from.Latitude, from.Longitude, to.Latitude, to.Longitude))
+}
+
+// great-circle bearing on arrival to the destination waypoint in degrees from true North, (-180, 180]
+func FinalBearing(from *Waypoint, to *Waypoint) float64 {
+	reverse := toDegrees(initialBearing(to.Latitude, to.Longitude, from.Latitude, from.Longitude))
+	if reverse > 0 {
+		return reverse - 180
+	}
+	return reverse + 180
+}
+
+// point reached by travelling the given distance along the great circle with the initial bearing in degrees
+func DestinationPoint(from *Waypoint, bearing float64, distanceMeters float64) *Waypoint {
+	lat1 := toRadians(from.Latitude)
+	long1 := toRadians(from.Longitude)
+	theta := toRadians(bearing)
+	delta := distanceMeters / earthRadiusMeters
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(delta) + math.Cos(lat1)*math.Sin(delta)*math.Cos(theta))
+	long2 := long1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(lat1),
+		math.Cos(delta)-math.Sin(lat1)*math.Sin(lat2))
+
+	return &Waypoint{
+		Latitude:  toDegrees(lat2),
+		Longitude: math.Mod(toDegrees(long2)+540, 360) - 180,
+	}
+}
+
+// half-way point along the great circle between two waypoints
+func Midpoint(a *Waypoint, b *Waypoint) *Waypoint {
+	lat1 := toRadians(a.Latitude)
+	long1 := toRadians(a.Longitude)
+	lat2 := toRadians(b.Latitude)
+	dLong := toRadians(b.Longitude - a.Longitude)
+
+	bx := math.Cos(lat2) * math.Cos(dLong)
+	by := math.Cos(lat2) * math.Sin(dLong)
+	lat := math.Atan2(math.Sin(lat1)+math.Sin(lat2), math.Sqrt((math.Cos(lat1)+bx)*(math.Cos(lat1)+bx)+by*by))
+	long := long1 + math.Atan2(by, math.Cos(lat1)+bx)
+
+	return &Waypoint{
+		Latitude:  toDegrees(lat),
+		Longitude: math.Mod(toDegrees(long)+540, 360) - 180,
+	}
+}
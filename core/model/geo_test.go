@@ -0,0 +1,164 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCrossTrackMeters(t *testing.T) {
+	start := &Waypoint{
+		Latitude:  56.30,
+		Longitude: 44.00,
+	}
+	end := &Waypoint{
+		Latitude:  56.31,
+		Longitude: 44.00,
+	}
+
+	tests := []struct {
+		latitude  float64
+		longitude float64
+		expected  float64
+	}{
+		{56.305, 44.00, 0.0},
+		// leg goes north, east side is on the right
+		{56.305, 44.001, 61.7},
+		{56.305, 43.999, -61.7},
+	}
+
+	tolerance := 0.5
+	for _, test := range tests {
+		pos := &Position{
+			Latitude:  test.latitude,
+			Longitude: test.longitude,
+		}
+		xte := pos.CrossTrackMeters(start, end)
+		if math.Abs(xte-test.expected) > tolerance {
+			t.Errorf("Expected cross-track distance for %f, %f to be %f, got %f",
+				test.latitude, test.longitude, test.expected, xte)
+		}
+	}
+
+	pos := &Position{
+		Latitude:  56.305,
+		Longitude: 44.001,
+	}
+	if pos.CrossTrackMeters(nil, end) != 0 {
+		t.Errorf("Expected cross-track distance to be 0 without the leg start")
+	}
+}
+
+func TestInitialFinalBearing(t *testing.T) {
+	tests := []struct {
+		from    *Waypoint
+		to      *Waypoint
+		initial float64
+		final   float64
+	}{
+		{&Waypoint{Latitude: 0, Longitude: 0}, &Waypoint{Latitude: 0, Longitude: 1}, 90.0, 90.0},
+		{&Waypoint{Latitude: 0, Longitude: 0}, &Waypoint{Latitude: 1, Longitude: 0}, 0.0, 0.0},
+		{&Waypoint{Latitude: 1, Longitude: 0}, &Waypoint{Latitude: 0, Longitude: 0}, 180.0, 180.0},
+		{&Waypoint{Latitude: 35, Longitude: 45}, &Waypoint{Latitude: 35, Longitude: 135}, 60.1624, 119.8376},
+		{&Waypoint{Latitude: 56.34000, Longitude: 43.99394}, &Waypoint{Latitude: 56.33956, Longitude: 43.98449}, -94.7979, -94.8058},
+	}
+
+	tolerance := 0.0001
+	for _, test := range tests {
+		initial := InitialBearing(test.from, test.to)
+		if math.Abs(initial-test.initial) > tolerance {
+			t.Errorf("Expected initial bearing %v -> %v to be %f, got %f",
+				*test.from, *test.to, test.initial, initial)
+		}
+		final := FinalBearing(test.from, test.to)
+		if math.Abs(final-test.final) > tolerance {
+			t.Errorf("Expected final bearing %v -> %v to be %f, got %f",
+				*test.from, *test.to, test.final, final)
+		}
+	}
+}
+
+func TestDestinationPoint(t *testing.T) {
+	from := &Waypoint{
+		Latitude:  56.34000,
+		Longitude: 43.99394,
+	}
+	to := &Waypoint{
+		Latitude:  56.33956,
+		Longitude: 43.98449,
+	}
+
+	pos := &Position{
+		Latitude:  from.Latitude,
+		Longitude: from.Longitude,
+	}
+	destination := DestinationPoint(from, InitialBearing(from, to), pos.DistanceMeters(to))
+
+	tolerance := 0.000001
+	if math.Abs(destination.Latitude-to.Latitude) > tolerance {
+		t.Errorf("Expected destination latitude to be %f, got %f", to.Latitude, destination.Latitude)
+	}
+	if math.Abs(destination.Longitude-to.Longitude) > tolerance {
+		t.Errorf("Expected destination longitude to be %f, got %f", to.Longitude, destination.Longitude)
+	}
+}
+
+func TestMidpoint(t *testing.T) {
+	tests := []struct {
+		a        *Waypoint
+		b        *Waypoint
+		midpoint *Waypoint
+	}{
+		{&Waypoint{Latitude: 0, Longitude: 0}, &Waypoint{Latitude: 0, Longitude: 10}, &Waypoint{Latitude: 0, Longitude: 5}},
+		{&Waypoint{Latitude: 56.30, Longitude: 44.00}, &Waypoint{Latitude: 56.31, Longitude: 44.00}, &Waypoint{Latitude: 56.305, Longitude: 44.00}},
+		{&Waypoint{Latitude: 0, Longitude: 179}, &Waypoint{Latitude: 0, Longitude: -179}, &Waypoint{Latitude: 0, Longitude: 180}},
+	}
+
+	tolerance := 0.000001
+	for _, test := range tests {
+		midpoint := Midpoint(test.a, test.b)
+		if math.Abs(midpoint.Latitude-test.midpoint.Latitude) > tolerance ||
+			math.Abs(math.Mod(midpoint.Longitude-test.midpoint.Longitude, 360)) > tolerance {
+			t.Errorf("Expected midpoint of %v and %v to be %v, got %v",
+				*test.a, *test.b, *test.midpoint, *midpoint)
+		}
+	}
+}
+
+func TestPastLineMeters(t *testing.T) {
+	start := &Waypoint{Latitude: 56.30, Longitude: 44.00}
+	end := &Waypoint{Latitude: 56.31, Longitude: 44.00}
+
+	tests := []struct {
+		latitude  float64
+		longitude float64
+		expected  float64
+	}{
+		{56.31, 44.00, 0.0},
+		{56.305, 44.00, -556.0},
+		{56.3101, 44.00, 11.1},
+		// the distance to the line does not depend on the cross-track distance
+		{56.3101, 44.002, 11.1},
+		{56.3099, 43.998, -11.1},
+	}
+
+	tolerance := 0.5
+	for _, test := range tests {
+		pos := &Position{
+			Latitude:  test.latitude,
+			Longitude: test.longitude,
+		}
+		past := pos.PastLineMeters(start, end)
+		if math.Abs(past-test.expected) > tolerance {
+			t.Errorf("Expected distance past the line for %f, %f to be %f, got %f",
+				test.latitude, test.longitude, test.expected, past)
+		}
+	}
+
+	pos := &Position{Latitude: 56.31, Longitude: 44.00}
+	if !math.IsInf(pos.PastLineMeters(nil, end), -1) {
+		t.Errorf("Expected the line not to be defined without the leg start")
+	}
+	if !math.IsInf(pos.PastLineMeters(end, end), -1) {
+		t.Errorf("Expected the line not to be defined for the zero length leg")
+	}
+}
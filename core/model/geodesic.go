@@ -0,0 +1,108 @@
+package model
+
+import (
+	"fmt"
+	"math"
+)
+
+type Geodesic uint8
+
+const (
+	// spherical Earth model using the haversine formula
+	GeodesicHaversine Geodesic = iota
+	// WGS-84 ellipsoid using Vincenty's inverse formula
+	GeodesicVincenty
+)
+
+const (
+	wgs84SemiMajorAxis  = 6378137.0
+	wgs84Flattening     = 1 / 298.257223563
+	vincentyMaxIter     = 200
+	vincentyConvergence = 1e-12
+)
+
+func ParseGeodesic(name string) (Geodesic, error) {
+	switch name {
+	case "", "haversine":
+		return GeodesicHaversine, nil
+	case "vincenty", "wgs84":
+		return GeodesicVincenty, nil
+	default:
+		return GeodesicHaversine, fmt.Errorf("unknown geodesic model %s", name)
+	}
+}
+
+func (g Geodesic) String() string {
+	switch g {
+	case GeodesicVincenty:
+		return "vincenty"
+	default:
+		return "haversine"
+	}
+}
+
+// distance between two points in meters
+func (g Geodesic) Distance(lat1, long1, lat2, long2 float64) float64 {
+	if g == GeodesicVincenty {
+		distance, ok := vincentyDistance(lat1, long1, lat2, long2)
+		if ok {
+			return distance
+		}
+		// Vincenty's formula fails to converge for nearly antipodal points
+	}
+	return angularDistance(lat1, long1, lat2, long2) * earthRadiusMeters
+}
+
+func vincentyDistance(lat1, long1, lat2, long2 float64) (float64, bool) {
+	a := wgs84SemiMajorAxis
+	f := wgs84Flattening
+	b := (1 - f) * a
+
+	l := toRadians(long2 - long1)
+	u1 := math.Atan((1 - f) * math.Tan(toRadians(lat1)))
+	u2 := math.Atan((1 - f) * math.Tan(toRadians(lat2)))
+	sinU1, cosU1 := math.Sincos(u1)
+	sinU2, cosU2 := math.Sincos(u2)
+
+	lambda := l
+	var sinSigma, cosSigma, sigma, cosSqAlpha, cos2SigmaM float64
+	converged := false
+	for i := 0; i < vincentyMaxIter; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma = math.Sqrt((cosU2*sinLambda)*(cosU2*sinLambda) +
+			(cosU1*sinU2-sinU1*cosU2*cosLambda)*(cosU1*sinU2-sinU1*cosU2*cosLambda))
+		if sinSigma == 0 {
+			// coincident points
+			return 0, true
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+		if cosSqAlpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		} else {
+			// equatorial line
+			cos2SigmaM = 0
+		}
+		c := f / 16 * cosSqAlpha * (4 + f*(4-3*cosSqAlpha))
+		prevLambda := lambda
+		lambda = l + (1-c)*f*sinAlpha*
+			(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prevLambda) < vincentyConvergence {
+			converged = true
+			break
+		}
+	}
+	if !converged {
+		return 0, false
+	}
+
+	uSq := cosSqAlpha * (a*a - b*b) / (b * b)
+	aa := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+	bb := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+	deltaSigma := bb * sinSigma * (cos2SigmaM + bb/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+		bb/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+
+	return b * aa * (sigma - deltaSigma), true
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeodesicDistance(t *testing.T) {
+	flindersLat := -(37 + 57.0/60 + 3.72030/3600)
+	flindersLong := 144 + 25.0/60 + 29.52440/3600
+	buninyongLat := -(37 + 39.0/60 + 10.15610/3600)
+	buninyongLong := 143 + 55.0/60 + 35.38390/3600
+
+	tests := []struct {
+		name      string
+		geodesic  Geodesic
+		lat1      float64
+		long1     float64
+		lat2      float64
+		long2     float64
+		distance  float64
+		tolerance float64
+	}{
+		{"vincenty reference", GeodesicVincenty, flindersLat, flindersLong, buninyongLat, buninyongLong, 54972.271, 0.001},
+		{"haversine reference", GeodesicHaversine, flindersLat, flindersLong, buninyongLat, buninyongLong, 54972.271, 150},
+		{"vincenty equator degree", GeodesicVincenty, 0, 0, 0, 1, 111319.491, 0.001},
+		{"vincenty meridian degree", GeodesicVincenty, 0, 0, 1, 0, 110574.389, 0.001},
+		{"haversine 1120 m", GeodesicHaversine, 56.326773, 44.006053, 56.318266, 44.015766, 1120.0, 1.0},
+		{"haversine 1 m", GeodesicHaversine, 56.326773, 44.006053, 56.326782, 44.006053, 1.0, 0.01},
+		{"vincenty 1 m", GeodesicVincenty, 56.326773, 44.006053, 56.326782, 44.006053, 1.0, 0.01},
+		{"haversine 10 cm", GeodesicHaversine, 56.326773, 44.006053, 56.326773, 44.0060546, 0.0987, 0.001},
+		{"haversine same point", GeodesicHaversine, 56.326773, 44.006053, 56.326773, 44.006053, 0, 0},
+		{"vincenty same point", GeodesicVincenty, 56.326773, 44.006053, 56.326773, 44.006053, 0, 0},
+		{"vincenty antipodal fallback", GeodesicVincenty, 0, 0, 0.5, 179.7, 19936288, 20000},
+	}
+
+	for _, test := range tests {
+		distance := test.geodesic.Distance(test.lat1, test.long1, test.lat2, test.long2)
+		if math.IsNaN(distance) || math.Abs(distance-test.distance) > test.tolerance {
+			t.Errorf("%s: expected distance to be %f, got %f", test.name, test.distance, distance)
+		}
+	}
+}
+
+func TestParseGeodesic(t *testing.T) {
+	tests := []struct {
+		name     string
+		geodesic Geodesic
+		valid    bool
+	}{
+		{"", GeodesicHaversine, true},
+		{"haversine", GeodesicHaversine, true},
+		{"vincenty", GeodesicVincenty, true},
+		{"wgs84", GeodesicVincenty, true},
+		{"flat", GeodesicHaversine, false},
+	}
+
+	for _, test := range tests {
+		geodesic, err := ParseGeodesic(test.name)
+		if (err == nil) != test.valid {
+			t.Errorf("Unexpected error result for %s: %v", test.name, err)
+		}
+		if geodesic != test.geodesic {
+			t.Errorf("Expected %s to be parsed as %s, got %s", test.name, test.geodesic, geodesic)
+		}
+	}
+}
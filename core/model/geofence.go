@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+type GeofenceStatus struct {
+	// keep-in area or maximum distance from home is configured
+	Enabled  bool
+	Breached bool
+	// why the ship is outside of the geofence, empty if it is inside
+	Reason string
+	// time of the last breach
+	Timestamp time.Time
+}
@@ -0,0 +1,82 @@
+package model
+
+import "fmt"
+
+// what the ship does after the last waypoint of the route is reached
+type MissionEndAction uint8
+
+const (
+	MissionEndStop MissionEndAction = iota
+	// hold position at the last waypoint
+	MissionEndLoiter
+	// return to the home waypoint, stop if it is not set
+	MissionEndReturnHome
+	// continue from the first waypoint
+	MissionEndLoop
+	// follow the route back from the last waypoint
+	MissionEndReverse
+)
+
+func ParseMissionEndAction(name string) (MissionEndAction, error) {
+	switch name {
+	case "", "stop":
+		return MissionEndStop, nil
+	case "loiter":
+		return MissionEndLoiter, nil
+	case "home":
+		return MissionEndReturnHome, nil
+	case "loop":
+		return MissionEndLoop, nil
+	case "reverse":
+		return MissionEndReverse, nil
+	default:
+		return MissionEndStop, fmt.Errorf("unknown mission end action %s", name)
+	}
+}
+
+func (a MissionEndAction) String() string {
+	switch a {
+	case MissionEndLoiter:
+		return "loiter"
+	case MissionEndReturnHome:
+		return "home"
+	case MissionEndLoop:
+		return "loop"
+	case MissionEndReverse:
+		return "reverse"
+	default:
+		return "stop"
+	}
+}
+
+type MissionEnd struct {
+	Action MissionEndAction
+	// number of times the route is repeated by loop and reverse actions, zero repeats it forever
+	Repeat int
+}
+
+// loop and reverse actions need at least two waypoints to repeat the route
+func (m MissionEnd) Repeats(numWaypoints int, repeated int) bool {
+	if m.Action != MissionEndLoop && m.Action != MissionEndReverse {
+		return false
+	}
+	if numWaypoints < 2 {
+		return false
+	}
+	return m.Repeat == 0 || repeated < m.Repeat
+}
+
+// kinds of problems found by the mission validation
+const (
+	MissionProblemEmptyRoute         = "empty_route"
+	MissionProblemInvalidCoordinates = "invalid_coordinates"
+	MissionProblemLegTooLong         = "leg_too_long"
+	MissionProblemOutsideGeofence    = "outside_geofence"
+)
+
+// waypoint index is -1 if the problem concerns the whole route
+type MissionProblem struct {
+	WaypointIndex int
+	Code          string
+	Message       string
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// action of the waypoint reached during the mission
+type MissionEvent struct {
+	// sequence number increasing with every event, clients use it to skip the events already seen
+	Seq           uint64
+	Timestamp     time.Time
+	WaypointIndex int
+	Latitude      float64
+	Longitude     float64
+	Action        string
+}
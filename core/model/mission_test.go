@@ -0,0 +1,55 @@
+package model
+
+import "testing"
+
+func TestParseMissionEndAction(t *testing.T) {
+	tests := []struct {
+		name   string
+		action MissionEndAction
+		valid  bool
+	}{
+		{"", MissionEndStop, true},
+		{"stop", MissionEndStop, true},
+		{"loiter", MissionEndLoiter, true},
+		{"home", MissionEndReturnHome, true},
+		{"loop", MissionEndLoop, true},
+		{"reverse", MissionEndReverse, true},
+		{"circle", MissionEndStop, false},
+	}
+
+	for _, test := range tests {
+		action, err := ParseMissionEndAction(test.name)
+		if (err == nil) != test.valid {
+			t.Errorf("Unexpected error for %q: %v", test.name, err)
+		}
+		if action != test.action {
+			t.Errorf("Expected %s for %q, got %s", test.action, test.name, action)
+		}
+		if test.valid && test.name != "" && action.String() != test.name {
+			t.Errorf("Expected %s string, got %s", test.name, action.String())
+		}
+	}
+}
+
+func TestMissionEndRepeats(t *testing.T) {
+	tests := []struct {
+		missionEnd MissionEnd
+		waypoints  int
+		repeated   int
+		repeats    bool
+	}{
+		{MissionEnd{Action: MissionEndStop}, 3, 0, false},
+		{MissionEnd{Action: MissionEndLoiter}, 3, 0, false},
+		{MissionEnd{Action: MissionEndLoop}, 3, 100, true},
+		{MissionEnd{Action: MissionEndLoop}, 1, 0, false},
+		{MissionEnd{Action: MissionEndLoop, Repeat: 2}, 3, 1, true},
+		{MissionEnd{Action: MissionEndLoop, Repeat: 2}, 3, 2, false},
+		{MissionEnd{Action: MissionEndReverse, Repeat: 1}, 2, 0, true},
+	}
+
+	for i, test := range tests {
+		if test.missionEnd.Repeats(test.waypoints, test.repeated) != test.repeats {
+			t.Errorf("Expected repeats to be %t in test %d", test.repeats, i)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"fmt"
+	"math"
+)
+
+// closed polygon on the water surface, the last vertex is connected to the first one,
+// edges are straight lines in latitude and longitude which is accurate for areas of a few kilometers
+type Polygon struct {
+	Vertices []*Waypoint
+}
+
+func NewPolygon(vertices []*Waypoint) (*Polygon, error) {
+	if len(vertices) < 3 {
+		return nil, fmt.Errorf("polygon has %d vertices, at least 3 are required", len(vertices))
+	}
+	for _, vertex := range vertices {
+		if vertex == nil {
+			return nil, fmt.Errorf("polygon has an undefined vertex")
+		}
+	}
+
+	return &Polygon{
+		Vertices: vertices,
+	}, nil
+}
+
+// the point is inside of the polygon or on its boundary
+func (p *Polygon) Contains(w *Waypoint) bool {
+	inside := false
+	n := len(p.Vertices)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := p.Vertices[i], p.Vertices[j]
+		if onSegment(a, b, w) {
+			return true
+		}
+		if (a.Latitude > w.Latitude) != (b.Latitude > w.Latitude) {
+			longitude := a.Longitude + (w.Latitude-a.Latitude)*(b.Longitude-a.Longitude)/
+				(b.Latitude-a.Latitude)
+			if w.Longitude < longitude {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// the segment between the waypoints enters the polygon or touches its boundary
+func (p *Polygon) IntersectsSegment(start *Waypoint, end *Waypoint) bool {
+	if p.Contains(start) || p.Contains(end) {
+		return true
+	}
+	n := len(p.Vertices)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		if segmentsIntersect(start, end, p.Vertices[j], p.Vertices[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// twice the signed area of the triangle, positive if the points turn counterclockwise
+// with longitude as X and latitude as Y
+func cross(a, b, c *Waypoint) float64 {
+	return (b.Longitude-a.Longitude)*(c.Latitude-a.Latitude) -
+		(b.Latitude-a.Latitude)*(c.Longitude-a.Longitude)
+}
+
+func onSegment(a, b, w *Waypoint) bool {
+	if cross(a, b, w) != 0 {
+		return false
+	}
+	return w.Longitude >= math.Min(a.Longitude, b.Longitude) &&
+		w.Longitude <= math.Max(a.Longitude, b.Longitude) &&
+		w.Latitude >= math.Min(a.Latitude, b.Latitude) &&
+		w.Latitude <= math.Max(a.Latitude, b.Latitude)
+}
+
+func segmentsIntersect(a, b, c, d *Waypoint) bool {
+	d1 := cross(c, d, a)
+	d2 := cross(c, d, b)
+	d3 := cross(a, b, c)
+	d4 := cross(a, b, d)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return onSegment(c, d, a) || onSegment(c, d, b) || onSegment(a, b, c) || onSegment(a, b, d)
+}
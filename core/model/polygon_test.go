@@ -0,0 +1,93 @@
+package model
+
+import "testing"
+
+// L-shaped island with a concave corner at the North-East
+func testPolygon(t *testing.T) *Polygon {
+	polygon, err := NewPolygon([]*Waypoint{
+		{Latitude: 56.300, Longitude: 44.000},
+		{Latitude: 56.300, Longitude: 44.002},
+		{Latitude: 56.301, Longitude: 44.002},
+		{Latitude: 56.301, Longitude: 44.001},
+		{Latitude: 56.302, Longitude: 44.001},
+		{Latitude: 56.302, Longitude: 44.000},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create polygon: %s", err.Error())
+	}
+	return polygon
+}
+
+func TestNewPolygon(t *testing.T) {
+	_, err := NewPolygon([]*Waypoint{
+		{Latitude: 56.300, Longitude: 44.000},
+		{Latitude: 56.300, Longitude: 44.002},
+	})
+	if err == nil {
+		t.Error("Expected polygon with 2 vertices to be rejected")
+	}
+
+	_, err = NewPolygon([]*Waypoint{
+		{Latitude: 56.300, Longitude: 44.000},
+		nil,
+		{Latitude: 56.301, Longitude: 44.002},
+	})
+	if err == nil {
+		t.Error("Expected polygon with an undefined vertex to be rejected")
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	polygon := testPolygon(t)
+
+	tests := []struct {
+		waypoint *Waypoint
+		contains bool
+	}{
+		{&Waypoint{Latitude: 56.3005, Longitude: 44.0005}, true},
+		{&Waypoint{Latitude: 56.3005, Longitude: 44.0015}, true},
+		{&Waypoint{Latitude: 56.3015, Longitude: 44.0005}, true},
+		// concave corner
+		{&Waypoint{Latitude: 56.3015, Longitude: 44.0015}, false},
+		{&Waypoint{Latitude: 56.2990, Longitude: 44.0010}, false},
+		{&Waypoint{Latitude: 56.3010, Longitude: 43.9990}, false},
+		// boundary
+		{&Waypoint{Latitude: 56.3000, Longitude: 44.0010}, true},
+		{&Waypoint{Latitude: 56.3010, Longitude: 44.0010}, true},
+	}
+
+	for i, test := range tests {
+		if polygon.Contains(test.waypoint) != test.contains {
+			t.Errorf("Expected point %d to be inside %t", i, test.contains)
+		}
+	}
+}
+
+func TestPolygonIntersectsSegment(t *testing.T) {
+	polygon := testPolygon(t)
+
+	tests := []struct {
+		start      *Waypoint
+		end        *Waypoint
+		intersects bool
+	}{
+		// crosses the whole island
+		{&Waypoint{Latitude: 56.2990, Longitude: 44.0005}, &Waypoint{Latitude: 56.3030, Longitude: 44.0005}, true},
+		// ends inside
+		{&Waypoint{Latitude: 56.2990, Longitude: 44.0005}, &Waypoint{Latitude: 56.3005, Longitude: 44.0005}, true},
+		// passes by
+		{&Waypoint{Latitude: 56.2990, Longitude: 44.0030}, &Waypoint{Latitude: 56.3030, Longitude: 44.0030}, false},
+		// stays in the concave corner
+		{&Waypoint{Latitude: 56.3015, Longitude: 44.0015}, &Waypoint{Latitude: 56.3030, Longitude: 44.0015}, false},
+		// cuts the concave corner
+		{&Waypoint{Latitude: 56.3015, Longitude: 44.0015}, &Waypoint{Latitude: 56.3030, Longitude: 43.9990}, true},
+		// touches a vertex
+		{&Waypoint{Latitude: 56.2990, Longitude: 44.0010}, &Waypoint{Latitude: 56.3010, Longitude: 44.0030}, true},
+	}
+
+	for i, test := range tests {
+		if polygon.IntersectsSegment(test.start, test.end) != test.intersects {
+			t.Errorf("Expected segment %d to intersect the polygon %t", i, test.intersects)
+		}
+	}
+}
@@ -1,6 +1,16 @@
 package model
 
-import "math"
+import "time"
+
+// GPS fix type as reported by the receiver in NMEA GSA sentence, zero if not reported
+type FixType uint8
+
+const (
+	FixUnknown FixType = iota
+	FixNone
+	Fix2D
+	Fix3D
+)
 
 type Position struct {
 	NumSatellites int8
@@ -8,17 +18,30 @@ type Position struct {
 	Longitude     float64
 	SpeedKnots    float64
 	SpeedKm       float64
+	Hdop          float64
+	FixType       FixType
+	// time elapsed since the receiver computed the fix
+	FixAge time.Duration
+	// time the position was received from the position service
+	Timestamp time.Time
 }
 
+// distance to the waypoint in meters using the spherical Earth model
 func (p *Position) DistanceMeters(w *Waypoint) float64 {
+	return p.DistanceMetersWith(GeodesicHaversine, w)
+}
+
+func (p *Position) DistanceMetersWith(g Geodesic, w *Waypoint) float64 {
 	if (p == nil) || (w == nil) {
 		return 0
 	}
 
-	lat1 := p.Latitude * math.Pi / 180
-	long1 := p.Longitude * math.Pi / 180
-	lat2 := w.Latitude * math.Pi / 180
-	long2 := w.Longitude * math.Pi / 180
+	return g.Distance(p.Latitude, p.Longitude, w.Latitude, w.Longitude)
+}
 
-	return math.Acos(math.Sin(lat1)*math.Sin(lat2)+math.Cos(lat1)*math.Cos(lat2)*math.Cos(long2-long1)) * 6372795
+func (p *Position) Waypoint() *Waypoint {
+	return &Waypoint{
+		Latitude:  p.Latitude,
+		Longitude: p.Longitude,
+	}
 }
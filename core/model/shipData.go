@@ -1,6 +1,23 @@
 package model
 
+import "time"
+
 type ShipData struct {
-	Speed    string
-	Steering string
+	Speed    Speed
+	Steering Steering
+	// time the data was received from the ship control service
+	Timestamp time.Time
+	// commands sent to the ship control service so far
+	Commands CommandMetrics
+}
+
+type CommandMetrics struct {
+	Sent uint64
+	// replaced by a newer command before being sent
+	Coalesced uint64
+	// same as the last command sent
+	Deduplicated uint64
+	// not sent because ship control was not connected
+	Dropped uint64
+	Failed  uint64
 }
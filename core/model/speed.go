@@ -0,0 +1,144 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxPercent = 100
+
+	speedForward = "fwd"
+	speedReverse = "rev"
+	speedStop    = "stop"
+)
+
+// ship speed as signed percentage of the full power, positive is forward, negative is reverse,
+// on the wire it is represented as fwd<percent>, rev<percent> or stop
+type Speed int
+
+const SpeedStop Speed = 0
+
+func Forward(percent int) Speed {
+	return Speed(clampPercent(percent))
+}
+
+func Reverse(percent int) Speed {
+	return Speed(-clampPercent(percent))
+}
+
+func ParseSpeed(s string) (Speed, error) {
+	if s == speedStop {
+		return SpeedStop, nil
+	}
+	if value, ok := strings.CutPrefix(s, speedForward); ok {
+		percent, err := parsePercent(value)
+		if err != nil {
+			return SpeedStop, fmt.Errorf("invalid speed %q: %w", s, err)
+		}
+		return Forward(percent), nil
+	}
+	if value, ok := strings.CutPrefix(s, speedReverse); ok {
+		percent, err := parsePercent(value)
+		if err != nil {
+			return SpeedStop, fmt.Errorf("invalid speed %q: %w", s, err)
+		}
+		return Reverse(percent), nil
+	}
+	return SpeedStop, fmt.Errorf("invalid speed %q", s)
+}
+
+func (s Speed) String() string {
+	switch {
+	case s > 0:
+		return fmt.Sprintf("%s%d", speedForward, int(s))
+	case s < 0:
+		return fmt.Sprintf("%s%d", speedReverse, -int(s))
+	default:
+		return speedStop
+	}
+}
+
+func (s Speed) Percent() int {
+	return absPercent(int(s))
+}
+
+func (s Speed) IsStop() bool {
+	return s == SpeedStop
+}
+
+func (s Speed) IsForward() bool {
+	return s > 0
+}
+
+func (s Speed) IsReverse() bool {
+	return s < 0
+}
+
+// changes the speed by delta percent, reverse speed is increased by a negative delta
+func (s Speed) Add(delta int) Speed {
+	return Speed(clampSigned(int(s) + delta))
+}
+
+// linear interpolation, ratio 0 gives s, ratio 1 gives to
+func (s Speed) Interpolate(to Speed, ratio float64) Speed {
+	return Speed(interpolate(int(s), int(to), ratio))
+}
+
+func (s Speed) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Speed) UnmarshalText(text []byte) error {
+	speed, err := ParseSpeed(string(text))
+	if err != nil {
+		return err
+	}
+	*s = speed
+	return nil
+}
+
+func parsePercent(s string) (int, error) {
+	percent, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if percent < 0 || percent > MaxPercent {
+		return 0, fmt.Errorf("percentage %d is out of range [0, %d]", percent, MaxPercent)
+	}
+	return percent, nil
+}
+
+func clampPercent(percent int) int {
+	if percent < 0 {
+		return 0
+	}
+	if percent > MaxPercent {
+		return MaxPercent
+	}
+	return percent
+}
+
+func clampSigned(value int) int {
+	if value < -MaxPercent {
+		return -MaxPercent
+	}
+	if value > MaxPercent {
+		return MaxPercent
+	}
+	return value
+}
+
+func absPercent(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+func interpolate(from int, to int, ratio float64) int {
+	ratio = math.Max(0, math.Min(1, ratio))
+	return clampSigned(int(math.Round(float64(from) + float64(to-from)*ratio)))
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		s     string
+		speed Speed
+	}{
+		{"stop", SpeedStop},
+		{"fwd30", Forward(30)},
+		{"fwd100", Forward(100)},
+		{"rev80", Reverse(80)},
+		{"fwd0", SpeedStop},
+	}
+
+	for _, test := range tests {
+		speed, err := ParseSpeed(test.s)
+		if err != nil {
+			t.Errorf("Failed to parse speed %s: %s", test.s, err.Error())
+			continue
+		}
+		if speed != test.speed {
+			t.Errorf("Expected speed %s to be %d, got %d", test.s, test.speed, speed)
+		}
+	}
+
+	for _, s := range []string{"", "fwd", "fwd101", "rev-10", "forward30", "left30", "fwd 30"} {
+		_, err := ParseSpeed(s)
+		if err == nil {
+			t.Errorf("Expected speed %q to be invalid", s)
+		}
+	}
+}
+
+func TestSpeedString(t *testing.T) {
+	for _, s := range []string{"stop", "fwd10", "fwd100", "rev5", "rev100"} {
+		speed, err := ParseSpeed(s)
+		if err != nil {
+			t.Fatalf("Failed to parse speed %s: %s", s, err.Error())
+		}
+		if speed.String() != s {
+			t.Errorf("Expected speed to be formatted as %s, got %s", s, speed.String())
+		}
+	}
+}
+
+func TestSpeedDirection(t *testing.T) {
+	if !Forward(30).IsForward() || Forward(30).Percent() != 30 {
+		t.Errorf("Expected fwd30 to be forward 30%%")
+	}
+	if !Reverse(30).IsReverse() || Reverse(30).Percent() != 30 {
+		t.Errorf("Expected rev30 to be reverse 30%%")
+	}
+	if !SpeedStop.IsStop() || SpeedStop.Percent() != 0 {
+		t.Errorf("Expected stop to be 0%%")
+	}
+	if Forward(150) != Forward(100) || Reverse(-10) != SpeedStop {
+		t.Errorf("Expected speed percentage to be clamped")
+	}
+}
+
+func TestSpeedArithmetic(t *testing.T) {
+	if speed := Forward(30).Add(20); speed != Forward(50) {
+		t.Errorf("Expected fwd30 + 20 to be fwd50, got %s", speed)
+	}
+	if speed := Forward(90).Add(20); speed != Forward(100) {
+		t.Errorf("Expected fwd90 + 20 to be fwd100, got %s", speed)
+	}
+	if speed := Forward(10).Add(-30); speed != Reverse(20) {
+		t.Errorf("Expected fwd10 - 30 to be rev20, got %s", speed)
+	}
+
+	tests := []struct {
+		from  Speed
+		to    Speed
+		ratio float64
+		speed Speed
+	}{
+		{Forward(50), Forward(100), 0.0, Forward(50)},
+		{Forward(50), Forward(100), 0.5, Forward(75)},
+		{Forward(50), Forward(100), 1.0, Forward(100)},
+		{Forward(50), Forward(100), 2.0, Forward(100)},
+		{Forward(50), Forward(100), -1.0, Forward(50)},
+		{Reverse(20), Forward(20), 0.5, SpeedStop},
+		{Forward(30), Forward(80), 0.33, Forward(47)},
+	}
+
+	for _, test := range tests {
+		speed := test.from.Interpolate(test.to, test.ratio)
+		if speed != test.speed {
+			t.Errorf("Expected %s..%s at %f to be %s, got %s",
+				test.from, test.to, test.ratio, test.speed, speed)
+		}
+	}
+}
+
+func TestSpeedJSON(t *testing.T) {
+	var value struct {
+		Speed Speed `json:"speed"`
+	}
+
+	err := json.Unmarshal([]byte(`{"speed": "rev40"}`), &value)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal speed: %s", err.Error())
+	}
+	if value.Speed != Reverse(40) {
+		t.Errorf("Expected speed to be rev40, got %s", value.Speed)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Failed to marshal speed: %s", err.Error())
+	}
+	if string(data) != `{"speed":"rev40"}` {
+		t.Errorf("Expected speed to be marshalled as rev40, got %s", string(data))
+	}
+
+	err = json.Unmarshal([]byte(`{"speed": "fwd200"}`), &value)
+	if err == nil {
+		t.Errorf("Expected fwd200 to be rejected")
+	}
+}
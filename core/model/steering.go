@@ -0,0 +1,97 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	steeringLeft     = "left"
+	steeringRight    = "right"
+	steeringStraight = "straight"
+)
+
+// rudder position as signed percentage of the full deflection, positive is right, negative is left,
+// on the wire it is represented as left<percent>, right<percent> or straight
+type Steering int
+
+const SteeringStraight Steering = 0
+
+func Left(percent int) Steering {
+	return Steering(-clampPercent(percent))
+}
+
+func Right(percent int) Steering {
+	return Steering(clampPercent(percent))
+}
+
+func ParseSteering(s string) (Steering, error) {
+	if s == steeringStraight {
+		return SteeringStraight, nil
+	}
+	if value, ok := strings.CutPrefix(s, steeringLeft); ok {
+		percent, err := parsePercent(value)
+		if err != nil {
+			return SteeringStraight, fmt.Errorf("invalid steering %q: %w", s, err)
+		}
+		return Left(percent), nil
+	}
+	if value, ok := strings.CutPrefix(s, steeringRight); ok {
+		percent, err := parsePercent(value)
+		if err != nil {
+			return SteeringStraight, fmt.Errorf("invalid steering %q: %w", s, err)
+		}
+		return Right(percent), nil
+	}
+	return SteeringStraight, fmt.Errorf("invalid steering %q", s)
+}
+
+func (s Steering) String() string {
+	switch {
+	case s > 0:
+		return fmt.Sprintf("%s%d", steeringRight, int(s))
+	case s < 0:
+		return fmt.Sprintf("%s%d", steeringLeft, -int(s))
+	default:
+		return steeringStraight
+	}
+}
+
+func (s Steering) Percent() int {
+	return absPercent(int(s))
+}
+
+func (s Steering) IsStraight() bool {
+	return s == SteeringStraight
+}
+
+func (s Steering) IsLeft() bool {
+	return s < 0
+}
+
+func (s Steering) IsRight() bool {
+	return s > 0
+}
+
+// changes the steering by delta percent, positive delta turns right
+func (s Steering) Add(delta int) Steering {
+	return Steering(clampSigned(int(s) + delta))
+}
+
+// linear interpolation, ratio 0 gives s, ratio 1 gives to
+func (s Steering) Interpolate(to Steering, ratio float64) Steering {
+	return Steering(interpolate(int(s), int(to), ratio))
+}
+
+func (s Steering) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Steering) UnmarshalText(text []byte) error {
+	steering, err := ParseSteering(string(text))
+	if err != nil {
+		return err
+	}
+	*s = steering
+	return nil
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseSteering(t *testing.T) {
+	tests := []struct {
+		s        string
+		steering Steering
+	}{
+		{"straight", SteeringStraight},
+		{"left40", Left(40)},
+		{"right100", Right(100)},
+		{"right0", SteeringStraight},
+	}
+
+	for _, test := range tests {
+		steering, err := ParseSteering(test.s)
+		if err != nil {
+			t.Errorf("Failed to parse steering %s: %s", test.s, err.Error())
+			continue
+		}
+		if steering != test.steering {
+			t.Errorf("Expected steering %s to be %d, got %d", test.s, test.steering, steering)
+		}
+	}
+
+	for _, s := range []string{"", "left", "right101", "left-5", "fwd30", "Straight"} {
+		_, err := ParseSteering(s)
+		if err == nil {
+			t.Errorf("Expected steering %q to be invalid", s)
+		}
+	}
+}
+
+func TestSteeringString(t *testing.T) {
+	for _, s := range []string{"straight", "left10", "left100", "right5", "right70"} {
+		steering, err := ParseSteering(s)
+		if err != nil {
+			t.Fatalf("Failed to parse steering %s: %s", s, err.Error())
+		}
+		if steering.String() != s {
+			t.Errorf("Expected steering to be formatted as %s, got %s", s, steering.String())
+		}
+	}
+}
+
+func TestSteeringArithmetic(t *testing.T) {
+	if !Left(30).IsLeft() || !Right(30).IsRight() || !SteeringStraight.IsStraight() {
+		t.Errorf("Expected steering directions to match")
+	}
+	if steering := Left(30).Add(50); steering != Right(20) {
+		t.Errorf("Expected left30 + 50 to be right20, got %s", steering)
+	}
+	if steering := Left(80).Add(-50); steering != Left(100) {
+		t.Errorf("Expected left80 - 50 to be left100, got %s", steering)
+	}
+	if steering := Left(40).Interpolate(Right(40), 0.25); steering != Left(20) {
+		t.Errorf("Expected left40..right40 at 0.25 to be left20, got %s", steering)
+	}
+}
+
+func TestSteeringJSON(t *testing.T) {
+	var value struct {
+		Steering Steering `json:"steering"`
+	}
+
+	err := json.Unmarshal([]byte(`{"steering": "left70"}`), &value)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal steering: %s", err.Error())
+	}
+	if value.Steering != Left(70) {
+		t.Errorf("Expected steering to be left70, got %s", value.Steering)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Failed to marshal steering: %s", err.Error())
+	}
+	if string(data) != `{"steering":"left70"}` {
+		t.Errorf("Expected steering to be marshalled as left70, got %s", string(data))
+	}
+}
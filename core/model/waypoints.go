@@ -1,8 +1,30 @@
 package model
 
+import "time"
+
 type Waypoint struct {
 	Latitude  float64
 	Longitude float64
+	// target speed over ground on the leg to the waypoint, zero to use the configured speed
+	TargetSpeedKnots float64
+	// distance in meters at which the waypoint is reached, zero to use the configured distance
+	ArrivalRadius float64
+	// criterion used to decide that the waypoint is reached
+	Arrival ArrivalMode
+	// time to stay at the waypoint with the motors stopped before moving on
+	HoldTime time.Duration
+	// optional action tag reported in the mission events when the waypoint is reached
+	Action string
+	// inserted by the route planner to pass around a no-go zone
+	Detour bool
+}
+
+// arrival radius of the waypoint, the default radius if the waypoint does not specify it
+func (w *Waypoint) ArrivalRadiusOr(defaultRadius float64) float64 {
+	if w == nil || w.ArrivalRadius <= 0 {
+		return defaultRadius
+	}
+	return w.ArrivalRadius
 }
 
 type Waypoints struct {
@@ -33,6 +55,89 @@ func (w *Waypoints) GetNextWaypoint() *Waypoint {
 	}
 }
 
+func (w *Waypoints) GetPreviousWaypoint() *Waypoint {
+	if w.nextWaypoint == 0 || w.nextWaypoint > len(w.waypoints) {
+		return nil
+	} else {
+		return w.waypoints[w.nextWaypoint-1]
+	}
+}
+
+// index of the next waypoint in the route, the detour waypoints are not counted so the index
+// of the waypoint following a detour is reported while the detour is passed
+func (w *Waypoints) GetNextWaypointIndex() int {
+	index := 0
+	for i := 0; i < w.nextWaypoint && i < len(w.waypoints); i++ {
+		if w.waypoints[i] == nil || !w.waypoints[i].Detour {
+			index++
+		}
+	}
+	return index
+}
+
+// waypoints which are not reached yet
+func (w *Waypoints) GetRemainingWaypoints() []*Waypoint {
+	if w.nextWaypoint >= len(w.waypoints) {
+		return make([]*Waypoint, 0)
+	}
+	remaining := make([]*Waypoint, len(w.waypoints)-w.nextWaypoint)
+	copy(remaining, w.waypoints[w.nextWaypoint:])
+	return remaining
+}
+
+// inserts the waypoints in front of the next waypoint, the first inserted waypoint becomes the next one
+func (w *Waypoints) InsertBeforeNext(waypoints []*Waypoint) {
+	if w.nextWaypoint > len(w.waypoints) {
+		w.nextWaypoint = len(w.waypoints)
+	}
+	inserted := make([]*Waypoint, 0, len(w.waypoints)+len(waypoints))
+	inserted = append(inserted, w.waypoints[:w.nextWaypoint]...)
+	inserted = append(inserted, waypoints...)
+	inserted = append(inserted, w.waypoints[w.nextWaypoint:]...)
+	w.waypoints = inserted
+}
+
+// removes the detour waypoints inserted by the route planner, the next waypoint becomes
+// the route waypoint following the removed detour
+func (w *Waypoints) RemoveDetours() {
+	kept := make([]*Waypoint, 0, len(w.waypoints))
+	next := 0
+	for i, waypoint := range w.waypoints {
+		if waypoint != nil && waypoint.Detour {
+			continue
+		}
+		if i < w.nextWaypoint {
+			next++
+		}
+		kept = append(kept, waypoint)
+	}
+	w.waypoints = kept
+	w.nextWaypoint = next
+}
+
+func (w *Waypoints) Len() int {
+	return len(w.waypoints)
+}
+
+// starts the route again from the first waypoint
+func (w *Waypoints) Restart() {
+	w.nextWaypoint = 0
+}
+
+// reverses the order of the waypoints after the last waypoint is reached,
+// the last waypoint becomes the start of the first leg back
+func (w *Waypoints) Reverse() {
+	reversed := make([]*Waypoint, len(w.waypoints))
+	for i, waypoint := range w.waypoints {
+		reversed[len(w.waypoints)-1-i] = waypoint
+	}
+	w.waypoints = reversed
+	w.nextWaypoint = 1
+	if w.nextWaypoint > len(w.waypoints) {
+		w.nextWaypoint = len(w.waypoints)
+	}
+}
+
 func (w *Waypoints) WaypointReached() {
 	w.nextWaypoint++
 }
@@ -0,0 +1,191 @@
+package model
+
+import "testing"
+
+func TestWaypointArrivalRadius(t *testing.T) {
+	var waypoint *Waypoint
+	if waypoint.ArrivalRadiusOr(3.0) != 3.0 {
+		t.Errorf("Expected default arrival radius for nil waypoint")
+	}
+
+	waypoint = &Waypoint{Latitude: 56.0, Longitude: 44.0}
+	if waypoint.ArrivalRadiusOr(3.0) != 3.0 {
+		t.Errorf("Expected arrival radius to be 3.0, got %f", waypoint.ArrivalRadiusOr(3.0))
+	}
+
+	waypoint.ArrivalRadius = 10.0
+	if waypoint.ArrivalRadiusOr(3.0) != 10.0 {
+		t.Errorf("Expected arrival radius to be 10.0, got %f", waypoint.ArrivalRadiusOr(3.0))
+	}
+}
+
+func TestRemainingWaypoints(t *testing.T) {
+	waypoints := NewWaypoints()
+	if len(waypoints.GetRemainingWaypoints()) != 0 {
+		t.Errorf("Expected no remaining waypoints")
+	}
+
+	waypoints.SetWaypoints([]*Waypoint{
+		{Latitude: 56.0, Longitude: 44.0},
+		{Latitude: 56.1, Longitude: 44.1},
+		{Latitude: 56.2, Longitude: 44.2},
+	})
+	waypoints.WaypointReached()
+
+	remaining := waypoints.GetRemainingWaypoints()
+	if len(remaining) != 2 {
+		t.Fatalf("Expected 2 remaining waypoints, got %d", len(remaining))
+	}
+	if remaining[0].Latitude != 56.1 || remaining[1].Latitude != 56.2 {
+		t.Errorf("Unexpected remaining waypoints %v, %v", remaining[0], remaining[1])
+	}
+	if waypoints.GetNextWaypointIndex() != 1 {
+		t.Errorf("Expected next waypoint index to be 1, got %d", waypoints.GetNextWaypointIndex())
+	}
+
+	// the returned slice does not alias the route
+	remaining[0] = nil
+	if waypoints.GetNextWaypoint() == nil {
+		t.Errorf("Expected next waypoint not to be changed")
+	}
+
+	waypoints.WaypointReached()
+	waypoints.WaypointReached()
+	if len(waypoints.GetRemainingWaypoints()) != 0 {
+		t.Errorf("Expected no remaining waypoints after the last waypoint")
+	}
+}
+
+func TestWaypointsRestartReverse(t *testing.T) {
+	waypoints := NewWaypoints()
+	waypoints.SetWaypoints([]*Waypoint{
+		{Latitude: 56.0, Longitude: 44.0},
+		{Latitude: 56.1, Longitude: 44.1},
+		{Latitude: 56.2, Longitude: 44.2},
+	})
+	for waypoints.GetNextWaypoint() != nil {
+		waypoints.WaypointReached()
+	}
+
+	waypoints.Restart()
+	if waypoints.GetNextWaypoint().Latitude != 56.0 {
+		t.Errorf("Expected route to restart from the first waypoint, got %f",
+			waypoints.GetNextWaypoint().Latitude)
+	}
+	if waypoints.GetPreviousWaypoint() != nil {
+		t.Errorf("Expected no previous waypoint after restart")
+	}
+
+	for waypoints.GetNextWaypoint() != nil {
+		waypoints.WaypointReached()
+	}
+	waypoints.Reverse()
+	if waypoints.Len() != 3 {
+		t.Fatalf("Expected 3 waypoints, got %d", waypoints.Len())
+	}
+	if waypoints.GetPreviousWaypoint().Latitude != 56.2 {
+		t.Errorf("Expected the last waypoint to start the leg back, got %f",
+			waypoints.GetPreviousWaypoint().Latitude)
+	}
+	if waypoints.GetNextWaypoint().Latitude != 56.1 {
+		t.Errorf("Expected next waypoint latitude to be 56.1, got %f",
+			waypoints.GetNextWaypoint().Latitude)
+	}
+	waypoints.WaypointReached()
+	if waypoints.GetNextWaypoint().Latitude != 56.0 {
+		t.Errorf("Expected next waypoint latitude to be 56.0, got %f",
+			waypoints.GetNextWaypoint().Latitude)
+	}
+}
+
+func TestWaypointsInsertBeforeNext(t *testing.T) {
+	route := []*Waypoint{
+		{Latitude: 56.0, Longitude: 44.0},
+		{Latitude: 56.1, Longitude: 44.1},
+	}
+	waypoints := NewWaypoints()
+	waypoints.SetWaypoints(route)
+	waypoints.WaypointReached()
+
+	waypoints.InsertBeforeNext([]*Waypoint{
+		{Latitude: 56.05, Longitude: 44.0, Detour: true},
+		{Latitude: 56.05, Longitude: 44.1, Detour: true},
+	})
+	if waypoints.Len() != 4 {
+		t.Fatalf("Expected 4 waypoints, got %d", waypoints.Len())
+	}
+	if waypoints.GetPreviousWaypoint().Latitude != 56.0 {
+		t.Errorf("Expected previous waypoint latitude to be 56.0, got %f",
+			waypoints.GetPreviousWaypoint().Latitude)
+	}
+	if !waypoints.GetNextWaypoint().Detour || waypoints.GetNextWaypoint().Longitude != 44.0 {
+		t.Errorf("Expected next waypoint to be the first detour waypoint, got %v",
+			waypoints.GetNextWaypoint())
+	}
+	remaining := waypoints.GetRemainingWaypoints()
+	if len(remaining) != 3 || remaining[2].Latitude != 56.1 {
+		t.Errorf("Expected the route to continue to 56.1 after the detour, got %v", remaining)
+	}
+	// the route of the caller is not modified
+	if route[1].Latitude != 56.1 {
+		t.Errorf("Expected the original route to be kept, got %f", route[1].Latitude)
+	}
+}
+
+func TestWaypointsRemoveDetours(t *testing.T) {
+	waypoints := NewWaypoints()
+	waypoints.SetWaypoints([]*Waypoint{
+		{Latitude: 56.0, Longitude: 44.0},
+		{Latitude: 56.1, Longitude: 44.1},
+	})
+	waypoints.WaypointReached()
+	waypoints.InsertBeforeNext([]*Waypoint{
+		{Latitude: 56.05, Longitude: 44.0, Detour: true},
+		{Latitude: 56.05, Longitude: 44.1, Detour: true},
+	})
+
+	// the detour waypoints are not counted in the index
+	if waypoints.GetNextWaypointIndex() != 1 {
+		t.Errorf("Expected next waypoint index to be 1, got %d", waypoints.GetNextWaypointIndex())
+	}
+	waypoints.WaypointReached()
+	if waypoints.GetNextWaypointIndex() != 1 {
+		t.Errorf("Expected next waypoint index to be 1, got %d", waypoints.GetNextWaypointIndex())
+	}
+
+	waypoints.RemoveDetours()
+	if waypoints.Len() != 2 {
+		t.Fatalf("Expected 2 waypoints, got %d", waypoints.Len())
+	}
+	if waypoints.GetNextWaypointIndex() != 1 || waypoints.GetNextWaypoint().Latitude != 56.1 {
+		t.Errorf("Expected next waypoint to be 56.1 at index 1, got %v at index %d",
+			waypoints.GetNextWaypoint(), waypoints.GetNextWaypointIndex())
+	}
+}
+
+func TestWaypointArrivalMode(t *testing.T) {
+	var waypoint *Waypoint
+	if waypoint.ArrivalModeOr(ArrivalModeLine) != ArrivalModeLine {
+		t.Errorf("Expected default arrival mode for nil waypoint")
+	}
+
+	waypoint = &Waypoint{Latitude: 56.0, Longitude: 44.0}
+	if waypoint.ArrivalModeOr(ArrivalModeRadius) != ArrivalModeRadius {
+		t.Errorf("Expected default arrival mode, got %s", waypoint.ArrivalModeOr(ArrivalModeRadius))
+	}
+
+	waypoint.Arrival = ArrivalModeClosestApproach
+	if waypoint.ArrivalModeOr(ArrivalModeRadius) != ArrivalModeClosestApproach {
+		t.Errorf("Expected cpa arrival mode, got %s", waypoint.ArrivalModeOr(ArrivalModeRadius))
+	}
+
+	for _, name := range []string{"radius", "line", "cpa"} {
+		mode, err := ParseArrivalMode(name)
+		if err != nil || mode.String() != name {
+			t.Errorf("Expected %s arrival mode, got %s, %v", name, mode, err)
+		}
+	}
+	if _, err := ParseArrivalMode("cross"); err == nil {
+		t.Errorf("Expected unknown arrival mode to be rejected")
+	}
+}
@@ -1,6 +1,10 @@
 package core
 
 import (
+	"math"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
 	"github.com/rs/zerolog"
 )
 
@@ -8,15 +12,24 @@ type movingHandler struct {
 	logger             *zerolog.Logger
 	coreData           *coreData
 	shipControl        ShipControl
-	approachSpeed      string
-	fullSpeed          string
+	approachSpeed      model.Speed
+	fullSpeed          model.Speed
 	approachDistance   float64
+	decelDistance      float64
 	distanceInaccuracy float64
+	headingController  *headingController
+	crossTrack         *crossTrackCorrector
+	sogController      *sogController
+	arrival            *arrivalDetector
+	legStart           *model.Waypoint
+	rejoin             bool
 }
 
 func newMovingHandler(logger *zerolog.Logger, coreData *coreData, shipControl ShipControl,
-	approachSpeed string, fullSpeed string, approachDistance float64,
-	distanceInaccuracy float64) *movingHandler {
+	approachSpeed model.Speed, fullSpeed model.Speed, approachDistance float64, decelDistance float64,
+	distanceInaccuracy float64, headingController *headingController,
+	crossTrack *crossTrackCorrector, sogController *sogController,
+	arrival *arrivalDetector) *movingHandler {
 	return &movingHandler{
 		logger:             logger,
 		coreData:           coreData,
@@ -24,19 +37,36 @@ func newMovingHandler(logger *zerolog.Logger, coreData *coreData, shipControl Sh
 		approachSpeed:      approachSpeed,
 		fullSpeed:          fullSpeed,
 		approachDistance:   approachDistance,
+		decelDistance:      decelDistance,
 		distanceInaccuracy: distanceInaccuracy,
+		headingController:  headingController,
+		crossTrack:         crossTrack,
+		sogController:      sogController,
+		arrival:            arrival,
 	}
 }
 
 func (handler *movingHandler) OnEnter() {
 	handler.logger.Debug().Msg("OnEnter")
 
-	handler.shipControl.SetSteering("straight")
+	handler.legStart = handler.coreData.waypoints.GetPreviousWaypoint()
+	if handler.legStart == nil || handler.rejoin {
+		// start a new leg from the current position
+		handler.legStart = handler.coreData.position.Waypoint()
+	}
+	handler.rejoin = false
+	handler.startSteering(handler.coreData.waypoints.GetNextWaypoint())
+	if handler.sogController != nil {
+		handler.sogController.reset()
+	}
+	if handler.arrival != nil {
+		handler.arrival.reset()
+	}
 
-	distance := handler.coreData.position.DistanceMeters(handler.coreData.waypoints.GetNextWaypoint())
+	distance := handler.coreData.distanceMeters(handler.coreData.waypoints.GetNextWaypoint())
 	handler.logger.Debug().Msgf("distance to target = %f", distance)
 
-	handler.setSpeed(distance)
+	handler.setSpeed(distance, handler.coreData.waypoints.GetNextWaypoint())
 }
 
 func (handler *movingHandler) OnExit() {
@@ -48,20 +78,23 @@ func (handler *movingHandler) HandleEvent(event Event) string {
 
 	switch event {
 	case eventPositionUpdate:
-		distance := handler.coreData.position.DistanceMeters(handler.coreData.waypoints.GetNextWaypoint())
+		if transition := geofenceTransition(handler.logger, handler.coreData); transition != "" {
+			return transition
+		}
+		waypoint := handler.coreData.waypoints.GetNextWaypoint()
+		distance := handler.coreData.distanceMeters(waypoint)
 		handler.logger.Debug().Msgf("distance to target = %f", distance)
-		if distance <= handler.distanceInaccuracy {
-			// waypoint reached
-			handler.coreData.waypoints.WaypointReached()
-			if handler.coreData.waypoints.GetNextWaypoint() == nil {
-				return "last waypoint"
-			} else {
-				return "waypoint"
-			}
+		if handler.arrived(waypoint, distance) {
+			return handler.waypointReached(waypoint)
+		} else if handler.offTrack(waypoint) {
+			handler.rejoin = true
+			return "off track"
 		} else {
 			// continue moving
-			handler.setSpeed(distance)
+			handler.setSpeed(distance, waypoint)
 		}
+	case eventBearingUpdate:
+		handler.steer()
 	case eventNetLoss:
 		if handler.coreData.homeWaypoint != nil {
 			handler.logger.Info().Msg("net loss home")
@@ -71,19 +104,155 @@ func (handler *movingHandler) HandleEvent(event Event) string {
 		}
 	case eventNavStop:
 		return "nav stop"
+	case eventFixLost:
+		return "fix lost"
+	case eventSensorStale:
+		return "sensor stale"
+	case eventPositionLost:
+		return "position lost"
+	case eventShipControlLost:
+		return "ship control lost"
+	case eventShipCommandFailed:
+		return "command failed"
+	case eventHoldPosition:
+		return "hold position"
 	case eventWaypointsSet:
 		return "waypoints set"
 	case eventWaypointsCleared:
 		return "waypoints cleared"
+	case eventNoGoZonesSet:
+		next := handler.coreData.waypoints.GetNextWaypoint()
+		if next != nil && next.Detour {
+			// the detour was planned around the previous zones
+			handler.logger.Info().Msg("detour is planned again")
+			return "zones set"
+		}
+		if handler.coreData.planner != nil && handler.coreData.planner.Blocked(
+			handler.coreData.position.Waypoint(), next) {
+			handler.logger.Info().Msg("leg crosses a no-go zone")
+			return "zones set"
+		}
 	}
 
 	return ""
 }
 
-func (handler *movingHandler) setSpeed(distance float64) {
+// the arrival radius is always checked, the arrival detector checks line crossing and closest
+// approach if it is configured
+func (handler *movingHandler) arrived(waypoint *model.Waypoint, distance float64) bool {
+	radius := waypoint.ArrivalRadiusOr(handler.distanceInaccuracy)
+	if handler.arrival == nil {
+		return distance <= radius
+	}
+	return handler.arrival.arrived(handler.coreData.position, handler.legStart, waypoint, distance, radius)
+}
+
+// reports the waypoint action and moves to the next waypoint, the ship holds at the reached
+// waypoint if the waypoint has a hold time
+func (handler *movingHandler) waypointReached(waypoint *model.Waypoint) string {
+	index := handler.coreData.waypoints.GetNextWaypointIndex()
+	handler.coreData.waypoints.WaypointReached()
+	if waypoint == nil {
+		handler.logger.Info().Msgf("waypoint %d reached", index)
+		return "last waypoint"
+	}
+	if waypoint.Detour {
+		handler.logger.Info().Msgf("detour waypoint in front of waypoint %d reached", index)
+		return "waypoint"
+	}
+	handler.logger.Info().Msgf("waypoint %d reached", index)
+
+	if waypoint.Action != "" {
+		handler.logger.Info().Msgf("waypoint %d action %s", index, waypoint.Action)
+		handler.coreData.missionLog.add(index, waypoint, time.Now())
+	}
+	if waypoint.HoldTime > 0 {
+		return "waypoint hold"
+	}
+	if handler.coreData.waypoints.GetNextWaypoint() == nil {
+		if transition := missionEndTransition(handler.logger, handler.coreData); transition != "" {
+			return transition
+		}
+	}
+	return "waypoint"
+}
+
+// the throttle of the speed profile is corrected by the speed over ground controller if it is
+// configured, open-loop throttle is used while the speed over ground is not available
+func (handler *movingHandler) setSpeed(distance float64, waypoint *model.Waypoint) {
+	speed := handler.targetSpeed(distance)
+	if handler.sogController != nil {
+		now := time.Now()
+		target, approach := handler.sogController.target(waypoint)
+		if target > 0 && handler.sogController.available(handler.coreData.position, now) {
+			targetSog := handler.speedProfile(distance, approach, target)
+			speed = handler.sogController.throttle(speed, targetSog, handler.coreData.position.SpeedKnots, now)
+			handler.logger.Debug().Msgf("target sog = %f, sog = %f, throttle = %s",
+				targetSog, handler.coreData.position.SpeedKnots, speed)
+		} else {
+			handler.sogController.reset()
+		}
+	}
+	handler.shipControl.SetSpeed(speed)
+}
+
+func (handler *movingHandler) targetSpeed(distance float64) model.Speed {
+	speed := handler.speedProfile(distance, float64(handler.approachSpeed), float64(handler.fullSpeed))
+	return model.Forward(int(math.Round(speed)))
+}
+
+// approach speed inside the approach zone, full speed far from the waypoint and constant
+// deceleration in between, the squared speed decreases linearly with the distance
+func (handler *movingHandler) speedProfile(distance float64, approach float64, full float64) float64 {
 	if distance < handler.approachDistance {
-		handler.shipControl.SetSpeed(handler.approachSpeed)
-	} else {
-		handler.shipControl.SetSpeed(handler.fullSpeed)
+		return approach
+	}
+	if handler.decelDistance <= 0 || distance >= handler.approachDistance+handler.decelDistance {
+		return full
 	}
+
+	ratio := (distance - handler.approachDistance) / handler.decelDistance
+	return math.Sqrt(approach*approach + (full*full-approach*approach)*ratio)
+}
+
+// keep the ship on the target bearing with the heading controller if it is configured,
+// otherwise move straight, the cross-track correction of the target bearing needs the heading
+// controller, without it the ship only turns again when the maximum cross-track distance is exceeded
+func (handler *movingHandler) startSteering(waypoint *model.Waypoint) {
+	if handler.headingController == nil {
+		handler.shipControl.SetSteering(model.SteeringStraight)
+		return
+	}
+
+	handler.headingController.reset()
+	handler.updateTargetBearing(waypoint)
+	handler.steer()
+}
+
+// returns true if the cross-track distance exceeds the allowed maximum,
+// updates the target bearing with the cross-track correction otherwise
+func (handler *movingHandler) offTrack(waypoint *model.Waypoint) bool {
+	crossTrack := handler.coreData.position.CrossTrackMeters(handler.legStart, waypoint)
+	handler.logger.Debug().Msgf("cross-track distance = %f", crossTrack)
+	if handler.crossTrack.exceeded(crossTrack) {
+		handler.logger.Info().Msgf("cross-track distance %f exceeds the limit", crossTrack)
+		return true
+	}
+
+	handler.updateTargetBearing(waypoint)
+	return false
+}
+
+func (handler *movingHandler) updateTargetBearing(waypoint *model.Waypoint) {
+	crossTrack := handler.coreData.position.CrossTrackMeters(handler.legStart, waypoint)
+	setLegTargetBearing(handler.coreData, handler.legStart, waypoint, handler.crossTrack.correction(crossTrack))
+}
+
+func (handler *movingHandler) steer() {
+	if handler.headingController == nil {
+		return
+	}
+
+	deltaAngle := handler.coreData.headingErrorDeg()
+	handler.shipControl.SetSteering(handler.headingController.steering(deltaAngle, time.Now()))
 }
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"github.com/moosethebrown/ship-nav/core/model"
 	"github.com/rs/zerolog"
 )
 
@@ -9,11 +10,14 @@ type movingHomeHandler struct {
 }
 
 func newMovingHomeHandler(logger *zerolog.Logger, coreData *coreData,
-	shipControl ShipControl, approachSpeed string, fullSpeed string,
-	approachDistance float64, distanceInaccuracy float64) *movingHomeHandler {
+	shipControl ShipControl, approachSpeed model.Speed, fullSpeed model.Speed,
+	approachDistance float64, decelDistance float64, distanceInaccuracy float64,
+	headingController *headingController, crossTrack *crossTrackCorrector,
+	sogController *sogController, arrival *arrivalDetector) *movingHomeHandler {
 
 	movingHandler := newMovingHandler(logger, coreData, shipControl, approachSpeed,
-		fullSpeed, approachDistance, distanceInaccuracy)
+		fullSpeed, approachDistance, decelDistance, distanceInaccuracy, headingController, crossTrack,
+		sogController, arrival)
 	return &movingHomeHandler{
 		movingHandler: movingHandler,
 	}
@@ -26,10 +30,18 @@ func (handler *movingHomeHandler) OnEnter() {
 		return
 	}
 
-	handler.movingHandler.shipControl.SetSteering("straight")
+	handler.movingHandler.legStart = handler.movingHandler.coreData.position.Waypoint()
+	handler.movingHandler.startSteering(handler.movingHandler.coreData.nextHomeWaypoint())
+	if handler.movingHandler.sogController != nil {
+		handler.movingHandler.sogController.reset()
+	}
+	if handler.movingHandler.arrival != nil {
+		handler.movingHandler.arrival.reset()
+	}
 
-	distance := handler.movingHandler.coreData.position.DistanceMeters(handler.movingHandler.coreData.homeWaypoint)
-	handler.movingHandler.setSpeed(distance)
+	target := handler.movingHandler.coreData.nextHomeWaypoint()
+	distance := handler.movingHandler.coreData.distanceMeters(target)
+	handler.movingHandler.setSpeed(distance, target)
 }
 
 func (handler *movingHomeHandler) OnExit() {
@@ -41,16 +53,53 @@ func (handler *movingHomeHandler) HandleEvent(event Event) string {
 
 	switch event {
 	case eventPositionUpdate:
-		distance := handler.movingHandler.coreData.position.DistanceMeters(handler.movingHandler.coreData.homeWaypoint)
+		if transition := geofenceHomeTransition(handler.movingHandler.logger,
+			handler.movingHandler.coreData); transition != "" {
+			return transition
+		}
+		target := handler.movingHandler.coreData.nextHomeWaypoint()
+		distance := handler.movingHandler.coreData.distanceMeters(target)
 		handler.movingHandler.logger.Debug().Msgf("distance to target = %f", distance)
-		if distance <= handler.movingHandler.distanceInaccuracy {
-			return "home reached"
+		if handler.movingHandler.arrived(target, distance) {
+			return handler.targetReached()
+		} else if handler.movingHandler.offTrack(target) {
+			return "off track"
 		} else {
-			handler.movingHandler.setSpeed(distance)
+			handler.movingHandler.setSpeed(distance, target)
 		}
+	case eventBearingUpdate:
+		handler.movingHandler.steer()
 	case eventNavStop:
 		return "nav stop"
+	case eventFixLost:
+		return "fix lost"
+	case eventSensorStale:
+		return "sensor stale"
+	case eventPositionLost:
+		return "position lost"
+	case eventShipControlLost:
+		return "ship control lost"
+	case eventShipCommandFailed:
+		return "command failed"
+	case eventHoldPosition:
+		return "hold position"
+	case eventNoGoZonesSet:
+		// the route home is planned again by turning home
+		handler.movingHandler.coreData.homeRoute = nil
+		return "zones set"
 	}
 
 	return ""
 }
+
+// the ship turns to the next detour waypoint until the home waypoint is reached
+func (handler *movingHomeHandler) targetReached() string {
+	coreData := handler.movingHandler.coreData
+	if len(coreData.homeRoute) > 1 {
+		coreData.homeRoute = coreData.homeRoute[1:]
+		handler.movingHandler.logger.Info().Msgf("detour waypoint reached, %d waypoints to home",
+			len(coreData.homeRoute))
+		return "detour reached"
+	}
+	return "home reached"
+}
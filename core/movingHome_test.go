@@ -25,7 +25,8 @@ func TestMovingHomeOnEnter(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newMovingHomeHandler(&logger, coreData, shipControl, "fwd50", "fwd100", 50.0, 0.5)
+	handler := newMovingHomeHandler(&logger, coreData, shipControl,
+		model.Forward(50), model.Forward(100), 50.0, 0.0, 0.5, nil, nil, nil, nil)
 	handler.OnEnter()
 
 	if shipControl.speed != "fwd100" {
@@ -54,7 +55,8 @@ func TestMovingHomeEventPositionUpdate(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newMovingHomeHandler(&logger, coreData, shipControl, "fwd50", "fwd100", 50.0, 6)
+	handler := newMovingHomeHandler(&logger, coreData, shipControl,
+		model.Forward(50), model.Forward(100), 50.0, 0.0, 6, nil, nil, nil, nil)
 	handler.OnEnter()
 
 	// approach home position
@@ -95,7 +97,8 @@ func TestMovingHomeEventNavStop(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newMovingHomeHandler(&logger, coreData, shipControl, "fwd50", "fwd100", 50.0, 6)
+	handler := newMovingHomeHandler(&logger, coreData, shipControl,
+		model.Forward(50), model.Forward(100), 50.0, 0.0, 6, nil, nil, nil, nil)
 	handler.OnEnter()
 
 	transition := handler.HandleEvent(Event(eventNavStop))
@@ -2,6 +2,7 @@ package core
 
 import (
 	"testing"
+	"time"
 
 	"github.com/moosethebrown/ship-nav/core/model"
 	"github.com/rs/zerolog"
@@ -32,7 +33,8 @@ func TestMovingOnEnter(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newMovingHandler(&logger, coreData, shipControl, "fwd30", "fwd80", 50.0, 0.5)
+	handler := newMovingHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
 	handler.OnEnter()
 
 	if shipControl.speed != "fwd80" {
@@ -68,7 +70,8 @@ func TestMovingEventPositionUpdate(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newMovingHandler(&logger, coreData, shipControl, "fwd30", "fwd80", 50.0, 0.5)
+	handler := newMovingHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
 	handler.OnEnter()
 
 	// approach first waypoint
@@ -137,7 +140,8 @@ func TestMovingEventNetLoss(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newMovingHandler(&logger, coreData, shipControl, "fwd30", "fwd80", 50.0, 0.5)
+	handler := newMovingHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
 	handler.OnEnter()
 
 	transition := handler.HandleEvent(Event(eventNetLoss))
@@ -181,7 +185,8 @@ func TestMovingEventNavStop(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newMovingHandler(&logger, coreData, shipControl, "fwd30", "fwd80", 50.0, 0.5)
+	handler := newMovingHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
 	handler.OnEnter()
 
 	transition := handler.HandleEvent(Event(eventNavStop))
@@ -211,7 +216,8 @@ func TestMovingEventWaypointsSet(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newMovingHandler(&logger, coreData, shipControl, "fwd30", "fwd80", 50.0, 0.5)
+	handler := newMovingHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
 	handler.OnEnter()
 
 	coreData.waypoints = model.NewWaypoints()
@@ -246,7 +252,8 @@ func TestMovingEventWaypointsCleared(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newMovingHandler(&logger, coreData, shipControl, "fwd30", "fwd80", 50.0, 0.5)
+	handler := newMovingHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
 	handler.OnEnter()
 
 	coreData.waypoints = model.NewWaypoints()
@@ -255,3 +262,264 @@ func TestMovingEventWaypointsCleared(t *testing.T) {
 		t.Errorf("Expected waypoints cleared transition, got %s", transition)
 	}
 }
+
+func TestMovingHeadingControl(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	waypoints := model.NewWaypoints()
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.33956,
+		Longitude: 43.98449,
+	})
+
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  56.34,
+			Longitude: 43.99394,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		waypoints:     waypoints,
+	}
+
+	shipControl := &mockShipControl{}
+
+	headingController := newHeadingController(2.0, 0.0, 0.0, 0.0, 100, 10)
+	handler := newMovingHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5,
+		headingController, nil, nil, nil)
+
+	// target bearing is -94.797892 degrees, current bearing is -80 degrees
+	coreData.curBearing.SetFloat(0.173648, -0.984808)
+	handler.OnEnter()
+	if shipControl.steering != "left30" {
+		t.Errorf("Expected steering to be left30, got %s", shipControl.steering)
+	}
+
+	// on course
+	coreData.curBearing.SetAngleDeg(-94.5)
+	transition := handler.HandleEvent(Event(eventBearingUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	if shipControl.steering != "straight" {
+		t.Errorf("Expected steering to be straight, got %s", shipControl.steering)
+	}
+}
+
+func TestMovingCrossTrack(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	waypoints := model.NewWaypoints()
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.30,
+		Longitude: 44.00,
+	})
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.31,
+		Longitude: 44.00,
+	})
+	waypoints.WaypointReached()
+
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  56.30,
+			Longitude: 44.00,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		waypoints:     waypoints,
+	}
+
+	shipControl := &mockShipControl{}
+
+	headingController := newHeadingController(1.0, 0.0, 0.0, 0.0, 100, 10)
+	crossTrack := newCrossTrackCorrector(1.0, 30.0, 50.0)
+	handler := newMovingHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5,
+		headingController, crossTrack, nil, nil)
+	handler.OnEnter()
+	if shipControl.steering != "straight" {
+		t.Errorf("Expected steering to be straight, got %s", shipControl.steering)
+	}
+
+	// pushed ~12 meters to the right of the leg, the ship heading north has to steer left
+	coreData.position.Latitude = 56.305
+	coreData.position.Longitude = 44.0002
+	transition := handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	transition = handler.HandleEvent(Event(eventBearingUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	if shipControl.steering != "left10" {
+		t.Errorf("Expected steering to be left10, got %s", shipControl.steering)
+	}
+
+	// too far from the leg
+	coreData.position.Longitude = 44.001
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "off track" {
+		t.Errorf("Expected off track transition, got %s", transition)
+	}
+
+	// the new leg starts from the current position
+	handler.OnExit()
+	handler.OnEnter()
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+}
+
+// the ship moves straight without the heading controller and turns again only when it is off track
+func TestMovingCrossTrackWithoutHeadingControl(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	waypoints := model.NewWaypoints()
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.30,
+		Longitude: 44.00,
+	})
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.31,
+		Longitude: 44.00,
+	})
+	waypoints.WaypointReached()
+
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  56.30,
+			Longitude: 44.00,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		waypoints:     waypoints,
+	}
+
+	shipControl := &mockShipControl{}
+
+	crossTrack := newCrossTrackCorrector(1.0, 30.0, 50.0)
+	handler := newMovingHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, crossTrack, nil, nil)
+	handler.OnEnter()
+
+	coreData.position.Latitude = 56.305
+	coreData.position.Longitude = 44.0002
+	transition := handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	transition = handler.HandleEvent(Event(eventBearingUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	if shipControl.steering != "straight" {
+		t.Errorf("Expected steering to be straight, got %s", shipControl.steering)
+	}
+
+	coreData.position.Longitude = 44.001
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "off track" {
+		t.Errorf("Expected off track transition, got %s", transition)
+	}
+}
+
+func TestMovingDecelerationCurve(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	handler := newMovingHandler(&logger, &coreData{}, &mockShipControl{},
+		model.Forward(30), model.Forward(90), 10.0, 40.0, 0.5, nil, nil, nil, nil)
+
+	tests := []struct {
+		distance float64
+		speed    model.Speed
+	}{
+		{100.0, model.Forward(90)},
+		{50.0, model.Forward(90)},
+		{30.0, model.Forward(67)},
+		{20.0, model.Forward(52)},
+		{10.0, model.Forward(30)},
+		{5.0, model.Forward(30)},
+	}
+
+	for _, test := range tests {
+		speed := handler.targetSpeed(test.distance)
+		if speed != test.speed {
+			t.Errorf("Expected speed to be %s at %f meters, got %s", test.speed, test.distance, speed)
+		}
+	}
+
+	// speed decreases faster closer to the approach zone
+	if handler.targetSpeed(45.0)-handler.targetSpeed(40.0) >= handler.targetSpeed(15.0)-handler.targetSpeed(10.0) {
+		t.Errorf("Expected deceleration to be stronger near the approach zone")
+	}
+}
+
+func TestMovingWaypointAttributes(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	waypoints := model.NewWaypoints()
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:      56.33956,
+		Longitude:     43.98449,
+		ArrivalRadius: 20.0,
+		HoldTime:      time.Minute,
+		Action:        "sample",
+	})
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.333015,
+		Longitude: 44.007853,
+		Action:    "photo",
+	})
+
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  56.34,
+			Longitude: 43.99394,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		waypoints:     waypoints,
+		missionLog:    newMissionLog(defaultMissionLogSize),
+	}
+
+	handler := newMovingHandler(&logger, coreData, &mockShipControl{},
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
+	handler.OnEnter()
+
+	// the first waypoint is reached inside its own arrival radius
+	coreData.position.Latitude = 56.339582
+	coreData.position.Longitude = 43.984714
+	transition := handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "waypoint hold" {
+		t.Errorf("Expected waypoint hold transition, got %s", transition)
+	}
+
+	// the last waypoint uses the configured distance
+	handler.OnEnter()
+	coreData.position.Latitude = 56.333030
+	coreData.position.Longitude = 44.007853
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	coreData.position.Latitude = 56.333015
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "last waypoint" {
+		t.Errorf("Expected last waypoint transition, got %s", transition)
+	}
+
+	events := coreData.missionLog.snapshot()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 mission events, got %d", len(events))
+	}
+	if events[0].WaypointIndex != 0 || events[0].Action != "sample" {
+		t.Errorf("Unexpected first mission event %+v", events[0])
+	}
+	if events[1].WaypointIndex != 1 || events[1].Action != "photo" {
+		t.Errorf("Unexpected second mission event %+v", events[1])
+	}
+}
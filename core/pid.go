@@ -0,0 +1,59 @@
+package core
+
+import "math"
+
+// PID controller with integral clamping and conditional integration anti-windup
+type pidController struct {
+	kp            float64
+	ki            float64
+	kd            float64
+	integralLimit float64
+	outputLimit   float64
+	integral      float64
+	prevError     float64
+	initialized   bool
+}
+
+func newPIDController(kp, ki, kd, integralLimit, outputLimit float64) *pidController {
+	return &pidController{
+		kp:            kp,
+		ki:            ki,
+		kd:            kd,
+		integralLimit: integralLimit,
+		outputLimit:   outputLimit,
+	}
+}
+
+func (pid *pidController) reset() {
+	pid.integral = 0
+	pid.prevError = 0
+	pid.initialized = false
+}
+
+// update controller state with the new error value, dt is in seconds
+func (pid *pidController) update(err float64, dt float64) float64 {
+	derivative := 0.0
+	integral := pid.integral
+	if pid.initialized && dt > 0 {
+		derivative = (err - pid.prevError) / dt
+		integral += err * dt
+		if pid.integralLimit > 0 {
+			integral = math.Max(-pid.integralLimit, math.Min(pid.integralLimit, integral))
+		}
+	}
+	pid.prevError = err
+	pid.initialized = true
+
+	output := pid.kp*err + pid.ki*integral + pid.kd*derivative
+	if pid.outputLimit > 0 && math.Abs(output) > pid.outputLimit {
+		output = math.Copysign(pid.outputLimit, output)
+		// do not let the integral grow further while the output is saturated
+		if math.Signbit(err) != math.Signbit(output) {
+			pid.integral = integral
+		}
+		return output
+	}
+
+	pid.integral = integral
+	return output
+}
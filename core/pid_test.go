@@ -0,0 +1,66 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPIDProportional(t *testing.T) {
+	pid := newPIDController(2.0, 0.0, 0.0, 0.0, 100.0)
+
+	output := pid.update(10.0, 0.5)
+	if output != 20.0 {
+		t.Errorf("Expected output to be 20.0, got %f", output)
+	}
+
+	output = pid.update(-80.0, 0.5)
+	if output != -100.0 {
+		t.Errorf("Expected output to be limited to -100.0, got %f", output)
+	}
+}
+
+func TestPIDIntegralAndDerivative(t *testing.T) {
+	pid := newPIDController(0.0, 1.0, 1.0, 0.0, 0.0)
+
+	// the first update only initializes the controller
+	output := pid.update(4.0, 1.0)
+	if output != 0.0 {
+		t.Errorf("Expected output to be 0.0, got %f", output)
+	}
+
+	// integral = 2 * 0.5, derivative = (2 - 4) / 0.5
+	output = pid.update(2.0, 0.5)
+	tolerance := 0.000001
+	if math.Abs(output-(1.0-4.0)) > tolerance {
+		t.Errorf("Expected output to be -3.0, got %f", output)
+	}
+
+	pid.reset()
+	output = pid.update(2.0, 0.5)
+	if output != 0.0 {
+		t.Errorf("Expected output to be 0.0 after reset, got %f", output)
+	}
+}
+
+func TestPIDAntiWindup(t *testing.T) {
+	pid := newPIDController(1.0, 1.0, 0.0, 50.0, 100.0)
+
+	// saturated output must not accumulate integral
+	pid.update(200.0, 1.0)
+	for i := 0; i < 10; i++ {
+		pid.update(200.0, 1.0)
+	}
+	if pid.integral != 0.0 {
+		t.Errorf("Expected integral to stay 0 while saturated, got %f", pid.integral)
+	}
+
+	// integral is clamped to the configured limit
+	pid = newPIDController(0.1, 1.0, 0.0, 50.0, 100.0)
+	pid.update(20.0, 1.0)
+	for i := 0; i < 10; i++ {
+		pid.update(20.0, 1.0)
+	}
+	if pid.integral != 50.0 {
+		t.Errorf("Expected integral to be clamped to 50, got %f", pid.integral)
+	}
+}
@@ -0,0 +1,249 @@
+package planner
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+const (
+	// length of a degree of latitude
+	metersPerDegree = 111226.3
+	// vertices of the no-go zones are moved outwards at least this far
+	minMargin = 1.0
+	// limits the offset of sharp vertices
+	minMiterCos = 0.3
+)
+
+var (
+	ErrStartInZone = errors.New("start position is inside of a no-go zone")
+	ErrEndInZone   = errors.New("waypoint is inside of a no-go zone")
+	ErrNoRoute     = errors.New("no route around the no-go zones")
+)
+
+// plans routes around polygonal no-go zones, the shortest route is searched with A* in the visibility
+// graph of the zone vertices moved outwards by the safety margin
+type Planner struct {
+	zones []*model.Polygon
+	// detour waypoints around the zones
+	nodes []*model.Waypoint
+}
+
+// returns nil if there are no zones
+func NewPlanner(zones []*model.Polygon, margin float64) *Planner {
+	if len(zones) == 0 {
+		return nil
+	}
+	if margin < minMargin {
+		margin = minMargin
+	}
+
+	planner := &Planner{
+		zones: zones,
+	}
+	for _, zone := range zones {
+		for _, node := range inflate(zone, margin) {
+			if !planner.inZone(node) {
+				planner.nodes = append(planner.nodes, node)
+			}
+		}
+	}
+	return planner
+}
+
+func (p *Planner) Zones() []*model.Polygon {
+	return p.zones
+}
+
+// the straight leg between the waypoints crosses a no-go zone
+func (p *Planner) Blocked(start *model.Waypoint, end *model.Waypoint) bool {
+	for _, zone := range p.zones {
+		if zone.IntersectsSegment(start, end) {
+			return true
+		}
+	}
+	return false
+}
+
+// detour waypoints between start and end avoiding the no-go zones, empty if the straight leg is clear
+func (p *Planner) Plan(start *model.Waypoint, end *model.Waypoint) ([]*model.Waypoint, error) {
+	if p.inZone(start) {
+		return nil, ErrStartInZone
+	}
+	if p.inZone(end) {
+		return nil, ErrEndInZone
+	}
+	if !p.Blocked(start, end) {
+		return make([]*model.Waypoint, 0), nil
+	}
+
+	// start is the first node, end is the last one
+	nodes := make([]*model.Waypoint, 0, len(p.nodes)+2)
+	nodes = append(nodes, start)
+	nodes = append(nodes, p.nodes...)
+	nodes = append(nodes, end)
+	goal := len(nodes) - 1
+
+	cost := make([]float64, len(nodes))
+	previous := make([]int, len(nodes))
+	for i := range nodes {
+		cost[i] = math.Inf(1)
+		previous[i] = -1
+	}
+	cost[0] = 0
+	closed := make([]bool, len(nodes))
+
+	open := &nodeQueue{}
+	heap.Push(open, &queueItem{node: 0, priority: distance(start, start, end)})
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*queueItem).node
+		if current == goal {
+			return route(nodes, previous, goal), nil
+		}
+		if closed[current] {
+			continue
+		}
+		closed[current] = true
+
+		for next := range nodes {
+			if closed[next] || next == current || p.Blocked(nodes[current], nodes[next]) {
+				continue
+			}
+			nextCost := cost[current] + distance(start, nodes[current], nodes[next])
+			if nextCost < cost[next] {
+				cost[next] = nextCost
+				previous[next] = current
+				heap.Push(open, &queueItem{
+					node:     next,
+					priority: nextCost + distance(start, nodes[next], end),
+				})
+			}
+		}
+	}
+
+	return nil, ErrNoRoute
+}
+
+func (p *Planner) inZone(w *model.Waypoint) bool {
+	for _, zone := range p.zones {
+		if zone.Contains(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// intermediate nodes of the route found by the search
+func route(nodes []*model.Waypoint, previous []int, goal int) []*model.Waypoint {
+	waypoints := make([]*model.Waypoint, 0)
+	for node := previous[goal]; node > 0; node = previous[node] {
+		waypoints = append([]*model.Waypoint{{
+			Latitude:  nodes[node].Latitude,
+			Longitude: nodes[node].Longitude,
+			Detour:    true,
+		}}, waypoints...)
+	}
+	return waypoints
+}
+
+// vertices of the zone moved outwards by the margin along the bisector of the adjacent edges
+func inflate(zone *model.Polygon, margin float64) []*model.Waypoint {
+	origin := zone.Vertices[0]
+	n := len(zone.Vertices)
+	xs := make([]float64, n)
+	ys := make([]float64, n)
+	area := 0.0
+	for i, vertex := range zone.Vertices {
+		xs[i], ys[i] = toLocal(origin, vertex)
+	}
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		area += xs[i]*ys[j] - xs[j]*ys[i]
+	}
+	// outward normals are on the right side of the edges of a counterclockwise polygon
+	orientation := 1.0
+	if area < 0 {
+		orientation = -1.0
+	}
+
+	nodes := make([]*model.Waypoint, 0, n)
+	for i := 0; i < n; i++ {
+		prev := (i + n - 1) % n
+		next := (i + 1) % n
+		n1x, n1y := normal(xs[prev], ys[prev], xs[i], ys[i], orientation)
+		n2x, n2y := normal(xs[i], ys[i], xs[next], ys[next], orientation)
+		bx, by := n1x+n2x, n1y+n2y
+		length := math.Hypot(bx, by)
+		if length == 0 {
+			bx, by, length = n1x, n1y, 1
+		}
+		bx, by = bx/length, by/length
+		// keeps the margin to both edges at the vertex
+		offset := margin / math.Max(bx*n1x+by*n1y, minMiterCos)
+		nodes = append(nodes, fromLocal(origin, xs[i]+bx*offset, ys[i]+by*offset))
+	}
+	return nodes
+}
+
+// unit normal of the edge pointing outwards of the polygon
+func normal(x1, y1, x2, y2, orientation float64) (float64, float64) {
+	dx, dy := x2-x1, y2-y1
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return 0, 0
+	}
+	return orientation * dy / length, -orientation * dx / length
+}
+
+// east and north offsets in meters from the origin, accurate for distances of a few kilometers
+func toLocal(origin *model.Waypoint, w *model.Waypoint) (float64, float64) {
+	x := (w.Longitude - origin.Longitude) * metersPerDegree * math.Cos(origin.Latitude*math.Pi/180)
+	y := (w.Latitude - origin.Latitude) * metersPerDegree
+	return x, y
+}
+
+func fromLocal(origin *model.Waypoint, x, y float64) *model.Waypoint {
+	return &model.Waypoint{
+		Latitude:  origin.Latitude + y/metersPerDegree,
+		Longitude: origin.Longitude + x/(metersPerDegree*math.Cos(origin.Latitude*math.Pi/180)),
+	}
+}
+
+func distance(origin *model.Waypoint, a *model.Waypoint, b *model.Waypoint) float64 {
+	ax, ay := toLocal(origin, a)
+	bx, by := toLocal(origin, b)
+	return math.Hypot(bx-ax, by-ay)
+}
+
+type queueItem struct {
+	node     int
+	priority float64
+}
+
+// priority queue of the open nodes ordered by the estimated route length
+type nodeQueue []*queueItem
+
+func (q nodeQueue) Len() int {
+	return len(q)
+}
+
+func (q nodeQueue) Less(i, j int) bool {
+	return q[i].priority < q[j].priority
+}
+
+func (q nodeQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *nodeQueue) Push(x any) {
+	*q = append(*q, x.(*queueItem))
+}
+
+func (q *nodeQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,129 @@
+package planner
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+func rectangle(t *testing.T, south, west, north, east float64) *model.Polygon {
+	polygon, err := model.NewPolygon([]*model.Waypoint{
+		{Latitude: south, Longitude: west},
+		{Latitude: south, Longitude: east},
+		{Latitude: north, Longitude: east},
+		{Latitude: north, Longitude: west},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create polygon: %s", err.Error())
+	}
+	return polygon
+}
+
+func routeLength(start *model.Waypoint, detour []*model.Waypoint, end *model.Waypoint) float64 {
+	length := 0.0
+	previous := start
+	for _, waypoint := range append(detour, end) {
+		length += distance(start, previous, waypoint)
+		previous = waypoint
+	}
+	return length
+}
+
+func TestNewPlannerNoZones(t *testing.T) {
+	if NewPlanner(nil, 5.0) != nil {
+		t.Error("Expected planner to be disabled without zones")
+	}
+}
+
+func TestPlannerClearLeg(t *testing.T) {
+	planner := NewPlanner([]*model.Polygon{rectangle(t, 56.300, 44.000, 56.301, 44.002)}, 5.0)
+
+	start := &model.Waypoint{Latitude: 56.2990, Longitude: 44.0030}
+	end := &model.Waypoint{Latitude: 56.3030, Longitude: 44.0030}
+	if planner.Blocked(start, end) {
+		t.Error("Expected leg not to be blocked")
+	}
+	detour, err := planner.Plan(start, end)
+	if err != nil {
+		t.Fatalf("Failed to plan route: %s", err.Error())
+	}
+	if len(detour) != 0 {
+		t.Errorf("Expected no detour waypoints, got %d", len(detour))
+	}
+}
+
+func TestPlannerDetour(t *testing.T) {
+	island := rectangle(t, 56.300, 44.000, 56.301, 44.002)
+	planner := NewPlanner([]*model.Polygon{island}, 5.0)
+
+	// the island is about 111 meters long and 124 meters wide
+	start := &model.Waypoint{Latitude: 56.2990, Longitude: 44.0012}
+	end := &model.Waypoint{Latitude: 56.3020, Longitude: 44.0012}
+	if !planner.Blocked(start, end) {
+		t.Fatal("Expected leg to be blocked")
+	}
+
+	detour, err := planner.Plan(start, end)
+	if err != nil {
+		t.Fatalf("Failed to plan route: %s", err.Error())
+	}
+	// around the two eastern corners which are closer
+	if len(detour) != 2 {
+		t.Fatalf("Expected 2 detour waypoints, got %d", len(detour))
+	}
+
+	previous := start
+	for i, waypoint := range append(detour, end) {
+		if i < len(detour) && !waypoint.Detour {
+			t.Errorf("Expected waypoint %d to be a detour waypoint", i)
+		}
+		if i < len(detour) && waypoint.Longitude <= 44.002 {
+			t.Errorf("Expected waypoint %d to pass the island on the East, got %f", i, waypoint.Longitude)
+		}
+		if planner.Blocked(previous, waypoint) {
+			t.Errorf("Expected leg %d of the detour not to be blocked", i)
+		}
+		previous = waypoint
+	}
+
+	// the margin is kept from the corners
+	for i, waypoint := range detour {
+		corner := &model.Waypoint{Latitude: 56.300 + 0.001*float64(i), Longitude: 44.002}
+		d := distance(start, corner, waypoint)
+		if d < 5.0 || d > 10.0 {
+			t.Errorf("Expected waypoint %d to be 5 to 10 meters from the corner, got %f", i, d)
+		}
+	}
+
+	direct := distance(start, start, end)
+	length := routeLength(start, detour, end)
+	if length < direct || length > 1.5*direct {
+		t.Errorf("Expected detour length to be close to %f, got %f", direct, length)
+	}
+}
+
+func TestPlannerErrors(t *testing.T) {
+	planner := NewPlanner([]*model.Polygon{rectangle(t, 56.300, 44.000, 56.301, 44.002)}, 5.0)
+
+	outside := &model.Waypoint{Latitude: 56.2990, Longitude: 44.0010}
+	inside := &model.Waypoint{Latitude: 56.3005, Longitude: 44.0010}
+	if _, err := planner.Plan(inside, outside); !errors.Is(err, ErrStartInZone) {
+		t.Errorf("Expected start in zone error, got %v", err)
+	}
+	if _, err := planner.Plan(outside, inside); !errors.Is(err, ErrEndInZone) {
+		t.Errorf("Expected end in zone error, got %v", err)
+	}
+
+	// the waypoint is enclosed by a frame of zones
+	planner = NewPlanner([]*model.Polygon{
+		rectangle(t, 56.3000, 44.0000, 56.3002, 44.0030),
+		rectangle(t, 56.3018, 44.0000, 56.3020, 44.0030),
+		rectangle(t, 56.3000, 44.0000, 56.3020, 44.0003),
+		rectangle(t, 56.3000, 44.0027, 56.3020, 44.0030),
+	}, 5.0)
+	enclosed := &model.Waypoint{Latitude: 56.3010, Longitude: 44.0015}
+	if _, err := planner.Plan(outside, enclosed); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("Expected no route error, got %v", err)
+	}
+}
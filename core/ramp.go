@@ -0,0 +1,111 @@
+package core
+
+import (
+	"math"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+const (
+	defaultSpeedRampInterval = 100
+)
+
+// ship control decorator limiting the rate of speed changes, speed commands set the target speed
+// which is approached in steps on every tick, steering commands are passed through
+type speedRamp struct {
+	shipControl ShipControl
+	// percent of the full power per second, zero is unlimited
+	accelRate float64
+	decelRate float64
+	// speed sent to ship control with the fractional part accumulated between the steps
+	level    float64
+	current  model.Speed
+	target   model.Speed
+	lastStep time.Time
+}
+
+func newSpeedRamp(shipControl ShipControl, accelRate float64, decelRate float64) *speedRamp {
+	if accelRate <= 0 && decelRate <= 0 {
+		return nil
+	}
+
+	return &speedRamp{
+		shipControl: shipControl,
+		accelRate:   accelRate,
+		decelRate:   decelRate,
+	}
+}
+
+func (r *speedRamp) SetSpeed(speed model.Speed) {
+	r.target = speed
+	if r.current == speed {
+		// repeated commands are passed through, e.g. to be resent after reconnection
+		r.shipControl.SetSpeed(speed)
+	}
+}
+
+// stops the motors immediately, the rate limits are not applied
+func (r *speedRamp) stopNow() {
+	r.target = model.SpeedStop
+	r.current = model.SpeedStop
+	r.level = 0
+	r.shipControl.SetSpeed(model.SpeedStop)
+}
+
+// failsafe stop bypassing the speed ramp if it is configured
+func emergencyStop(shipControl ShipControl) {
+	if ramp, ok := shipControl.(*speedRamp); ok {
+		ramp.stopNow()
+		return
+	}
+	shipControl.SetSpeed(model.SpeedStop)
+}
+
+func (r *speedRamp) SetSteering(steering model.Steering) {
+	r.shipControl.SetSteering(steering)
+}
+
+func (r *speedRamp) step(now time.Time) {
+	dt := 0.0
+	if !r.lastStep.IsZero() {
+		dt = now.Sub(r.lastStep).Seconds()
+	}
+	r.lastStep = now
+
+	if r.current == r.target {
+		return
+	}
+
+	r.level = r.next(dt)
+	speed := model.Speed(int(math.Round(r.level)))
+	if speed != r.current {
+		r.current = speed
+		r.shipControl.SetSpeed(speed)
+	}
+}
+
+// moving away from stop accelerates, moving towards stop decelerates,
+// changing the direction decelerates to stop first
+func (r *speedRamp) next(dt float64) float64 {
+	target := float64(r.target)
+	rate := r.accelRate
+	if (r.level > 0 && target < r.level) || (r.level < 0 && target > r.level) {
+		rate = r.decelRate
+		if r.level*target < 0 {
+			target = 0
+		}
+	}
+	if rate <= 0 {
+		return target
+	}
+
+	maxStep := rate * dt
+	if math.Abs(target-r.level) <= maxStep {
+		return target
+	}
+	if target > r.level {
+		return r.level + maxStep
+	}
+	return r.level - maxStep
+}
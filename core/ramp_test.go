@@ -0,0 +1,148 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+func TestNewSpeedRampDisabled(t *testing.T) {
+	ramp := newSpeedRamp(&mockShipControl{}, 0.0, 0.0)
+	if ramp != nil {
+		t.Error("Expected speed ramp to be disabled with zero rates")
+	}
+}
+
+func TestSpeedRampAcceleration(t *testing.T) {
+	shipControl := &mockShipControl{}
+	ramp := newSpeedRamp(shipControl, 20.0, 50.0)
+	start := time.Now()
+
+	ramp.step(start)
+	ramp.SetSpeed(model.Forward(50))
+	if shipControl.speed != "" {
+		t.Errorf("Expected speed not to be sent before the first step, got %s", shipControl.speed)
+	}
+
+	steps := []struct {
+		elapsed time.Duration
+		speed   string
+	}{
+		{500 * time.Millisecond, "fwd10"},
+		{1000 * time.Millisecond, "fwd20"},
+		{1250 * time.Millisecond, "fwd25"},
+		{2500 * time.Millisecond, "fwd50"},
+		{3000 * time.Millisecond, "fwd50"},
+	}
+
+	for _, step := range steps {
+		ramp.step(start.Add(step.elapsed))
+		if shipControl.speed != step.speed {
+			t.Errorf("Expected speed to be %s after %s, got %s", step.speed, step.elapsed, shipControl.speed)
+		}
+	}
+
+	// deceleration uses its own rate
+	ramp.SetSpeed(model.SpeedStop)
+	ramp.step(start.Add(3500 * time.Millisecond))
+	if shipControl.speed != "fwd25" {
+		t.Errorf("Expected speed to be fwd25, got %s", shipControl.speed)
+	}
+	ramp.step(start.Add(4000 * time.Millisecond))
+	if shipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", shipControl.speed)
+	}
+}
+
+func TestSpeedRampReverse(t *testing.T) {
+	shipControl := &mockShipControl{}
+	ramp := newSpeedRamp(shipControl, 40.0, 80.0)
+	start := time.Now()
+
+	ramp.step(start)
+	ramp.SetSpeed(model.Forward(40))
+	ramp.step(start.Add(time.Second))
+	if shipControl.speed != "fwd40" {
+		t.Fatalf("Expected speed to be fwd40, got %s", shipControl.speed)
+	}
+
+	// the ship decelerates to stop before accelerating in reverse
+	ramp.SetSpeed(model.Reverse(40))
+	ramp.step(start.Add(1250 * time.Millisecond))
+	if shipControl.speed != "fwd20" {
+		t.Errorf("Expected speed to be fwd20, got %s", shipControl.speed)
+	}
+	ramp.step(start.Add(1750 * time.Millisecond))
+	if shipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", shipControl.speed)
+	}
+	ramp.step(start.Add(2250 * time.Millisecond))
+	if shipControl.speed != "rev20" {
+		t.Errorf("Expected speed to be rev20, got %s", shipControl.speed)
+	}
+}
+
+func TestSpeedRampPassThrough(t *testing.T) {
+	shipControl := &mockShipControl{}
+	ramp := newSpeedRamp(shipControl, 20.0, 0.0)
+	start := time.Now()
+
+	// repeated target is resent immediately
+	ramp.SetSpeed(model.SpeedStop)
+	if shipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", shipControl.speed)
+	}
+
+	ramp.SetSteering(model.Left(30))
+	if shipControl.steering != "left30" {
+		t.Errorf("Expected steering to be left30, got %s", shipControl.steering)
+	}
+
+	// zero deceleration rate is unlimited
+	ramp.step(start)
+	ramp.SetSpeed(model.Forward(20))
+	ramp.step(start.Add(time.Second))
+	ramp.SetSpeed(model.Forward(5))
+	ramp.step(start.Add(1100 * time.Millisecond))
+	if shipControl.speed != "fwd5" {
+		t.Errorf("Expected speed to be fwd5, got %s", shipControl.speed)
+	}
+}
+
+func TestSpeedRampEmergencyStop(t *testing.T) {
+	shipControl := &mockShipControl{}
+	ramp := newSpeedRamp(shipControl, 50.0, 25.0)
+	start := time.Now()
+
+	ramp.step(start)
+	ramp.SetSpeed(model.Forward(100))
+	ramp.step(start.Add(time.Second))
+	if shipControl.speed != "fwd50" {
+		t.Fatalf("Expected speed to be fwd50, got %s", shipControl.speed)
+	}
+
+	// the stop is sent without waiting for the next step
+	emergencyStop(ramp)
+	if shipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", shipControl.speed)
+	}
+	ramp.step(start.Add(1100 * time.Millisecond))
+	if shipControl.speed != "stop" {
+		t.Errorf("Expected speed to stay stop, got %s", shipControl.speed)
+	}
+
+	// the ramp continues from stop
+	ramp.SetSpeed(model.Forward(10))
+	ramp.step(start.Add(1200 * time.Millisecond))
+	if shipControl.speed != "fwd5" {
+		t.Errorf("Expected speed to be fwd5, got %s", shipControl.speed)
+	}
+
+	// ship control without the ramp is stopped directly
+	direct := &mockShipControl{}
+	emergencyStop(direct)
+	if direct.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", direct.speed)
+	}
+}
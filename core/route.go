@@ -0,0 +1,71 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/moosethebrown/ship-nav/core/planner"
+	"github.com/rs/zerolog"
+)
+
+// inserts detour waypoints in front of the next waypoint if the leg from the current position
+// crosses a no-go zone, returns false if the next waypoint can not be reached,
+// the detours planned before are removed as the no-go zones may have changed
+func planRoute(logger *zerolog.Logger, coreData *coreData) bool {
+	coreData.waypoints.RemoveDetours()
+	waypoint := coreData.waypoints.GetNextWaypoint()
+	if coreData.planner == nil || waypoint == nil {
+		return true
+	}
+
+	index := coreData.waypoints.GetNextWaypointIndex()
+	detour, err := coreData.planner.Plan(coreData.position.Waypoint(), waypoint)
+	if errors.Is(err, planner.ErrStartInZone) {
+		// the ship leaves the zone on the straight leg
+		logger.Warn().Err(err).Msgf("moving to waypoint %d without detour", index)
+		return true
+	}
+	if err != nil {
+		logger.Error().Err(err).Msgf("waypoint %d can not be reached", index)
+		return false
+	}
+
+	if len(detour) > 0 {
+		logger.Info().Msgf("%d detour waypoints inserted in front of waypoint %d", len(detour), index)
+		coreData.waypoints.InsertBeforeNext(detour)
+	}
+	return true
+}
+
+// plans the route from the current position to the home waypoint around the no-go zones,
+// returns false if the home waypoint can not be reached
+func planRouteHome(logger *zerolog.Logger, coreData *coreData) bool {
+	coreData.homeRoute = []*model.Waypoint{coreData.homeWaypoint}
+	if coreData.planner == nil || coreData.homeWaypoint == nil {
+		return true
+	}
+
+	detour, err := coreData.planner.Plan(coreData.position.Waypoint(), coreData.homeWaypoint)
+	if errors.Is(err, planner.ErrStartInZone) {
+		logger.Warn().Err(err).Msg("moving home without detour")
+		return true
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("home waypoint can not be reached")
+		return false
+	}
+
+	if len(detour) > 0 {
+		logger.Info().Msgf("%d detour waypoints on the route home", len(detour))
+		coreData.homeRoute = append(detour, coreData.homeWaypoint)
+	}
+	return true
+}
+
+// next waypoint on the route home, the home waypoint if the route is not planned
+func (d *coreData) nextHomeWaypoint() *model.Waypoint {
+	if len(d.homeRoute) == 0 {
+		return d.homeWaypoint
+	}
+	return d.homeRoute[0]
+}
@@ -0,0 +1,259 @@
+package core
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/moosethebrown/ship-nav/core/planner"
+	"github.com/rs/zerolog"
+)
+
+// island across the leg from the current position to the next waypoint
+func routeTestIsland(t *testing.T) *model.Polygon {
+	island, err := model.NewPolygon([]*model.Waypoint{
+		{Latitude: 56.300, Longitude: 44.000},
+		{Latitude: 56.300, Longitude: 44.002},
+		{Latitude: 56.301, Longitude: 44.002},
+		{Latitude: 56.301, Longitude: 44.000},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create polygon: %s", err.Error())
+	}
+	return island
+}
+
+func routeTestData(t *testing.T, zones ...*model.Polygon) *coreData {
+	waypoints := model.NewWaypoints()
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.3020,
+		Longitude: 44.0012,
+	})
+
+	return &coreData{
+		position: &model.Position{
+			Latitude:  56.2990,
+			Longitude: 44.0012,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		waypoints:     waypoints,
+		planner:       planner.NewPlanner(zones, 5.0),
+	}
+}
+
+func TestTurningDetour(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := routeTestData(t, routeTestIsland(t))
+	shipControl := &mockShipControl{}
+
+	handler := newTurningHandler(&logger, coreData, shipControl, model.Forward(30),
+		model.Left(40), model.Right(40), nil, nil)
+	handler.OnEnter()
+
+	if coreData.waypoints.Len() != 3 {
+		t.Fatalf("Expected 2 detour waypoints to be inserted, got %d waypoints", coreData.waypoints.Len())
+	}
+	if !coreData.waypoints.GetNextWaypoint().Detour {
+		t.Error("Expected next waypoint to be a detour waypoint")
+	}
+	// the first detour waypoint is to the North-East
+	if shipControl.steering != "right40" {
+		t.Errorf("Expected steering to be right40, got %s", shipControl.steering)
+	}
+
+	transition := handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+
+	// the route is planned only once
+	handler.OnEnter()
+	if coreData.waypoints.Len() != 3 {
+		t.Errorf("Expected 3 waypoints, got %d", coreData.waypoints.Len())
+	}
+
+	// the detour is removed with the no-go zones
+	coreData.planner = nil
+	handler.HandleEvent(Event(eventNoGoZonesSet))
+	if coreData.waypoints.Len() != 1 || coreData.waypoints.GetNextWaypoint().Detour {
+		t.Errorf("Expected detour waypoints to be removed, got %d waypoints", coreData.waypoints.Len())
+	}
+	if coreData.waypoints.GetNextWaypointIndex() != 0 {
+		t.Errorf("Expected next waypoint index to be 0, got %d", coreData.waypoints.GetNextWaypointIndex())
+	}
+}
+
+func TestTurningRouteBlocked(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := routeTestData(t, routeTestIsland(t))
+	// the waypoint is on the island
+	coreData.waypoints.SetWaypoints([]*model.Waypoint{
+		{Latitude: 56.3005, Longitude: 44.0010},
+	})
+	shipControl := &mockShipControl{}
+
+	handler := newTurningHandler(&logger, coreData, shipControl, model.Forward(30),
+		model.Left(40), model.Right(40), nil, nil)
+	handler.OnEnter()
+
+	transition := handler.HandleEvent(Event(eventBearingUpdate))
+	if transition != "route blocked" {
+		t.Errorf("Expected route blocked transition, got %s", transition)
+	}
+
+	// the zones are removed
+	coreData.planner = nil
+	transition = handler.HandleEvent(Event(eventNoGoZonesSet))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+}
+
+func TestMovingNoGoZonesSet(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := routeTestData(t)
+	shipControl := &mockShipControl{}
+
+	handler := newMovingHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, nil, nil)
+	handler.OnEnter()
+
+	coreData.planner = planner.NewPlanner([]*model.Polygon{routeTestIsland(t)}, 5.0)
+	transition := handler.HandleEvent(Event(eventNoGoZonesSet))
+	if transition != "zones set" {
+		t.Errorf("Expected zones set transition, got %s", transition)
+	}
+
+	coreData.planner = nil
+	transition = handler.HandleEvent(Event(eventNoGoZonesSet))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+
+	// the detour is planned again even if the leg to it is clear
+	coreData.waypoints.InsertBeforeNext([]*model.Waypoint{
+		{Latitude: 56.3000, Longitude: 44.0030, Detour: true},
+	})
+	transition = handler.HandleEvent(Event(eventNoGoZonesSet))
+	if transition != "zones set" {
+		t.Errorf("Expected zones set transition, got %s", transition)
+	}
+}
+
+func TestCoreNoGoZones(t *testing.T) {
+	mockCoreConfigurer := &mockCoreConfigurer{}
+	logger := zerolog.New(os.Stdout).Level(zerolog.DebugLevel)
+
+	core := NewCore(mockCoreConfigurer, &mockShipControl{}, &logger)
+	go core.Run()
+	defer core.Stop()
+
+	if core.data.planner != nil {
+		t.Error("Expected no planner without no-go zones")
+	}
+
+	core.SetNoGoZones([]*model.Polygon{routeTestIsland(t)})
+	time.Sleep(10 * time.Millisecond)
+	if core.data.planner == nil || len(core.data.planner.Zones()) != 1 {
+		t.Fatal("Expected planner with 1 no-go zone")
+	}
+
+	core.SetNoGoZones(nil)
+	time.Sleep(10 * time.Millisecond)
+	if core.data.planner != nil {
+		t.Error("Expected no-go zones to be cleared")
+	}
+}
+
+func TestTurningHomeDetour(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := routeTestData(t, routeTestIsland(t))
+	coreData.homeWaypoint = coreData.waypoints.GetNextWaypoint()
+	shipControl := &mockShipControl{}
+
+	turningHome := newTurningHomeHandler(&logger, coreData, shipControl, model.Forward(30),
+		model.Left(40), model.Right(40), nil, nil)
+	turningHome.OnEnter()
+
+	if len(coreData.homeRoute) != 3 {
+		t.Fatalf("Expected 2 detour waypoints on the route home, got %d waypoints", len(coreData.homeRoute))
+	}
+	if !coreData.nextHomeWaypoint().Detour {
+		t.Error("Expected next home waypoint to be a detour waypoint")
+	}
+	if shipControl.steering != "right40" {
+		t.Errorf("Expected steering to be right40, got %s", shipControl.steering)
+	}
+	transition := turningHome.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+
+	// the detour waypoints are followed before the home waypoint is reached
+	movingHome := newMovingHomeHandler(&logger, coreData, shipControl,
+		model.Forward(50), model.Forward(100), 50.0, 0.0, 0.5, nil, nil, nil, nil)
+	movingHome.OnEnter()
+	detour := coreData.nextHomeWaypoint()
+	coreData.position.Latitude = detour.Latitude
+	coreData.position.Longitude = detour.Longitude
+	transition = movingHome.HandleEvent(Event(eventPositionUpdate))
+	if transition != "detour reached" {
+		t.Errorf("Expected detour reached transition, got %s", transition)
+	}
+	if len(coreData.homeRoute) != 2 {
+		t.Errorf("Expected 2 waypoints to home, got %d", len(coreData.homeRoute))
+	}
+
+	// the route is kept when turning to the next detour waypoint
+	turningHome.OnEnter()
+	if len(coreData.homeRoute) != 2 {
+		t.Errorf("Expected 2 waypoints to home, got %d", len(coreData.homeRoute))
+	}
+
+	transition = movingHome.HandleEvent(Event(eventNoGoZonesSet))
+	if transition != "zones set" {
+		t.Errorf("Expected zones set transition, got %s", transition)
+	}
+	if coreData.homeRoute != nil {
+		t.Error("Expected route home to be planned again")
+	}
+}
+
+func TestTurningHomeRouteBlocked(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := routeTestData(t, routeTestIsland(t))
+	// the home waypoint is on the island
+	coreData.homeWaypoint = &model.Waypoint{Latitude: 56.3005, Longitude: 44.0010}
+	shipControl := &mockShipControl{}
+
+	handler := newTurningHomeHandler(&logger, coreData, shipControl, model.Forward(30),
+		model.Left(40), model.Right(40), nil, nil)
+	handler.OnEnter()
+
+	transition := handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "route blocked" {
+		t.Errorf("Expected route blocked transition, got %s", transition)
+	}
+
+	// the zones are removed
+	coreData.planner = nil
+	transition = handler.HandleEvent(Event(eventNoGoZonesSet))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	transition = handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"math"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+)
+
+// adjusts the open-loop throttle to hold the target speed over ground against current and wind,
+// the PI output in percent of the full power is added to the throttle of the speed profile
+type sogController struct {
+	pid *pidController
+	// default target speed in knots, waypoints may override it
+	targetKnots   float64
+	approachKnots float64
+	minThrottle   model.Speed
+	maxThrottle   model.Speed
+	// speed older than this is not used, zero disables the check
+	maxAge     time.Duration
+	lastUpdate time.Time
+}
+
+func newSogController(enabled bool, kp, ki, integralLimit, targetKnots, approachKnots float64,
+	minThrottle model.Speed, maxThrottle model.Speed, maxAgeMs int64) *sogController {
+	if !enabled {
+		return nil
+	}
+	if maxThrottle.IsStop() {
+		maxThrottle = model.Forward(model.MaxPercent)
+	}
+
+	return &sogController{
+		pid:           newPIDController(kp, ki, 0.0, integralLimit, model.MaxPercent),
+		targetKnots:   targetKnots,
+		approachKnots: approachKnots,
+		minThrottle:   minThrottle,
+		maxThrottle:   maxThrottle,
+		maxAge:        time.Duration(maxAgeMs) * time.Millisecond,
+	}
+}
+
+func (c *sogController) reset() {
+	c.pid.reset()
+	c.lastUpdate = time.Time{}
+}
+
+// target speed over ground in knots for the leg to the waypoint, zero if not specified
+func (c *sogController) target(waypoint *model.Waypoint) (float64, float64) {
+	target := c.targetKnots
+	if waypoint != nil && waypoint.TargetSpeedKnots > 0 {
+		target = waypoint.TargetSpeedKnots
+	}
+	approach := c.approachKnots
+	if approach <= 0 || approach > target {
+		approach = target
+	}
+	return target, approach
+}
+
+// speed over ground can not be used without a fix or if the position is outdated
+func (c *sogController) available(position *model.Position, now time.Time) bool {
+	if position.FixType == model.FixNone {
+		return false
+	}
+	if math.IsNaN(position.SpeedKnots) || position.SpeedKnots < 0 {
+		return false
+	}
+	if c.maxAge > 0 && !position.Timestamp.IsZero() &&
+		now.Sub(position.Timestamp)+position.FixAge > c.maxAge {
+		return false
+	}
+	return true
+}
+
+func (c *sogController) throttle(base model.Speed, targetKnots float64, sogKnots float64,
+	now time.Time) model.Speed {
+	dt := 0.0
+	if !c.lastUpdate.IsZero() {
+		dt = now.Sub(c.lastUpdate).Seconds()
+	}
+	c.lastUpdate = now
+
+	output := c.pid.update(targetKnots-sogKnots, dt)
+	throttle := model.Forward(int(math.Round(float64(base) + output)))
+	if throttle < c.minThrottle {
+		return c.minThrottle
+	}
+	if throttle > c.maxThrottle {
+		return c.maxThrottle
+	}
+	return throttle
+}
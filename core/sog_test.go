@@ -0,0 +1,159 @@
+package core
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+func TestNewSogControllerDisabled(t *testing.T) {
+	controller := newSogController(false, 10.0, 2.0, 20.0, 4.0, 1.5,
+		model.Forward(20), model.Forward(100), 2000)
+	if controller != nil {
+		t.Error("Expected sog controller to be disabled")
+	}
+}
+
+func TestSogControllerThrottle(t *testing.T) {
+	controller := newSogController(true, 10.0, 2.0, 20.0, 4.0, 1.5,
+		model.Forward(20), model.Forward(90), 2000)
+	now := time.Now()
+
+	// slower than the target, proportional term only on the first update
+	throttle := controller.throttle(model.Forward(50), 4.0, 3.0, now)
+	if throttle != model.Forward(60) {
+		t.Errorf("Expected throttle to be fwd60, got %s", throttle)
+	}
+
+	// integral term grows while the error persists
+	throttle = controller.throttle(model.Forward(50), 4.0, 3.0, now.Add(time.Second))
+	if throttle != model.Forward(62) {
+		t.Errorf("Expected throttle to be fwd62, got %s", throttle)
+	}
+
+	// faster than the target
+	controller.reset()
+	throttle = controller.throttle(model.Forward(50), 4.0, 5.0, now)
+	if throttle != model.Forward(40) {
+		t.Errorf("Expected throttle to be fwd40, got %s", throttle)
+	}
+}
+
+func TestSogControllerLimits(t *testing.T) {
+	controller := newSogController(true, 10.0, 0.0, 0.0, 4.0, 1.5,
+		model.Forward(20), model.Forward(90), 2000)
+	now := time.Now()
+
+	throttle := controller.throttle(model.Forward(50), 4.0, 0.0, now)
+	if throttle != model.Forward(90) {
+		t.Errorf("Expected throttle to be limited to fwd90, got %s", throttle)
+	}
+
+	controller.reset()
+	throttle = controller.throttle(model.Forward(50), 4.0, 8.0, now)
+	if throttle != model.Forward(20) {
+		t.Errorf("Expected throttle to be limited to fwd20, got %s", throttle)
+	}
+
+	// maximum throttle defaults to full power
+	controller = newSogController(true, 10.0, 0.0, 0.0, 4.0, 1.5,
+		model.SpeedStop, model.SpeedStop, 2000)
+	throttle = controller.throttle(model.Forward(80), 4.0, 0.0, now)
+	if throttle != model.Forward(100) {
+		t.Errorf("Expected throttle to be limited to fwd100, got %s", throttle)
+	}
+}
+
+func TestSogControllerTarget(t *testing.T) {
+	controller := newSogController(true, 10.0, 2.0, 20.0, 4.0, 1.5,
+		model.Forward(20), model.Forward(100), 2000)
+
+	target, approach := controller.target(&model.Waypoint{})
+	if target != 4.0 || approach != 1.5 {
+		t.Errorf("Expected target 4.0 and approach 1.5, got %f and %f", target, approach)
+	}
+
+	target, approach = controller.target(&model.Waypoint{TargetSpeedKnots: 6.0})
+	if target != 6.0 || approach != 1.5 {
+		t.Errorf("Expected target 6.0 and approach 1.5, got %f and %f", target, approach)
+	}
+
+	// approach speed never exceeds the target speed of the leg
+	target, approach = controller.target(&model.Waypoint{TargetSpeedKnots: 1.0})
+	if target != 1.0 || approach != 1.0 {
+		t.Errorf("Expected target 1.0 and approach 1.0, got %f and %f", target, approach)
+	}
+}
+
+func TestSogControllerAvailable(t *testing.T) {
+	controller := newSogController(true, 10.0, 2.0, 20.0, 4.0, 1.5,
+		model.Forward(20), model.Forward(100), 2000)
+	now := time.Now()
+
+	tests := []struct {
+		position  *model.Position
+		available bool
+	}{
+		{&model.Position{FixType: model.Fix3D, SpeedKnots: 3.0, Timestamp: now}, true},
+		{&model.Position{FixType: model.FixUnknown, SpeedKnots: 3.0}, true},
+		{&model.Position{FixType: model.FixNone, SpeedKnots: 3.0, Timestamp: now}, false},
+		{&model.Position{FixType: model.Fix3D, SpeedKnots: math.NaN(), Timestamp: now}, false},
+		{&model.Position{FixType: model.Fix3D, SpeedKnots: 3.0,
+			Timestamp: now.Add(-3 * time.Second)}, false},
+		{&model.Position{FixType: model.Fix3D, SpeedKnots: 3.0, Timestamp: now.Add(-time.Second),
+			FixAge: 1500 * time.Millisecond}, false},
+	}
+
+	for i, test := range tests {
+		if controller.available(test.position, now) != test.available {
+			t.Errorf("Expected availability of position %d to be %t", i, test.available)
+		}
+	}
+}
+
+func TestMovingSogControl(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	waypoints := model.NewWaypoints()
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:         56.33956,
+		Longitude:        43.98449,
+		TargetSpeedKnots: 4.0,
+	})
+
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:   56.34000,
+			Longitude:  43.99394,
+			FixType:    model.Fix3D,
+			SpeedKnots: 3.0,
+			Timestamp:  time.Now(),
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		waypoints:     waypoints,
+	}
+
+	shipControl := &mockShipControl{}
+	sogController := newSogController(true, 10.0, 0.0, 0.0, 2.0, 1.5,
+		model.Forward(20), model.Forward(100), 2000)
+
+	handler := newMovingHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Forward(80), 50.0, 0.0, 0.5, nil, nil, sogController, nil)
+
+	// ship is slower than the waypoint speed, throttle is increased
+	handler.OnEnter()
+	if shipControl.speed != "fwd90" {
+		t.Errorf("Expected speed to be fwd90, got %s", shipControl.speed)
+	}
+
+	// open-loop throttle without the speed over ground
+	coreData.position.FixType = model.FixNone
+	handler.HandleEvent(Event(eventPositionUpdate))
+	if shipControl.speed != "fwd80" {
+		t.Errorf("Expected speed to be fwd80, got %s", shipControl.speed)
+	}
+}
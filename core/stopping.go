@@ -1,6 +1,7 @@
 package core
 
 import (
+	"github.com/moosethebrown/ship-nav/core/model"
 	"github.com/rs/zerolog"
 )
 
@@ -22,8 +23,7 @@ func newStoppingHandler(logger *zerolog.Logger, coreData *coreData,
 func (handler *stoppingHandler) OnEnter() {
 	handler.logger.Debug().Msg("OnEnter")
 
-	handler.shipControl.SetSpeed("stop")
-	handler.shipControl.SetSteering("straight")
+	handler.stop()
 }
 
 func (handler *stoppingHandler) OnExit() {
@@ -35,10 +35,18 @@ func (handler *stoppingHandler) HandleEvent(event Event) string {
 
 	switch event {
 	case eventShipDataUpdate:
-		if handler.coreData.shipData.Speed == "stop" {
+		if handler.coreData.shipData.Speed.IsStop() {
 			return "ship stopped"
 		}
+	case eventShipControlRestored, eventShipCommandFailed:
+		// commands sent while ship control was not connected or failed are lost
+		handler.stop()
 	}
 
 	return ""
 }
+
+func (handler *stoppingHandler) stop() {
+	emergencyStop(handler.shipControl)
+	handler.shipControl.SetSteering(model.SteeringStraight)
+}
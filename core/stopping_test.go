@@ -42,15 +42,43 @@ func TestStoppingEventShipDataUpdate(t *testing.T) {
 	handler := newStoppingHandler(&logger, coreData, shipControl)
 	handler.OnEnter()
 
-	coreData.shipData.Speed = "fwd10"
+	coreData.shipData.Speed = model.Forward(10)
 	transition := handler.HandleEvent(Event(eventShipDataUpdate))
 	if transition != "" {
 		t.Errorf("Expected empty transition, got %s", transition)
 	}
 
-	coreData.shipData.Speed = "stop"
+	coreData.shipData.Speed = model.SpeedStop
 	transition = handler.HandleEvent(Event(eventShipDataUpdate))
 	if transition != "ship stopped" {
 		t.Errorf("Expected ship stopped transition, got %s", transition)
 	}
 }
+
+func TestStoppingEventShipCommandFailed(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := &coreData{
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		shipData:      &model.ShipData{},
+	}
+
+	shipControl := &mockShipControl{}
+
+	handler := newStoppingHandler(&logger, coreData, shipControl)
+	handler.OnEnter()
+
+	shipControl.speed = ""
+	shipControl.steering = ""
+	transition := handler.HandleEvent(Event(eventShipCommandFailed))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	if shipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", shipControl.speed)
+	}
+	if shipControl.steering != "straight" {
+		t.Errorf("Expected steering to be straight, got %s", shipControl.steering)
+	}
+}
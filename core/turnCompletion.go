@@ -0,0 +1,77 @@
+package core
+
+import (
+	"math"
+	"time"
+)
+
+// decides that the turn to the target bearing is completed, the heading error has to stay within
+// the tolerance for the dwell time, the hull keeps turning after the rudder is centered so the error
+// is predicted ahead by the measured turn rate
+type turnCompletion struct {
+	toleranceDeg float64
+	dwell        time.Duration
+	leadTime     time.Duration
+	// the turn is abandoned if the heading does not converge, zero disables the timeout
+	timeout     time.Duration
+	started     time.Time
+	withinSince time.Time
+	lastError   float64
+	lastUpdate  time.Time
+	// change of the heading error in degrees per second
+	rate float64
+}
+
+func newTurnCompletion(toleranceDeg float64, dwellMs int64, leadTimeMs int64,
+	timeoutMs int64) *turnCompletion {
+	if toleranceDeg <= 0 {
+		return nil
+	}
+
+	return &turnCompletion{
+		toleranceDeg: toleranceDeg,
+		dwell:        time.Duration(dwellMs) * time.Millisecond,
+		leadTime:     time.Duration(leadTimeMs) * time.Millisecond,
+		timeout:      time.Duration(timeoutMs) * time.Millisecond,
+	}
+}
+
+// called at the start of every turn
+func (c *turnCompletion) reset(now time.Time) {
+	c.started = now
+	c.withinSince = time.Time{}
+	c.lastError = 0
+	c.lastUpdate = time.Time{}
+	c.rate = 0
+}
+
+// errorDeg is positive when the target is to the right of the current heading,
+// returns true when the turn is completed
+func (c *turnCompletion) update(errorDeg float64, now time.Time) bool {
+	if !c.lastUpdate.IsZero() {
+		if dt := now.Sub(c.lastUpdate).Seconds(); dt > 0 {
+			c.rate = headingErrorDeg(errorDeg, c.lastError) / dt
+		}
+	}
+	c.lastError = errorDeg
+	c.lastUpdate = now
+
+	if !c.within(errorDeg) {
+		c.withinSince = time.Time{}
+		return false
+	}
+	if c.withinSince.IsZero() {
+		c.withinSince = now
+	}
+	return now.Sub(c.withinSince) >= c.dwell
+}
+
+// heading error expected after the lead time is within the tolerance
+func (c *turnCompletion) within(errorDeg float64) bool {
+	predicted := errorDeg + c.rate*c.leadTime.Seconds()
+	return math.Abs(predicted) <= c.toleranceDeg
+}
+
+func (c *turnCompletion) timedOut(now time.Time) bool {
+	return c.timeout > 0 && !c.started.IsZero() && now.Sub(c.started) >= c.timeout
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+func TestNewTurnCompletionDisabled(t *testing.T) {
+	if newTurnCompletion(0.0, 1000, 500, 60000) != nil {
+		t.Error("Expected turn completion to be disabled")
+	}
+}
+
+func TestTurnCompletionDwell(t *testing.T) {
+	completion := newTurnCompletion(3.0, 1000, 0, 0)
+	now := time.Now()
+	completion.reset(now)
+
+	tests := []struct {
+		errorDeg  float64
+		offset    time.Duration
+		completed bool
+	}{
+		{20.0, 0, false},
+		{1.0, 500 * time.Millisecond, false},
+		// overshoot restarts the dwell time
+		{-5.0, 1000 * time.Millisecond, false},
+		{-1.0, 1500 * time.Millisecond, false},
+		{0.5, 2000 * time.Millisecond, false},
+		{-0.5, 2500 * time.Millisecond, true},
+	}
+
+	for i, test := range tests {
+		if completion.update(test.errorDeg, now.Add(test.offset)) != test.completed {
+			t.Errorf("Expected turn completion at update %d to be %t", i, test.completed)
+		}
+	}
+}
+
+func TestTurnCompletionLead(t *testing.T) {
+	completion := newTurnCompletion(3.0, 0, 500, 0)
+	now := time.Now()
+	completion.reset(now)
+
+	if completion.update(20.0, now) {
+		t.Error("Expected turn not to be completed")
+	}
+	// turn rate is 12 degrees per second, the heading is expected to reach the target
+	// in half a second
+	if !completion.update(8.0, now.Add(time.Second)) {
+		t.Error("Expected turn to be completed ahead of the target bearing")
+	}
+
+	// the ship turns away from the target bearing
+	completion.reset(now)
+	completion.update(-1.0, now)
+	if completion.update(2.0, now.Add(500*time.Millisecond)) {
+		t.Error("Expected turn not to be completed while turning away from the target")
+	}
+}
+
+func TestTurnCompletionTimeout(t *testing.T) {
+	completion := newTurnCompletion(3.0, 1000, 500, 10000)
+	now := time.Now()
+
+	if completion.timedOut(now) {
+		t.Error("Expected turn not to time out before it is started")
+	}
+	completion.reset(now)
+	if completion.timedOut(now.Add(9 * time.Second)) {
+		t.Error("Expected turn not to time out")
+	}
+	if !completion.timedOut(now.Add(10 * time.Second)) {
+		t.Error("Expected turn to time out")
+	}
+
+	completion = newTurnCompletion(3.0, 1000, 500, 0)
+	completion.reset(now)
+	if completion.timedOut(now.Add(time.Hour)) {
+		t.Error("Expected turn timeout to be disabled")
+	}
+}
+
+func TestTurningCompletion(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	waypoints := model.NewWaypoints()
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.33956,
+		Longitude: 43.98449,
+	})
+
+	coreData := &coreData{
+		position: &model.Position{
+			Latitude:  56.34000,
+			Longitude: 43.99394,
+		},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		waypoints:     waypoints,
+	}
+
+	shipControl := &mockShipControl{}
+	completion := newTurnCompletion(3.0, 1000, 0, 60000)
+
+	handler := newTurningHandler(&logger, coreData, shipControl, model.Forward(30),
+		model.Left(40), model.Right(40), nil, completion)
+	handler.OnEnter()
+	// target bearing is -94.797892 degrees here
+
+	event := Event(eventBearingUpdate)
+	coreData.curBearing.SetAngleDeg(-60.0)
+	transition := handler.HandleEvent(event)
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	if shipControl.steering != "left40" {
+		t.Errorf("Expected steering to be left40, got %s", shipControl.steering)
+	}
+
+	// within the tolerance, the rudder is centered for the dwell time
+	coreData.curBearing.SetAngleDeg(-96.0)
+	transition = handler.HandleEvent(event)
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	if shipControl.steering != "straight" {
+		t.Errorf("Expected steering to be straight, got %s", shipControl.steering)
+	}
+
+	// overshoot, the ship steers back
+	coreData.curBearing.SetAngleDeg(-110.0)
+	transition = handler.HandleEvent(event)
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+	if shipControl.steering != "right40" {
+		t.Errorf("Expected steering to be right40, got %s", shipControl.steering)
+	}
+
+	coreData.curBearing.SetAngleDeg(-95.0)
+	handler.HandleEvent(event)
+	completion.withinSince = completion.withinSince.Add(-time.Second)
+	transition = handler.HandleEvent(event)
+	if transition != "bearing adjust" {
+		t.Errorf("Expected bearing adjust transition, got %s", transition)
+	}
+
+	// the heading never converges
+	handler.OnEnter()
+	completion.started = completion.started.Add(-time.Minute)
+	coreData.curBearing.SetAngleDeg(-60.0)
+	transition = handler.HandleEvent(event)
+	if transition != "turn timeout" {
+		t.Errorf("Expected turn timeout transition, got %s", transition)
+	}
+}
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"math"
+	"time"
+
 	"github.com/moosethebrown/ship-nav/core/model"
 	"github.com/rs/zerolog"
 )
@@ -9,13 +12,18 @@ type turningHandler struct {
 	logger               *zerolog.Logger
 	coreData             *coreData
 	shipControl          ShipControl
-	turningSpeed         string
-	turningSteeringLeft  string
-	turningSteeringRight string
+	turningSpeed         model.Speed
+	turningSteeringLeft  model.Steering
+	turningSteeringRight model.Steering
+	headingController    *headingController
+	turnCompletion       *turnCompletion
+	// there is no route around the no-go zones to the next waypoint
+	routeBlocked bool
 }
 
 func newTurningHandler(logger *zerolog.Logger, coreData *coreData, shipControl ShipControl,
-	turningSpeed string, turningSteeringLeft string, turningSteeringRight string) *turningHandler {
+	turningSpeed model.Speed, turningSteeringLeft model.Steering, turningSteeringRight model.Steering,
+	headingController *headingController, turnCompletion *turnCompletion) *turningHandler {
 	return &turningHandler{
 		logger:               logger,
 		coreData:             coreData,
@@ -23,12 +31,20 @@ func newTurningHandler(logger *zerolog.Logger, coreData *coreData, shipControl S
 		turningSpeed:         turningSpeed,
 		turningSteeringLeft:  turningSteeringLeft,
 		turningSteeringRight: turningSteeringRight,
+		headingController:    headingController,
+		turnCompletion:       turnCompletion,
 	}
 }
 
 func (handler *turningHandler) OnEnter() {
 	handler.logger.Debug().Msg("OnEnter")
 
+	handler.coreData.homeBound = false
+	handler.coreData.loitering = false
+	handler.coreData.loiterPoint = nil
+	handler.coreData.homeRoute = nil
+	handler.resetTurn()
+	handler.routeBlocked = !planRoute(handler.logger, handler.coreData)
 	handler.calculateTargetBearing(handler.coreData.waypoints.GetNextWaypoint())
 	handler.steerToTarget()
 	handler.shipControl.SetSpeed(handler.turningSpeed)
@@ -45,15 +61,29 @@ func (handler *turningHandler) HandleEvent(event Event) string {
 
 	switch event {
 	case eventBearingUpdate:
-		deltaAngle := handler.coreData.targetBearing.AngleDeg() -
-			handler.coreData.curBearing.AngleDeg()
+		if handler.routeBlocked {
+			return "route blocked"
+		}
+		if handler.turnCompletion != nil {
+			return handler.checkTurn()
+		}
+		deltaAngle := handler.coreData.headingErrorDeg()
 		handler.logger.Debug().Msgf("delta angle = %f", deltaAngle)
-		if ((deltaAngle < 0.1) && (deltaAngle >= 0)) || ((deltaAngle > -0.1) && (deltaAngle <= 0)) {
+		if math.Abs(deltaAngle) < 0.1 {
 			// close enough
 			handler.logger.Info().Msgf("delta angle = %f, turning is completed", deltaAngle)
 			return "bearing adjust"
 		}
+		if handler.headingController != nil {
+			handler.steerToTarget()
+		}
 	case eventPositionUpdate:
+		if transition := geofenceTransition(handler.logger, handler.coreData); transition != "" {
+			return transition
+		}
+		if handler.routeBlocked {
+			return "route blocked"
+		}
 		handler.calculateTargetBearing(handler.coreData.waypoints.GetNextWaypoint())
 		handler.steerToTarget()
 	case eventNetLoss:
@@ -65,7 +95,21 @@ func (handler *turningHandler) HandleEvent(event Event) string {
 		}
 	case eventNavStop:
 		return "nav stop"
-	case eventWaypointsSet:
+	case eventFixLost:
+		return "fix lost"
+	case eventSensorStale:
+		return "sensor stale"
+	case eventPositionLost:
+		return "position lost"
+	case eventShipControlLost:
+		return "ship control lost"
+	case eventShipCommandFailed:
+		return "command failed"
+	case eventHoldPosition:
+		return "hold position"
+	case eventWaypointsSet, eventNoGoZonesSet:
+		handler.resetTurn()
+		handler.routeBlocked = !planRoute(handler.logger, handler.coreData)
 		handler.calculateTargetBearing(handler.coreData.waypoints.GetNextWaypoint())
 		handler.steerToTarget()
 	case eventWaypointsCleared:
@@ -75,18 +119,52 @@ func (handler *turningHandler) HandleEvent(event Event) string {
 	return ""
 }
 
+func (handler *turningHandler) resetTurn() {
+	if handler.headingController != nil {
+		handler.headingController.reset()
+	}
+	if handler.turnCompletion != nil {
+		handler.turnCompletion.reset(time.Now())
+	}
+}
+
+// the ship steers back to the target bearing if it overshoots, the turn is abandoned
+// if the heading does not converge
+func (handler *turningHandler) checkTurn() string {
+	now := time.Now()
+	deltaAngle := handler.coreData.headingErrorDeg()
+	handler.logger.Debug().Msgf("delta angle = %f, turn rate = %f", deltaAngle, handler.turnCompletion.rate)
+	if handler.turnCompletion.update(deltaAngle, now) {
+		handler.logger.Info().Msgf("delta angle = %f, turning is completed", deltaAngle)
+		return "bearing adjust"
+	}
+	if handler.turnCompletion.timedOut(now) {
+		handler.logger.Warn().Msgf("delta angle = %f, heading did not converge in %s", deltaAngle,
+			handler.turnCompletion.timeout)
+		return "turn timeout"
+	}
+	handler.steerToTarget()
+	return ""
+}
+
 func (handler *turningHandler) calculateTargetBearing(waypoint *model.Waypoint) {
-	diffLat := waypoint.Latitude - handler.coreData.position.Latitude
-	diffLong := waypoint.Longitude - handler.coreData.position.Longitude
-	handler.coreData.targetBearing.SetFloat(diffLat, diffLong)
+	setTargetBearing(handler.coreData, waypoint)
 	handler.logger.Debug().Msgf("current bearing = %f", handler.coreData.curBearing.AngleDeg())
 	handler.logger.Debug().Msgf("target bearing = %f", handler.coreData.targetBearing.AngleDeg())
 }
 
 func (handler *turningHandler) steerToTarget() {
-	deltaAngle := handler.coreData.targetBearing.AngleDeg() -
-		handler.coreData.curBearing.AngleDeg()
-	if (deltaAngle > 180) || ((deltaAngle > -180) && (deltaAngle < 0)) {
+	if handler.headingController != nil {
+		handler.shipControl.SetSteering(handler.headingController.steering(
+			handler.coreData.headingErrorDeg(), time.Now()))
+		return
+	}
+
+	deltaAngle := handler.coreData.headingErrorDeg()
+	if handler.turnCompletion != nil && handler.turnCompletion.within(deltaAngle) {
+		// the ship keeps turning with the rudder centered
+		handler.shipControl.SetSteering(model.SteeringStraight)
+	} else if deltaAngle < 0 {
 		handler.shipControl.SetSteering(handler.turningSteeringLeft)
 	} else {
 		handler.shipControl.SetSteering(handler.turningSteeringRight)
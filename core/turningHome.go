@@ -1,6 +1,7 @@
 package core
 
 import (
+	"github.com/moosethebrown/ship-nav/core/model"
 	"github.com/rs/zerolog"
 )
 
@@ -9,7 +10,8 @@ type turningHomeHandler struct {
 }
 
 func newTurningHomeHandler(logger *zerolog.Logger, coreData *coreData, shipControl ShipControl,
-	turningSpeed string, turningSteeringLeft string, turningSteeringRight string) *turningHomeHandler {
+	turningSpeed model.Speed, turningSteeringLeft model.Steering, turningSteeringRight model.Steering,
+	headingController *headingController, turnCompletion *turnCompletion) *turningHomeHandler {
 	return &turningHomeHandler{
 		turningHandler: &turningHandler{
 			logger:               logger,
@@ -18,6 +20,8 @@ func newTurningHomeHandler(logger *zerolog.Logger, coreData *coreData, shipContr
 			turningSpeed:         turningSpeed,
 			turningSteeringLeft:  turningSteeringLeft,
 			turningSteeringRight: turningSteeringRight,
+			headingController:    headingController,
+			turnCompletion:       turnCompletion,
 		},
 	}
 }
@@ -25,7 +29,14 @@ func newTurningHomeHandler(logger *zerolog.Logger, coreData *coreData, shipContr
 func (handler *turningHomeHandler) OnEnter() {
 	handler.turningHandler.logger.Debug().Msg("OnEnter")
 
-	handler.turningHandler.calculateTargetBearing(handler.turningHandler.coreData.homeWaypoint)
+	handler.turningHandler.coreData.homeBound = true
+	handler.turningHandler.resetTurn()
+	// the route is kept while the ship follows the detour waypoints
+	if handler.turningHandler.coreData.homeRoute == nil {
+		handler.turningHandler.routeBlocked = !planRouteHome(handler.turningHandler.logger,
+			handler.turningHandler.coreData)
+	}
+	handler.turningHandler.calculateTargetBearing(handler.turningHandler.coreData.nextHomeWaypoint())
 	handler.turningHandler.steerToTarget()
 	handler.turningHandler.shipControl.SetSpeed(handler.turningHandler.turningSpeed)
 }
@@ -42,11 +53,39 @@ func (handler *turningHomeHandler) HandleEvent(event Event) string {
 	switch event {
 	case eventNavStop:
 		return "nav stop"
+	case eventFixLost:
+		return "fix lost"
+	case eventSensorStale:
+		return "sensor stale"
+	case eventPositionLost:
+		return "position lost"
+	case eventShipControlLost:
+		return "ship control lost"
+	case eventShipCommandFailed:
+		return "command failed"
+	case eventHoldPosition:
+		return "hold position"
 	case eventBearingUpdate:
 		return handler.turningHandler.HandleEvent(event)
 	case eventPositionUpdate:
-		handler.turningHandler.calculateTargetBearing(handler.turningHandler.coreData.homeWaypoint)
+		if handler.turningHandler.routeBlocked {
+			return "route blocked"
+		}
+		if transition := geofenceHomeTransition(handler.turningHandler.logger,
+			handler.turningHandler.coreData); transition != "" {
+			return transition
+		}
+		handler.turningHandler.calculateTargetBearing(handler.turningHandler.coreData.nextHomeWaypoint())
+		if handler.turningHandler.headingController != nil {
+			handler.turningHandler.steerToTarget()
+		}
 		return ""
+	case eventNoGoZonesSet:
+		handler.turningHandler.resetTurn()
+		handler.turningHandler.routeBlocked = !planRouteHome(handler.turningHandler.logger,
+			handler.turningHandler.coreData)
+		handler.turningHandler.calculateTargetBearing(handler.turningHandler.coreData.nextHomeWaypoint())
+		handler.turningHandler.steerToTarget()
 	}
 
 	return ""
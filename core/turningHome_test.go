@@ -26,16 +26,17 @@ func TestTurningHomeOnEnter(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newTurningHomeHandler(&logger, coreData, shipControl, "fwd20", "left40", "right40")
+	handler := newTurningHomeHandler(&logger, coreData, shipControl,
+		model.Forward(20), model.Left(40), model.Right(40), nil, nil)
 
 	handler.OnEnter()
 
 	tolerance := 0.0001
-	if math.Abs(coreData.targetBearing.AngleDeg()-(116.022)) > tolerance {
-		t.Errorf("Expected target bearing to be 116.022, got %f", coreData.targetBearing.AngleDeg())
+	if math.Abs(coreData.targetBearing.AngleDeg()-(131.365019)) > tolerance {
+		t.Errorf("Expected target bearing to be 131.365019, got %f", coreData.targetBearing.AngleDeg())
 	}
 
-	// with current bearing = 0, target bearing 116.022, ship is expected to turn right
+	// with current bearing = 0, target bearing 131.365019, ship is expected to turn right
 	if shipControl.steering != "right40" {
 		t.Errorf("Expected steering to be right40, got %s", shipControl.steering)
 	}
@@ -63,7 +64,8 @@ func TestTurningHomeOnExit(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newTurningHomeHandler(&logger, coreData, shipControl, "fwd30", "left40", "right40")
+	handler := newTurningHomeHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Left(40), model.Right(40), nil, nil)
 
 	handler.OnExit()
 
@@ -90,10 +92,11 @@ func TestTurningHomeEventBearingUpdate(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newTurningHomeHandler(&logger, coreData, shipControl, "fwd30", "left40", "right40")
+	handler := newTurningHomeHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Left(40), model.Right(40), nil, nil)
 
 	handler.OnEnter()
-	// target bearing is 116.022 degrees here
+	// target bearing is 131.365019 degrees here
 
 	coreData.curBearing.SetInt(-6, 15) // angle = 111.80140948635182 degrees
 	transition := handler.HandleEvent(eventBearingUpdate)
@@ -101,7 +104,7 @@ func TestTurningHomeEventBearingUpdate(t *testing.T) {
 		t.Errorf("Expected empty transition, got %s", transition)
 	}
 
-	coreData.curBearing.SetFloat(-7.665, 15.7)
+	coreData.curBearing.SetAngleDeg(131.4)
 	transition = handler.HandleEvent(eventBearingUpdate)
 	if transition != "bearing adjust" {
 		t.Errorf("Expected bearing adjust transition, got %s", transition)
@@ -126,10 +129,11 @@ func TestTurningHomeEventPositionUpdate(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newTurningHomeHandler(&logger, coreData, shipControl, "fwd30", "left40", "right40")
+	handler := newTurningHomeHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Left(40), model.Right(40), nil, nil)
 
 	handler.OnEnter()
-	// target bearing is 116.022 degrees here
+	// target bearing is 131.365019 degrees here
 
 	coreData.position.Latitude = 56.33014
 	coreData.position.Longitude = 43.98509
@@ -138,8 +142,8 @@ func TestTurningHomeEventPositionUpdate(t *testing.T) {
 		t.Errorf("Expected empty transition, got %s", transition)
 	}
 	tolerance := 0.0001
-	if math.Abs(coreData.targetBearing.AngleDeg()-(84.87715)) > tolerance {
-		t.Errorf("Expected target bearing to be 84.87715, got %f", coreData.targetBearing.AngleDeg())
+	if math.Abs(coreData.targetBearing.AngleDeg()-(80.804105)) > tolerance {
+		t.Errorf("Expected target bearing to be 80.804105, got %f", coreData.targetBearing.AngleDeg())
 	}
 }
 
@@ -161,7 +165,8 @@ func TestTurningHomeEventNavStop(t *testing.T) {
 
 	shipControl := &mockShipControl{}
 
-	handler := newTurningHomeHandler(&logger, coreData, shipControl, "fwd30", "left40", "right40")
+	handler := newTurningHomeHandler(&logger, coreData, shipControl,
+		model.Forward(30), model.Left(40), model.Right(40), nil, nil)
 
 	handler.OnEnter()
 
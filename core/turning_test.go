@@ -13,12 +13,13 @@ type mockShipControl struct {
 	steering string
 }
 
-func (m *mockShipControl) SetSpeed(speed string) {
-	m.speed = speed
+// commands are stored in the wire format
+func (m *mockShipControl) SetSpeed(speed model.Speed) {
+	m.speed = speed.String()
 }
 
-func (m *mockShipControl) SetSteering(steering string) {
-	m.steering = steering
+func (m *mockShipControl) SetSteering(steering model.Steering) {
+	m.steering = steering.String()
 }
 
 func TestTurningOnEnter(t *testing.T) {
@@ -46,19 +47,19 @@ func TestTurningOnEnter(t *testing.T) {
 		logger:               &logger,
 		coreData:             coreData,
 		shipControl:          shipControl,
-		turningSpeed:         "fwd30",
-		turningSteeringLeft:  "left40",
-		turningSteeringRight: "right40",
+		turningSpeed:         model.Forward(30),
+		turningSteeringLeft:  model.Left(40),
+		turningSteeringRight: model.Right(40),
 	}
 
 	handler.OnEnter()
 
 	tolerance := 0.0001
-	if math.Abs(coreData.targetBearing.AngleDeg()-(-92.665815)) > tolerance {
-		t.Errorf("Expected target bearing to be -92.665815, got %f", coreData.targetBearing.AngleDeg())
+	if math.Abs(coreData.targetBearing.AngleDeg()-(-94.797892)) > tolerance {
+		t.Errorf("Expected target bearing to be -94.797892, got %f", coreData.targetBearing.AngleDeg())
 	}
 
-	// with current bearing = 0, target bearing -92.665815, ship is expected to turn left
+	// with current bearing = 0, target bearing -94.797892, ship is expected to turn left
 	if shipControl.steering != "left40" {
 		t.Errorf("Expected steering to be left40, got %s", shipControl.steering)
 	}
@@ -115,13 +116,13 @@ func TestTurningEventBearingUpdate(t *testing.T) {
 		logger:               &logger,
 		coreData:             coreData,
 		shipControl:          shipControl,
-		turningSpeed:         "fwd30",
-		turningSteeringLeft:  "left40",
-		turningSteeringRight: "right40",
+		turningSpeed:         model.Forward(30),
+		turningSteeringLeft:  model.Left(40),
+		turningSteeringRight: model.Right(40),
 	}
 
 	handler.OnEnter()
-	// target bearing is -92.665815 degrees here
+	// target bearing is -94.797892 degrees here
 
 	event := Event(eventBearingUpdate)
 	coreData.curBearing.SetInt(2, -1) // angle = -26.56 degrees
@@ -130,13 +131,55 @@ func TestTurningEventBearingUpdate(t *testing.T) {
 		t.Errorf("Expected empty transition, got %s", transition)
 	}
 
-	coreData.curBearing.SetFloat(-0.04655, -1) // angle = -92.66519457519553 degrees
+	coreData.curBearing.SetAngleDeg(-94.8)
 	transition = handler.HandleEvent(event)
 	if transition != "bearing adjust" {
 		t.Errorf("Expected bearing adjust transition, got %s", transition)
 	}
 }
 
+func TestTurningWrapAround(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	coreData := &coreData{
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+	}
+
+	shipControl := &mockShipControl{}
+
+	handler := &turningHandler{
+		logger:               &logger,
+		coreData:             coreData,
+		shipControl:          shipControl,
+		turningSpeed:         model.Forward(30),
+		turningSteeringLeft:  model.Left(40),
+		turningSteeringRight: model.Right(40),
+	}
+
+	// the shortest turn crosses South
+	coreData.targetBearing.SetAngleDeg(170.0)
+	coreData.curBearing.SetAngleDeg(-170.0)
+	handler.steerToTarget()
+	if shipControl.steering != "left40" {
+		t.Errorf("Expected steering to be left40, got %s", shipControl.steering)
+	}
+
+	coreData.targetBearing.SetAngleDeg(-170.0)
+	coreData.curBearing.SetAngleDeg(170.0)
+	handler.steerToTarget()
+	if shipControl.steering != "right40" {
+		t.Errorf("Expected steering to be right40, got %s", shipControl.steering)
+	}
+
+	coreData.targetBearing.SetAngleDeg(-179.95)
+	coreData.curBearing.SetAngleDeg(179.99)
+	transition := handler.HandleEvent(Event(eventBearingUpdate))
+	if transition != "bearing adjust" {
+		t.Errorf("Expected bearing adjust transition, got %s", transition)
+	}
+}
+
 func TestTurningEventPositionUpdate(t *testing.T) {
 	logger := zerolog.New(nil).Level(zerolog.Disabled)
 
@@ -162,13 +205,13 @@ func TestTurningEventPositionUpdate(t *testing.T) {
 		logger:               &logger,
 		coreData:             coreData,
 		shipControl:          shipControl,
-		turningSpeed:         "fwd30",
-		turningSteeringLeft:  "left40",
-		turningSteeringRight: "right40",
+		turningSpeed:         model.Forward(30),
+		turningSteeringLeft:  model.Left(40),
+		turningSteeringRight: model.Right(40),
 	}
 
 	handler.OnEnter()
-	// target bearing is -92.665815 degrees here
+	// target bearing is -94.797892 degrees here
 
 	coreData.position.Latitude = 56.33938
 	coreData.position.Longitude = 43.99413
@@ -179,8 +222,8 @@ func TestTurningEventPositionUpdate(t *testing.T) {
 		t.Errorf("Expected empty transition, got %s", transition)
 	}
 	tolerance := 0.0001
-	if math.Abs(coreData.targetBearing.AngleDeg()-(-88.93028610071595)) > tolerance {
-		t.Errorf("Expected target bearing to be -88.93028610071595, got %f", coreData.targetBearing.AngleDeg())
+	if math.Abs(coreData.targetBearing.AngleDeg()-(-88.066547)) > tolerance {
+		t.Errorf("Expected target bearing to be -88.066547, got %f", coreData.targetBearing.AngleDeg())
 	}
 }
 
@@ -257,13 +300,13 @@ func TestTurningEventWaypointsSet(t *testing.T) {
 		logger:               &logger,
 		coreData:             coreData,
 		shipControl:          shipControl,
-		turningSpeed:         "fwd30",
-		turningSteeringLeft:  "left40",
-		turningSteeringRight: "right40",
+		turningSpeed:         model.Forward(30),
+		turningSteeringLeft:  model.Left(40),
+		turningSteeringRight: model.Right(40),
 	}
 
 	handler.OnEnter()
-	// target bearing is -92.665815 degrees here, ship turns left
+	// target bearing is -94.797892 degrees here, ship turns left
 
 	coreData.waypoints = model.NewWaypoints()
 	coreData.waypoints.AddWaypoint(&model.Waypoint{
@@ -304,9 +347,9 @@ func TestTurningEventWaypointsCleared(t *testing.T) {
 		logger:               &logger,
 		coreData:             coreData,
 		shipControl:          shipControl,
-		turningSpeed:         "fwd30",
-		turningSteeringLeft:  "left40",
-		turningSteeringRight: "right40",
+		turningSpeed:         model.Forward(30),
+		turningSteeringLeft:  model.Left(40),
+		turningSteeringRight: model.Right(40),
 	}
 
 	handler.OnEnter()
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"time"
+)
+
+const (
+	watchdogInterval = 100 * time.Millisecond
+)
+
+// tracks the age of the latest sensor data, zero max age disables the check
+type sensorWatchdog struct {
+	maxPositionAge time.Duration
+	maxBearingAge  time.Duration
+	maxShipDataAge time.Duration
+	lastPosition   time.Time
+	lastBearing    time.Time
+	lastShipData   time.Time
+}
+
+func newSensorWatchdog(maxPositionAgeMs, maxBearingAgeMs, maxShipDataAgeMs int64) *sensorWatchdog {
+	return &sensorWatchdog{
+		maxPositionAge: time.Duration(maxPositionAgeMs) * time.Millisecond,
+		maxBearingAge:  time.Duration(maxBearingAgeMs) * time.Millisecond,
+		maxShipDataAge: time.Duration(maxShipDataAgeMs) * time.Millisecond,
+	}
+}
+
+func (w *sensorWatchdog) enabled() bool {
+	return w.maxPositionAge > 0 || w.maxBearingAge > 0 || w.maxShipDataAge > 0
+}
+
+// sensors get the full max age to deliver the first sample after start
+func (w *sensorWatchdog) start(now time.Time) {
+	w.lastPosition = now
+	w.lastBearing = now
+	w.lastShipData = now
+}
+
+func (w *sensorWatchdog) positionUpdated(timestamp time.Time) {
+	w.lastPosition = latest(w.lastPosition, timestamp)
+}
+
+func (w *sensorWatchdog) bearingUpdated(timestamp time.Time) {
+	w.lastBearing = latest(w.lastBearing, timestamp)
+}
+
+func (w *sensorWatchdog) shipDataUpdated(timestamp time.Time) {
+	w.lastShipData = latest(w.lastShipData, timestamp)
+}
+
+// returns names of the sensors with data older than allowed
+func (w *sensorWatchdog) stale(now time.Time) []string {
+	var sensors []string
+	if w.maxPositionAge > 0 && now.Sub(w.lastPosition) > w.maxPositionAge {
+		sensors = append(sensors, "position")
+	}
+	if w.maxBearingAge > 0 && now.Sub(w.lastBearing) > w.maxBearingAge {
+		sensors = append(sensors, "bearing")
+	}
+	if w.maxShipDataAge > 0 && now.Sub(w.lastShipData) > w.maxShipDataAge {
+		sensors = append(sensors, "ship data")
+	}
+	return sensors
+}
+
+// samples without acquisition time are considered fresh on arrival
+func latest(last time.Time, timestamp time.Time) time.Time {
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	if timestamp.After(last) {
+		return timestamp
+	}
+	return last
+}
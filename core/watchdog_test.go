@@ -0,0 +1,43 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSensorWatchdog(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	watchdog := newSensorWatchdog(1000, 500, 0)
+	if !watchdog.enabled() {
+		t.Fatalf("Expected watchdog to be enabled")
+	}
+	watchdog.start(now)
+
+	if stale := watchdog.stale(now.Add(400 * time.Millisecond)); len(stale) != 0 {
+		t.Errorf("Expected no stale sensors, got %v", stale)
+	}
+
+	stale := watchdog.stale(now.Add(600 * time.Millisecond))
+	if len(stale) != 1 || stale[0] != "bearing" {
+		t.Errorf("Expected bearing to be stale, got %v", stale)
+	}
+
+	watchdog.bearingUpdated(now.Add(700 * time.Millisecond))
+	// samples are never older than the latest one
+	watchdog.bearingUpdated(now.Add(100 * time.Millisecond))
+	stale = watchdog.stale(now.Add(1100 * time.Millisecond))
+	if len(stale) != 1 || stale[0] != "position" {
+		t.Errorf("Expected position to be stale, got %v", stale)
+	}
+
+	// ship data age is not checked
+	watchdog.positionUpdated(now.Add(1100 * time.Millisecond))
+	if stale := watchdog.stale(now.Add(1150 * time.Millisecond)); len(stale) != 0 {
+		t.Errorf("Expected no stale sensors, got %v", stale)
+	}
+
+	if newSensorWatchdog(0, 0, 0).enabled() {
+		t.Errorf("Expected watchdog to be disabled")
+	}
+}
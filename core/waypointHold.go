@@ -0,0 +1,93 @@
+package core
+
+import (
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+// the ship stays at the reached waypoint with the motors stopped for the hold time of the waypoint,
+// the hold time is checked on every sensor update
+type waypointHoldHandler struct {
+	logger      *zerolog.Logger
+	coreData    *coreData
+	shipControl ShipControl
+	holdUntil   time.Time
+}
+
+func newWaypointHoldHandler(logger *zerolog.Logger, coreData *coreData,
+	shipControl ShipControl) *waypointHoldHandler {
+	return &waypointHoldHandler{
+		logger:      logger,
+		coreData:    coreData,
+		shipControl: shipControl,
+	}
+}
+
+func (handler *waypointHoldHandler) OnEnter() {
+	handler.logger.Debug().Msg("OnEnter")
+
+	handler.shipControl.SetSpeed(model.SpeedStop)
+	handler.shipControl.SetSteering(model.SteeringStraight)
+
+	var holdTime time.Duration
+	if waypoint := handler.coreData.waypoints.GetPreviousWaypoint(); waypoint != nil {
+		holdTime = waypoint.HoldTime
+	}
+	handler.holdUntil = time.Now().Add(holdTime)
+	handler.logger.Info().Msgf("holding at waypoint for %s", holdTime)
+}
+
+func (handler *waypointHoldHandler) OnExit() {
+	handler.logger.Debug().Msg("OnExit")
+}
+
+func (handler *waypointHoldHandler) HandleEvent(event Event) string {
+	handler.logger.Debug().Msgf("HandleEvent event=%s", event.String())
+
+	switch event {
+	case eventPositionUpdate, eventBearingUpdate, eventShipDataUpdate:
+		if event == eventPositionUpdate {
+			if transition := geofenceTransition(handler.logger, handler.coreData); transition != "" {
+				return transition
+			}
+		}
+		if time.Now().Before(handler.holdUntil) {
+			return ""
+		}
+		if handler.coreData.waypoints.GetNextWaypoint() == nil {
+			if transition := missionEndTransition(handler.logger, handler.coreData); transition != "" {
+				return transition
+			}
+		}
+		return "hold done"
+	case eventNetLoss:
+		if handler.coreData.homeWaypoint != nil {
+			handler.logger.Info().Msg("net loss home")
+			return "net loss home"
+		} else {
+			return "net loss stop"
+		}
+	case eventNavStop:
+		return "nav stop"
+	case eventFixLost:
+		return "fix lost"
+	case eventSensorStale:
+		return "sensor stale"
+	case eventPositionLost:
+		return "position lost"
+	case eventShipControlLost:
+		return "ship control lost"
+	case eventShipCommandFailed:
+		return "command failed"
+	case eventHoldPosition:
+		return "hold position"
+	case eventWaypointsSet:
+		return "waypoints set"
+	case eventWaypointsCleared:
+		return "waypoints cleared"
+	}
+
+	return ""
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moosethebrown/ship-nav/core/model"
+	"github.com/rs/zerolog"
+)
+
+func newWaypointHoldTestData(holdTime time.Duration, last bool) *coreData {
+	waypoints := model.NewWaypoints()
+	waypoints.AddWaypoint(&model.Waypoint{
+		Latitude:  56.33956,
+		Longitude: 43.98449,
+		HoldTime:  holdTime,
+	})
+	if !last {
+		waypoints.AddWaypoint(&model.Waypoint{
+			Latitude:  56.333015,
+			Longitude: 44.007853,
+		})
+	}
+	waypoints.WaypointReached()
+
+	return &coreData{
+		position:      &model.Position{},
+		curBearing:    model.NewBearing(0.0),
+		targetBearing: model.NewBearing(0.0),
+		waypoints:     waypoints,
+	}
+}
+
+func TestWaypointHoldOnEnter(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+	shipControl := &mockShipControl{}
+
+	handler := newWaypointHoldHandler(&logger, newWaypointHoldTestData(time.Minute, false), shipControl)
+	handler.OnEnter()
+
+	if shipControl.speed != "stop" {
+		t.Errorf("Expected speed to be stop, got %s", shipControl.speed)
+	}
+	if shipControl.steering != "straight" {
+		t.Errorf("Expected steering to be straight, got %s", shipControl.steering)
+	}
+
+	transition := handler.HandleEvent(Event(eventPositionUpdate))
+	if transition != "" {
+		t.Errorf("Expected empty transition, got %s", transition)
+	}
+}
+
+func TestWaypointHoldDone(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	tests := []struct {
+		last       bool
+		transition string
+	}{
+		{false, "hold done"},
+		{true, "last waypoint"},
+	}
+
+	for _, test := range tests {
+		handler := newWaypointHoldHandler(&logger, newWaypointHoldTestData(20*time.Millisecond, test.last),
+			&mockShipControl{})
+		handler.OnEnter()
+
+		transition := handler.HandleEvent(Event(eventBearingUpdate))
+		if transition != "" {
+			t.Errorf("Expected empty transition, got %s", transition)
+		}
+
+		time.Sleep(30 * time.Millisecond)
+		transition = handler.HandleEvent(Event(eventShipDataUpdate))
+		if transition != test.transition {
+			t.Errorf("Expected %s transition, got %s", test.transition, transition)
+		}
+	}
+}
+
+func TestWaypointHoldEvents(t *testing.T) {
+	logger := zerolog.New(nil).Level(zerolog.Disabled)
+
+	tests := []struct {
+		event      Event
+		transition string
+	}{
+		{eventNavStop, "nav stop"},
+		{eventNetLoss, "net loss stop"},
+		{eventFixLost, "fix lost"},
+		{eventSensorStale, "sensor stale"},
+		{eventPositionLost, "position lost"},
+		{eventShipControlLost, "ship control lost"},
+		{eventShipCommandFailed, "command failed"},
+		{eventWaypointsSet, "waypoints set"},
+		{eventWaypointsCleared, "waypoints cleared"},
+	}
+
+	for _, test := range tests {
+		handler := newWaypointHoldHandler(&logger, newWaypointHoldTestData(time.Minute, false),
+			&mockShipControl{})
+		handler.OnEnter()
+
+		transition := handler.HandleEvent(test.event)
+		if transition != test.transition {
+			t.Errorf("Expected %s transition on %s, got %s", test.transition, test.event, transition)
+		}
+	}
+
+	coreData := newWaypointHoldTestData(time.Minute, false)
+	coreData.homeWaypoint = &model.Waypoint{Latitude: 56.34, Longitude: 43.99}
+	handler := newWaypointHoldHandler(&logger, coreData, &mockShipControl{})
+	handler.OnEnter()
+	transition := handler.HandleEvent(Event(eventNetLoss))
+	if transition != "net loss home" {
+		t.Errorf("Expected net loss home transition, got %s", transition)
+	}
+}